@@ -2,25 +2,36 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/erauner/homelab-todoist-declarative/internal/config"
 	"github.com/erauner/homelab-todoist-declarative/internal/export"
+	"github.com/erauner/homelab-todoist-declarative/internal/metrics"
+	"github.com/erauner/homelab-todoist-declarative/internal/notify"
 	"github.com/erauner/homelab-todoist-declarative/internal/output"
 	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/auth"
 	todoisthttp "github.com/erauner/homelab-todoist-declarative/internal/todoist/http"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+	"github.com/erauner/homelab-todoist-declarative/internal/watch"
 )
 
 func main() {
@@ -54,10 +65,21 @@ func newRootCmd() *cobra.Command {
 	var (
 		file          string
 		jsonOut       bool
+		format        string
+		outOpts       output.Options
 		prune         bool
 		verbose       bool
 		yes           bool
 		syncBatchSize int
+		stateFile     string
+		planOut       string
+		adoptTasks    bool
+		targets       []string
+		snapshotMode  string
+		applyBackend  string
+		continueOnErr bool
+		journalDir    string
+		backupOut     string
 	)
 
 	root := &cobra.Command{
@@ -65,19 +87,39 @@ func newRootCmd() *cobra.Command {
 		Short:         "Homelab Todoist Declarative reconciler",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			f, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+			if jsonOut {
+				if cmd.Flags().Changed("format") && f != output.FormatJSON {
+					return fmt.Errorf("--json conflicts with --format %s", f)
+				}
+				f = output.FormatJSON
+			}
+			jsonOut = f == output.FormatJSON
+			outOpts = output.Options{Format: f}
+			return nil
+		},
 	}
 
-	root.PersistentFlags().StringVarP(&file, "file", "f", config.DefaultPath(), "config file path")
-	root.PersistentFlags().BoolVar(&jsonOut, "json", false, "output JSON")
+	root.PersistentFlags().StringVarP(&file, "file", "f", config.DefaultPath(), "config file, directory of *.yaml files, or glob")
+	root.PersistentFlags().BoolVar(&jsonOut, "json", false, "output JSON (same as --format json)")
+	root.PersistentFlags().StringVar(&format, "format", "text", "output format: text, json, markdown (plan/apply results, e.g. for PR comments) or junit (validate/plan/apply, for CI test reports)")
 	root.PersistentFlags().BoolVar(&prune, "prune", false, "allow deletions (also gated by spec.prune.*)")
 	root.PersistentFlags().BoolVar(&verbose, "verbose", false, "verbose debug logging")
 	root.PersistentFlags().IntVar(&syncBatchSize, "sync-batch-size", 100, "max /sync commands per request (Todoist limit is 100)")
+	root.PersistentFlags().StringVar(&snapshotMode, "snapshot-mode", "rest", "how to read remote state: rest (paginated list calls), sync (one full /sync read) or incremental (/sync deltas against a local cache)")
+	root.PersistentFlags().StringVar(&stateFile, "state", "", "state file path (default ~/.config/todoist/state/<config name>.json)")
 
 	var exportFull bool
 	var exportName string
 	var exportIncludeInbox bool
 	var exportIDs bool
 	var exportOnly []string
+	var exportMergeInto string
+	var exportTasks bool
 	exportCmd := &cobra.Command{
 		Use:   "export",
 		Short: "Export current Todoist configuration to YAML",
@@ -101,36 +143,29 @@ func newRootCmd() *cobra.Command {
 			v1c := v1.New(httpClient)
 			syncC := sync.New(httpClient, sync.WithMaxCommandsPerSync(syncBatchSize))
 
-			snap, err := reconcile.FetchSnapshot(ctx, v1c, syncC)
+			snap, err := fetchSnapshot(ctx, snapshotMode, token, nil, v1c, syncC)
 			if err != nil {
 				return err
 			}
 
+			includeProjects, includeLabels, includeFilters, includeTasks := true, true, true, exportTasks
+			if len(exportOnly) > 0 {
+				kinds, err := parseKinds(exportOnly)
+				if err != nil {
+					return err
+				}
+				includeProjects, includeLabels, includeFilters, includeTasks = kinds["projects"], kinds["labels"], kinds["filters"], kinds["tasks"]
+			}
+
 			name := exportName
 			if strings.TrimSpace(name) == "" {
 				name = "export"
 			}
-			cfg, err := export.FromSnapshot(name, snap, export.Options{Full: exportFull, IncludeInbox: exportIncludeInbox, IncludeIDs: exportIDs})
+			exportOpts := export.Options{Full: exportFull, IncludeInbox: exportIncludeInbox, IncludeIDs: exportIDs, Tasks: includeTasks}
+			cfg, err := export.FromSnapshot(name, snap, exportOpts)
 			if err != nil {
 				return err
 			}
-
-			includeProjects, includeLabels, includeFilters := true, true, true
-			if len(exportOnly) > 0 {
-				includeProjects, includeLabels, includeFilters = false, false, false
-				for _, v := range exportOnly {
-					switch strings.ToLower(strings.TrimSpace(v)) {
-					case "projects":
-						includeProjects = true
-					case "labels":
-						includeLabels = true
-					case "filters":
-						includeFilters = true
-					default:
-						return fmt.Errorf("invalid --only value %q (expected projects, labels, filters)", v)
-					}
-				}
-			}
 			if !includeProjects {
 				cfg.Projects = nil
 			}
@@ -141,6 +176,32 @@ func newRootCmd() *cobra.Command {
 				cfg.Filters = nil
 			}
 
+			if exportMergeInto != "" {
+				if jsonOut {
+					return fmt.Errorf("--merge-into writes YAML; it cannot be combined with --json")
+				}
+				existing, err := os.ReadFile(exportMergeInto)
+				if err != nil {
+					return err
+				}
+				res, err := export.MergeInto(existing, cfg, exportOpts)
+				if err != nil {
+					return fmt.Errorf("%s: %w", exportMergeInto, err)
+				}
+				info, err := os.Stat(exportMergeInto)
+				if err != nil {
+					return err
+				}
+				if err := os.WriteFile(exportMergeInto, res.YAML, info.Mode().Perm()); err != nil {
+					return err
+				}
+				for _, n := range res.Notes {
+					fmt.Fprintf(cmd.ErrOrStderr(), "note: %s\n", n)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Merged into %s: %d updated, %d added.\n", exportMergeInto, res.Updated, res.Added)
+				return nil
+			}
+
 			if jsonOut {
 				b, err := json.MarshalIndent(cfg, "", "  ")
 				if err != nil {
@@ -162,13 +223,188 @@ func newRootCmd() *cobra.Command {
 	exportCmd.Flags().StringVar(&exportName, "name", "export", "config name for exported YAML")
 	exportCmd.Flags().BoolVar(&exportIncludeInbox, "include-inbox", false, "include inbox project in exported YAML")
 	exportCmd.Flags().BoolVar(&exportIDs, "ids", false, "include stable remote IDs (helps disambiguate duplicates)")
-	exportCmd.Flags().StringSliceVar(&exportOnly, "only", nil, "export only these kinds: projects,labels,filters")
+	exportCmd.Flags().StringSliceVar(&exportOnly, "only", nil, "export only these kinds: projects,labels,filters,tasks")
+	exportCmd.Flags().BoolVar(&exportTasks, "tasks", false, "also export recurring tasks and tasks already managed by htd (apply with --adopt-tasks to mark them)")
+	exportCmd.Flags().StringVar(&exportMergeInto, "merge-into", "", "update this YAML file in place (keeping comments, anchors and order) instead of printing a fresh document")
+
+	var importAll bool
+	var importMatch []string
+	var importOnly []string
+	var importInto string
+	var importFull bool
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Add unmanaged remote objects to the config (mutating for tasks)",
+		Long: "Import appends specs for remote projects, labels, filters and tasks that the config does not " +
+			"declare to a config file, keeping its comments and layout. Without --all or --match it asks " +
+			"about each one.\n\n" +
+			"Imported tasks are pinned by id and get an HTD_KEY marker written to their description, so " +
+			"later plans treat them as managed. Tasks are only considered with --only tasks.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+			defer cancel()
+
+			kinds := map[string]bool{"projects": true, "labels": true, "filters": true}
+			if len(importOnly) > 0 {
+				var err error
+				if kinds, err = parseKinds(importOnly); err != nil {
+					return err
+				}
+			}
+			for _, m := range importMatch {
+				if _, err := path.Match(m, ""); err != nil {
+					return fmt.Errorf("invalid --match %q: %w", m, err)
+				}
+			}
+
+			cfg, err := config.Load(file)
+			if err != nil {
+				return err
+			}
+			into := importInto
+			if into == "" {
+				if fi, err := os.Stat(file); err != nil || fi.IsDir() || strings.ContainsAny(file, "*?[") {
+					return fmt.Errorf("-f %q is not a single file; use --into to choose the file to add to", file)
+				}
+				into = file
+			}
+			existing, err := os.ReadFile(into)
+			if err != nil {
+				return err
+			}
+
+			token, _, err := auth.DiscoverToken()
+			if err != nil {
+				return err
+			}
+			logger := log.New(io.Discard, "", 0)
+			if verbose {
+				logger = log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+			}
+			httpClient := todoisthttp.New(token,
+				todoisthttp.WithVerbose(verbose),
+				todoisthttp.WithLogger(logger),
+			)
+			v1c := v1.New(httpClient)
+			syncC := sync.New(httpClient, sync.WithMaxCommandsPerSync(syncBatchSize))
+
+			st, _, err := loadState(stateFile, cfg)
+			if err != nil {
+				return err
+			}
+			snap, err := fetchSnapshot(ctx, snapshotMode, token, cfg, v1c, syncC)
+			if err != nil {
+				return err
+			}
+
+			importOpts := export.Options{Full: importFull}
+			found, err := export.Unmanaged(cfg, snap, st, importOpts)
+			if err != nil {
+				return err
+			}
+			in := cmd.InOrStdin()
+			notes := found.Select(func(kind reconcile.Kind, name, key string) bool {
+				if !kinds[string(kind)+"s"] {
+					return false
+				}
+				if len(importMatch) > 0 {
+					for _, m := range importMatch {
+						if ok, _ := path.Match(m, name); ok {
+							return true
+						}
+						if ok, _ := path.Match(m, key); ok && key != "" {
+							return true
+						}
+					}
+					return false
+				}
+				if importAll {
+					return true
+				}
+				ok, _ := confirm(in, cmd.ErrOrStderr(), fmt.Sprintf("Import %s %q?", kind, name))
+				return ok
+			})
+			for _, n := range notes {
+				fmt.Fprintf(cmd.ErrOrStderr(), "note: %s\n", n)
+			}
+			if found.Count() == 0 {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Nothing to import.")
+				return nil
+			}
+
+			res, err := export.MergeInto(existing, found, importOpts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", into, err)
+			}
+			info, err := os.Stat(into)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(into, res.YAML, info.Mode().Perm()); err != nil {
+				return err
+			}
+			// Never leave a config behind that htd cannot load.
+			newCfg, err := config.Load(file)
+			if err != nil {
+				if werr := os.WriteFile(into, existing, info.Mode().Perm()); werr != nil {
+					return fmt.Errorf("imported config is invalid (%v) and %s could not be restored: %w", err, into, werr)
+				}
+				return fmt.Errorf("imported config is invalid, %s left unchanged: %w", into, err)
+			}
+			foundTasks := config.Spec{Tasks: found.Tasks}.AllTasks()
+			fmt.Fprintf(cmd.ErrOrStderr(), "Imported %d project(s), %d label(s), %d filter(s) and %d task(s) into %s.\n",
+				len(found.Projects), len(found.Labels), len(found.Filters), len(foundTasks), into)
+
+			// Mark the imported tasks, subtasks included, so they stay managed by key.
+			var tgts []reconcile.Target
+			for _, t := range foundTasks {
+				tgts = append(tgts, reconcile.Target{Kind: reconcile.KindTask, Pattern: t.Key})
+			}
+			if len(tgts) == 0 {
+				return nil
+			}
+			opts := reconcile.Options{State: st, Targets: tgts, AdoptTasks: true}
+			plan, err := reconcile.BuildPlan(newCfg, snap, opts)
+			if err != nil {
+				return err
+			}
+			if plan.Summary.TotalChanges() == 0 {
+				return nil
+			}
+			applyRes, applyErr := reconcile.Apply(ctx, newCfg, snap, plan, reconcile.Clients{V1: v1c, Sync: syncC}, opts)
+			if applyRes == nil {
+				return applyErr
+			}
+			writeJournal(cmd.ErrOrStderr(), journalDir, reconcile.NewJournal(newCfg.Metadata.Name, snap, plan, applyRes))
+			if applyErr != nil {
+				return ExitCodeError{Code: 1, Err: fmt.Errorf("write HTD_KEY markers (rerun with `htd apply --adopt-tasks`): %w", applyErr)}
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Wrote the HTD_KEY marker to %d task(s).\n", plan.Summary.TotalChanges())
+			return nil
+		},
+	}
+	importCmd.Flags().BoolVar(&importAll, "all", false, "import every unmanaged object of the selected kinds without asking")
+	importCmd.Flags().StringArrayVar(&importMatch, "match", nil, "import objects whose name (tasks: content or key) matches this glob, without asking (repeatable)")
+	importCmd.Flags().StringSliceVar(&importOnly, "only", nil, "kinds to import: projects,labels,filters,tasks (default projects,labels,filters)")
+	importCmd.Flags().BoolVar(&importFull, "full", false, "include managed fields (color/is_favorite/view_style)")
+	importCmd.Flags().StringVar(&importInto, "into", "", "config file to add the specs to (default -f, which must then be a single file)")
+	importCmd.Flags().StringVar(&journalDir, "journal-dir", "", "where to write the journal of the marker writes, used by `htd undo` (default ~/.config/todoist/journal)")
 
 	validateCmd := &cobra.Command{
 		Use:   "validate",
 		Short: "Validate config file (no network)",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if _, err := config.Load(file); err != nil {
+			_, err := config.Load(file)
+			if outOpts.Format == output.FormatJUnit {
+				if perr := output.PrintValidateJUnit(cmd.OutOrStdout(), file, err); perr != nil {
+					return perr
+				}
+				if err != nil {
+					return ExitCodeError{Code: 1, Err: err}
+				}
+				return nil
+			}
+			if err != nil {
 				return err
 			}
 			// Keep output minimal; primary use is a smoke check in CI.
@@ -181,6 +417,69 @@ func newRootCmd() *cobra.Command {
 		},
 	}
 
+	var lintOffline bool
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check filter queries for unknown projects, sections and labels",
+		Long: "Lint parses every filter query and reports #project, /section and @label references that match " +
+			"neither the config nor the remote account. With --offline only the config is consulted (no network).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(file)
+			if err != nil {
+				return err
+			}
+
+			var snap *reconcile.Snapshot
+			if !lintOffline {
+				ctx, cancel := context.WithTimeout(cmd.Context(), 60*time.Second)
+				defer cancel()
+
+				token, _, err := auth.DiscoverToken()
+				if err != nil {
+					return err
+				}
+				logger := log.New(io.Discard, "", 0)
+				if verbose {
+					logger = log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+				}
+				httpClient := todoisthttp.New(token,
+					todoisthttp.WithVerbose(verbose),
+					todoisthttp.WithLogger(logger),
+				)
+				snap, err = fetchSnapshot(ctx, snapshotMode, token, nil, v1.New(httpClient), sync.New(httpClient))
+				if err != nil {
+					return err
+				}
+			}
+
+			issues, err := reconcile.LintFilterQueries(cfg, snap)
+			if err != nil {
+				return err
+			}
+			if jsonOut {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if issues == nil {
+					issues = []reconcile.LintIssue{}
+				}
+				if err := enc.Encode(map[string]any{"issues": issues}); err != nil {
+					return err
+				}
+			} else if len(issues) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "OK")
+			} else {
+				for _, issue := range issues {
+					fmt.Fprintln(cmd.OutOrStdout(), issue.String())
+				}
+			}
+			if len(issues) > 0 {
+				return ExitCodeError{Code: 1, Err: fmt.Errorf("%d filter query problem(s)", len(issues))}
+			}
+			return nil
+		},
+	}
+	lintCmd.Flags().BoolVar(&lintOffline, "offline", false, "only check against names declared in the config")
+
 	planCmd := &cobra.Command{
 		Use:   "plan",
 		Short: "Compute and print the plan (no mutations)",
@@ -211,31 +510,72 @@ func newRootCmd() *cobra.Command {
 			v1c := v1.New(httpClient)
 			syncC := sync.New(httpClient, sync.WithMaxCommandsPerSync(syncBatchSize))
 
-			snap, err := reconcile.FetchSnapshot(ctx, v1c, syncC)
+			st, _, err := loadState(stateFile, cfg)
+			if err != nil {
+				return err
+			}
+
+			snap, err := fetchSnapshot(ctx, snapshotMode, token, cfg, v1c, syncC)
+			if err != nil {
+				return err
+			}
+			tgts, err := reconcile.ParseTargets(targets)
 			if err != nil {
 				return err
 			}
-			plan, err := reconcile.BuildPlan(cfg, snap, reconcile.Options{Prune: prune})
+			opts := reconcile.Options{Prune: prune, State: st, Targets: tgts, AdoptTasks: adoptTasks}
+			plan, err := reconcile.BuildPlan(cfg, snap, opts)
 			if err != nil {
 				return err
 			}
-			if err := output.PrintPlan(cmd.OutOrStdout(), plan, output.Options{JSON: jsonOut}); err != nil {
+			if outOpts.Format == output.FormatJUnit {
+				err = output.PrintPlanJUnit(cmd.OutOrStdout(), cfg, plan)
+			} else {
+				err = output.PrintPlan(cmd.OutOrStdout(), plan, outOpts)
+			}
+			if err != nil {
 				return err
 			}
+			if planOut != "" {
+				pf, err := reconcile.NewPlanFile(cfg, snap, plan, opts)
+				if err != nil {
+					return err
+				}
+				if err := reconcile.WritePlanFile(planOut, pf); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Saved plan to %s; apply it with: htd apply %s\n", planOut, planOut)
+			}
 			if plan.Summary.TotalChanges() > 0 {
 				return ExitCodeError{Code: 2, Err: nil}
 			}
 			return nil
 		},
 	}
+	planCmd.Flags().StringArrayVar(&targets, "target", nil, "restrict the plan to kind/name (glob ok, repeatable), e.g. filter/Work* or project/Homelab")
+	planCmd.Flags().StringVarP(&planOut, "out", "o", "", "save the plan (with a snapshot fingerprint) to this file for a later `htd apply <file>`")
+	planCmd.Flags().BoolVar(&adoptTasks, "adopt-tasks", false, "write the HTD_KEY marker to tasks matched by id that lack it")
 
 	applyCmd := &cobra.Command{
-		Use:   "apply",
+		Use:   "apply [plan.json]",
 		Short: "Apply the plan (mutating)",
+		Long: "Apply computes a fresh plan and applies it.\n\n" +
+			"Given a plan file saved with `htd plan --out`, apply runs exactly that plan instead, " +
+			"and refuses if the config or the remote state has changed since the plan was made.",
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
 			defer cancel()
 
+			apply := reconcile.Apply
+			switch applyBackend {
+			case "rest":
+			case "sync":
+				apply = reconcile.ApplySync
+			default:
+				return fmt.Errorf("unknown --apply-backend %q (expected rest or sync)", applyBackend)
+			}
+
 			cfg, err := config.Load(file)
 			if err != nil {
 				return err
@@ -255,21 +595,163 @@ func newRootCmd() *cobra.Command {
 			v1c := v1.New(httpClient)
 			syncC := sync.New(httpClient, sync.WithMaxCommandsPerSync(syncBatchSize))
 
-			snap, err := reconcile.FetchSnapshot(ctx, v1c, syncC)
+			st, stPath, err := loadState(stateFile, cfg)
 			if err != nil {
 				return err
 			}
-			plan, err := reconcile.BuildPlan(cfg, snap, reconcile.Options{Prune: prune})
+
+			snap, err := fetchSnapshot(ctx, snapshotMode, token, cfg, v1c, syncC)
 			if err != nil {
 				return err
 			}
-			if err := output.PrintPlan(cmd.OutOrStdout(), plan, output.Options{JSON: jsonOut}); err != nil {
+			tgts, err := reconcile.ParseTargets(targets)
+			if err != nil {
+				return err
+			}
+			opts := reconcile.Options{Prune: prune, State: st, Targets: tgts, ContinueOnError: continueOnErr, AdoptTasks: adoptTasks}
+			var plan *reconcile.Plan
+			if len(args) == 1 {
+				if len(targets) > 0 {
+					return fmt.Errorf("--target cannot be combined with a saved plan (targets are fixed at plan time)")
+				}
+				pf, err := reconcile.ReadPlanFile(args[0])
+				if err != nil {
+					return err
+				}
+				if err := pf.Verify(cfg, snap); err != nil {
+					return ExitCodeError{Code: 1, Err: err}
+				}
+				// The saved plan already encodes its prune decision.
+				opts.Prune = pf.Prune
+				opts.Targets = pf.Targets
+				plan = pf.ToPlan()
+			} else {
+				plan, err = reconcile.BuildPlan(cfg, snap, opts)
+				if err != nil {
+					return err
+				}
+			}
+			if err := output.PrintPlan(cmd.OutOrStdout(), plan, outOpts); err != nil {
 				return err
 			}
 			if plan.Summary.TotalChanges() == 0 {
+				if len(opts.Targets) > 0 {
+					return nil
+				}
+				return recordState(st, stPath, cfg, snap)
+			}
+
+			if !yes {
+				ok, err := confirmApply(cmd.InOrStdin(), cmd.ErrOrStderr())
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return ExitCodeError{Code: 1, Err: fmt.Errorf("aborted")}
+				}
+			}
+
+			res, applyErr := apply(ctx, cfg, snap, plan, reconcile.Clients{V1: v1c, Sync: syncC}, opts)
+			if res == nil {
+				return applyErr
+			}
+			writeJournal(cmd.ErrOrStderr(), journalDir, reconcile.NewJournal(cfg.Metadata.Name, snap, plan, res))
+			if err := output.PrintApplyResult(cmd.OutOrStdout(), res, outOpts); err != nil {
+				return err
+			}
+			if applyErr != nil {
+				// Partially applied: leave state alone so pending renames are retried next time.
+				return ExitCodeError{Code: 1, Err: applyErr}
+			}
+
+			// Record remote IDs so later renames in YAML are planned as updates. A targeted apply
+			// leaves other resources unreconciled, and recording then would forget their pending renames.
+			if len(opts.Targets) > 0 {
 				return nil
 			}
+			after, err := fetchSnapshot(ctx, snapshotMode, token, nil, v1c, syncC)
+			if err != nil {
+				return fmt.Errorf("refresh snapshot for state: %w", err)
+			}
+			return recordState(st, stPath, cfg, after)
+		},
+	}
+	applyCmd.Flags().BoolVar(&yes, "yes", false, "skip confirmation")
+	applyCmd.Flags().StringArrayVar(&targets, "target", nil, "restrict the apply to kind/name (glob ok, repeatable), e.g. filter/Work* or project/Homelab")
+	applyCmd.Flags().BoolVar(&continueOnErr, "continue-on-error", false, "keep applying after a failed operation (its dependents are skipped); exits 1 if anything failed")
+	applyCmd.Flags().StringVar(&applyBackend, "apply-backend", "rest", "how to write changes: rest (one call per operation) or sync (batched /sync commands with temp IDs)")
+	applyCmd.Flags().StringVar(&journalDir, "journal-dir", "", "where to write the apply journal used by `htd undo` (default ~/.config/todoist/journal)")
+	applyCmd.Flags().BoolVar(&adoptTasks, "adopt-tasks", false, "write the HTD_KEY marker to tasks matched by id that lack it")
 
+	undoCmd := &cobra.Command{
+		Use:   "undo [journal.json]",
+		Short: "Revert an apply using its journal (mutating)",
+		Long: "Undo reverts the changes recorded in an apply journal: old names, colors, parents, queries and " +
+			"orders are restored, created objects are deleted, and deleted labels, filters and managed tasks " +
+			"are recreated. Deleted projects and sections cannot be restored.\n\n" +
+			"Without an argument it reverts the latest apply of the config (-f) that has not been undone yet.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+			defer cancel()
+
+			dir := journalDir
+			if dir == "" {
+				var err error
+				if dir, err = reconcile.DefaultJournalDir(); err != nil {
+					return err
+				}
+			}
+			var path string
+			if len(args) == 1 {
+				path = args[0]
+			} else {
+				cfg, err := config.Load(file)
+				if err != nil {
+					return err
+				}
+				if path, err = reconcile.LatestJournal(dir, cfg.Metadata.Name); err != nil {
+					return err
+				}
+			}
+			j, err := reconcile.ReadJournal(path)
+			if err != nil {
+				return err
+			}
+			if j.UndoneAt != nil {
+				return fmt.Errorf("journal %s was already undone at %s", path, j.UndoneAt.Format(time.RFC3339))
+			}
+
+			token, _, err := auth.DiscoverToken()
+			if err != nil {
+				return err
+			}
+			logger := log.New(io.Discard, "", 0)
+			if verbose {
+				logger = log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+			}
+			httpClient := todoisthttp.New(token,
+				todoisthttp.WithVerbose(verbose),
+				todoisthttp.WithLogger(logger),
+			)
+			v1c := v1.New(httpClient)
+			syncC := sync.New(httpClient, sync.WithMaxCommandsPerSync(syncBatchSize))
+
+			snap, err := fetchSnapshot(ctx, snapshotMode, token, nil, v1c, syncC)
+			if err != nil {
+				return err
+			}
+			plan, err := reconcile.BuildUndoPlan(j, snap)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Undoing %s (%s)\n", path, j.CreatedAt.Format(time.RFC3339))
+			if err := output.PrintPlan(cmd.OutOrStdout(), plan, outOpts); err != nil {
+				return err
+			}
+			if plan.Summary.TotalChanges() == 0 {
+				return markUndone(path, j)
+			}
 			if !yes {
 				ok, err := confirmApply(cmd.InOrStdin(), cmd.ErrOrStderr())
 				if err != nil {
@@ -280,28 +762,527 @@ func newRootCmd() *cobra.Command {
 				}
 			}
 
-			res, err := reconcile.Apply(ctx, cfg, snap, plan, reconcile.Clients{V1: v1c, Sync: syncC}, reconcile.Options{Prune: prune})
+			// Undo is best-effort: restore as much as possible and report what could not be.
+			cfg := &config.TodoistConfig{Metadata: config.Metadata{Name: j.Config}}
+			res, applyErr := reconcile.Apply(ctx, cfg, snap, plan, reconcile.Clients{V1: v1c, Sync: syncC}, reconcile.Options{ContinueOnError: true})
+			if res == nil {
+				return applyErr
+			}
+			undo := reconcile.NewJournal(j.Config, snap, plan, res)
+			undo.Undoes = path
+			writeJournal(cmd.ErrOrStderr(), dir, undo)
+			if err := output.PrintApplyResult(cmd.OutOrStdout(), res, outOpts); err != nil {
+				return err
+			}
+			if applyErr != nil {
+				return ExitCodeError{Code: 1, Err: applyErr}
+			}
+			return markUndone(path, j)
+		},
+	}
+	undoCmd.Flags().BoolVar(&yes, "yes", false, "skip confirmation")
+	undoCmd.Flags().StringVar(&journalDir, "journal-dir", "", "journal directory (default ~/.config/todoist/journal)")
+
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Save every project, section, label, filter and task (with IDs) to a JSON file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), 60*time.Second)
+			defer cancel()
+
+			token, _, err := auth.DiscoverToken()
 			if err != nil {
 				return err
 			}
-			if err := output.PrintApplyResult(cmd.OutOrStdout(), res, output.Options{JSON: jsonOut}); err != nil {
+			logger := log.New(io.Discard, "", 0)
+			if verbose {
+				logger = log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+			}
+			httpClient := todoisthttp.New(token,
+				todoisthttp.WithVerbose(verbose),
+				todoisthttp.WithLogger(logger),
+			)
+			snap, err := fetchSnapshot(ctx, snapshotMode, token, nil, v1.New(httpClient), sync.New(httpClient))
+			if err != nil {
+				return err
+			}
+			b := reconcile.NewBackup(snap)
+			if err := reconcile.WriteBackup(backupOut, b); err != nil {
 				return err
 			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Backed up %d projects, %d sections, %d labels, %d filters and %d tasks to %s\n",
+				len(b.Projects), len(b.Sections), len(b.Labels), len(b.Filters), len(b.Tasks), backupOut)
 			return nil
 		},
 	}
-	applyCmd.Flags().BoolVar(&yes, "yes", false, "skip confirmation")
+	backupCmd.Flags().StringVarP(&backupOut, "out", "o", "", "backup file to write")
+	_ = backupCmd.MarkFlagRequired("out")
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore backup.json",
+		Short: "Reconcile the account back to a backup (mutating)",
+		Long: "Restore plans the changes that bring the account back to a backup made with `htd backup`: " +
+			"missing projects, sections, labels, filters and tasks are recreated, and existing ones are " +
+			"renamed, moved and updated back. Objects created since the backup are only deleted with --prune.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+			defer cancel()
+
+			b, err := reconcile.ReadBackup(args[0])
+			if err != nil {
+				return err
+			}
+			token, _, err := auth.DiscoverToken()
+			if err != nil {
+				return err
+			}
+			logger := log.New(io.Discard, "", 0)
+			if verbose {
+				logger = log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+			}
+			httpClient := todoisthttp.New(token,
+				todoisthttp.WithVerbose(verbose),
+				todoisthttp.WithLogger(logger),
+			)
+			v1c := v1.New(httpClient)
+			syncC := sync.New(httpClient, sync.WithMaxCommandsPerSync(syncBatchSize))
+
+			snap, err := fetchSnapshot(ctx, snapshotMode, token, nil, v1c, syncC)
+			if err != nil {
+				return err
+			}
+			cfg, notes := b.RestoreConfig(snap)
+			opts := reconcile.Options{Prune: prune, ContinueOnError: continueOnErr}
+			plan, err := reconcile.BuildPlan(cfg, snap, opts)
+			if err != nil {
+				return err
+			}
+			plan.Notes = append(plan.Notes, notes...)
+			if err := output.PrintPlan(cmd.OutOrStdout(), plan, outOpts); err != nil {
+				return err
+			}
+			if plan.Summary.TotalChanges() == 0 {
+				return nil
+			}
+			if !yes {
+				ok, err := confirmApply(cmd.InOrStdin(), cmd.ErrOrStderr())
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return ExitCodeError{Code: 1, Err: fmt.Errorf("aborted")}
+				}
+			}
+
+			res, applyErr := reconcile.Apply(ctx, cfg, snap, plan, reconcile.Clients{V1: v1c, Sync: syncC}, opts)
+			if res == nil {
+				return applyErr
+			}
+			writeJournal(cmd.ErrOrStderr(), journalDir, reconcile.NewJournal(cfg.Metadata.Name, snap, plan, res))
+			if err := output.PrintApplyResult(cmd.OutOrStdout(), res, outOpts); err != nil {
+				return err
+			}
+			if applyErr != nil {
+				return ExitCodeError{Code: 1, Err: applyErr}
+			}
+			return nil
+		},
+	}
+	restoreCmd.Flags().BoolVar(&yes, "yes", false, "skip confirmation")
+	restoreCmd.Flags().BoolVar(&continueOnErr, "continue-on-error", false, "keep restoring after a failed operation (its dependents are skipped); exits 1 if anything failed")
+	restoreCmd.Flags().StringVar(&journalDir, "journal-dir", "", "where to write the journal used by `htd undo` (default ~/.config/todoist/journal)")
+
+	var watchInterval time.Duration
+	var watchNotify []string
+	var watchAutoApply bool
+	var watchOnce bool
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Periodically plan and notify webhooks about drift",
+		Long: "Watch plans the config against Todoist every --interval and, when drift appears, posts a summary " +
+			"to each --notify target. The same drift is reported once; it is reported again only after it changes " +
+			"or is resolved. The config is re-read on every check.\n\n" +
+			"Notify targets are <type>=<url> with type webhook (the event as JSON), discord (a Discord webhook) " +
+			"or ntfy (a topic URL, e.g. ntfy=https://ntfy.sh/homelab).\n\n" +
+			"With --auto-apply every operation except deletes is applied without confirmation; deletes are only reported.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apply := reconcile.Apply
+			switch applyBackend {
+			case "rest":
+			case "sync":
+				apply = reconcile.ApplySync
+			default:
+				return fmt.Errorf("unknown --apply-backend %q (expected rest or sync)", applyBackend)
+			}
+			var notifiers notify.Multi
+			for _, spec := range watchNotify {
+				n, err := notify.Parse(spec, nil)
+				if err != nil {
+					return err
+				}
+				notifiers = append(notifiers, n)
+			}
+			// Fail fast on a broken config; later checks log config errors and keep watching.
+			if _, err := config.Load(file); err != nil {
+				return err
+			}
+
+			token, _, err := auth.DiscoverToken()
+			if err != nil {
+				return err
+			}
+			logger := log.New(io.Discard, "", 0)
+			if verbose {
+				logger = log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+			}
+			httpClient := todoisthttp.New(token,
+				todoisthttp.WithVerbose(verbose),
+				todoisthttp.WithLogger(logger),
+			)
+			v1c := v1.New(httpClient)
+			syncC := sync.New(httpClient, sync.WithMaxCommandsPerSync(syncBatchSize))
+
+			w := &watch.Watcher{
+				Load: func() (*config.TodoistConfig, reconcile.Options, error) {
+					cfg, err := config.Load(file)
+					if err != nil {
+						return nil, reconcile.Options{}, err
+					}
+					st, _, err := loadState(stateFile, cfg)
+					if err != nil {
+						return nil, reconcile.Options{}, err
+					}
+					return cfg, reconcile.Options{Prune: prune, State: st}, nil
+				},
+				Fetch: func(ctx context.Context, cfg *config.TodoistConfig) (*reconcile.Snapshot, error) {
+					return fetchSnapshot(ctx, snapshotMode, token, cfg, v1c, syncC)
+				},
+				Notifier:  notifiers,
+				Logger:    log.New(cmd.ErrOrStderr(), "", log.LstdFlags),
+				AutoApply: watchAutoApply,
+				Apply:     apply,
+				Clients:   reconcile.Clients{V1: v1c, Sync: syncC},
+				AfterApply: func(cfg *config.TodoistConfig, snap *reconcile.Snapshot, plan *reconcile.Plan, res *reconcile.ApplyResult) {
+					writeJournal(cmd.ErrOrStderr(), journalDir, reconcile.NewJournal(cfg.Metadata.Name, snap, plan, res))
+				},
+			}
+
+			if watchOnce {
+				ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+				defer cancel()
+				res, err := w.Check(ctx)
+				if res == nil {
+					return err
+				}
+				if err := output.PrintPlan(cmd.OutOrStdout(), res.Plan, outOpts); err != nil {
+					return err
+				}
+				if res.Applied != nil {
+					if err := output.PrintApplyResult(cmd.OutOrStdout(), res.Applied, outOpts); err != nil {
+						return err
+					}
+				}
+				if err != nil {
+					return ExitCodeError{Code: 1, Err: err}
+				}
+				if res.Plan.Summary.TotalChanges() > 0 {
+					return ExitCodeError{Code: 2, Err: nil}
+				}
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return w.Run(ctx, watchInterval)
+		},
+	}
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Minute, "time between checks")
+	watchCmd.Flags().StringArrayVar(&watchNotify, "notify", nil, "notify target <webhook|discord|ntfy>=<url> (repeatable)")
+	watchCmd.Flags().BoolVar(&watchAutoApply, "auto-apply", false, "apply non-destructive operations (everything but deletes) automatically")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "check once and exit (exit code 2 if drift was found), e.g. from cron")
+	watchCmd.Flags().StringVar(&applyBackend, "apply-backend", "rest", "how --auto-apply writes changes: rest or sync")
+	watchCmd.Flags().StringVar(&journalDir, "journal-dir", "", "where to write journals of auto-applies (default ~/.config/todoist/journal)")
+
+	var serveMetrics string
+	var serveTextfile string
+	var serveInterval time.Duration
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Export Prometheus metrics about managed objects, pending changes and API calls",
+		Long: "Serve plans the config every --interval and exposes per-kind managed/unmanaged counts, pending " +
+			"operations, the last successful check and Todoist API request/retry metrics on --metrics (at /metrics).\n\n" +
+			"With only --textfile, serve checks once, writes the metrics to that file for node_exporter's textfile " +
+			"collector and exits (for cron).",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serveMetrics == "" && serveTextfile == "" {
+				return fmt.Errorf("serve needs --metrics <addr> and/or --textfile <path>")
+			}
+			if serveInterval <= 0 {
+				return fmt.Errorf("--interval must be positive, got %s", serveInterval)
+			}
+			if _, err := config.Load(file); err != nil {
+				return err
+			}
+			token, _, err := auth.DiscoverToken()
+			if err != nil {
+				return err
+			}
+			logger := log.New(io.Discard, "", 0)
+			if verbose {
+				logger = log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+			}
+			m := metrics.New()
+			httpClient := todoisthttp.New(token,
+				todoisthttp.WithVerbose(verbose),
+				todoisthttp.WithLogger(logger),
+				todoisthttp.WithObserver(m),
+			)
+			v1c := v1.New(httpClient)
+			syncC := sync.New(httpClient, sync.WithMaxCommandsPerSync(syncBatchSize))
+
+			check := func(ctx context.Context) error {
+				ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+				defer cancel()
+				err := func() error {
+					cfg, err := config.Load(file)
+					if err != nil {
+						return err
+					}
+					st, _, err := loadState(stateFile, cfg)
+					if err != nil {
+						return err
+					}
+					snap, err := fetchSnapshot(ctx, snapshotMode, token, cfg, v1c, syncC)
+					if err != nil {
+						return err
+					}
+					plan, err := reconcile.BuildPlan(cfg, snap, reconcile.Options{Prune: prune, State: st})
+					if err != nil {
+						return err
+					}
+					m.ObservePlan(cfg, snap, plan, time.Now())
+					return nil
+				}()
+				if err != nil {
+					m.ObserveError()
+				}
+				if serveTextfile != "" {
+					if werr := m.WriteTextfile(serveTextfile); werr != nil {
+						return errors.Join(err, werr)
+					}
+				}
+				return err
+			}
+
+			if serveMetrics == "" {
+				return check(cmd.Context())
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", m.Handler())
+			srv := &http.Server{Addr: serveMetrics, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- srv.ListenAndServe() }()
+			defer srv.Close()
+			errLog := log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+			errLog.Printf("serving metrics on %s/metrics", serveMetrics)
+
+			ticker := time.NewTicker(serveInterval)
+			defer ticker.Stop()
+			for {
+				if err := check(ctx); err != nil && ctx.Err() == nil {
+					errLog.Printf("serve: %v", err)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case err := <-serveErr:
+					return err
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+	serveCmd.Flags().StringVar(&serveMetrics, "metrics", "", "listen address for the /metrics endpoint, e.g. :9090")
+	serveCmd.Flags().StringVar(&serveTextfile, "textfile", "", "also write metrics to this file (node_exporter textfile collector); alone, check once and exit")
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", 5*time.Minute, "time between checks")
+
+	stateCmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and repair the local state (config identity -> remote ID)",
+	}
+	stateListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List state entries",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(file)
+			if err != nil {
+				return err
+			}
+			st, _, err := loadState(stateFile, cfg)
+			if err != nil {
+				return err
+			}
+			return output.PrintState(cmd.OutOrStdout(), st.Resources, outOpts)
+		},
+	}
+	stateShowCmd := &cobra.Command{
+		Use:   "show <kind>/<name>",
+		Short: "Show a single state entry",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(file)
+			if err != nil {
+				return err
+			}
+			st, _, err := loadState(stateFile, cfg)
+			if err != nil {
+				return err
+			}
+			kind, name, err := parseStateAddress(args[0])
+			if err != nil {
+				return err
+			}
+			r, ok := st.Lookup(kind, name)
+			if !ok {
+				return fmt.Errorf("no state entry for %s", args[0])
+			}
+			return output.PrintState(cmd.OutOrStdout(), []state.Resource{r}, outOpts)
+		},
+	}
+	stateRmCmd := &cobra.Command{
+		Use:   "rm <kind>/<name>...",
+		Short: "Remove state entries (the remote objects are not touched)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(file)
+			if err != nil {
+				return err
+			}
+			st, stPath, err := loadState(stateFile, cfg)
+			if err != nil {
+				return err
+			}
+			for _, a := range args {
+				kind, name, err := parseStateAddress(a)
+				if err != nil {
+					return err
+				}
+				if !st.Remove(kind, name) {
+					return fmt.Errorf("no state entry for %s", a)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", a)
+			}
+			return st.Save(stPath)
+		},
+	}
+	stateCmd.AddCommand(stateListCmd, stateShowCmd, stateRmCmd)
 
 	root.AddCommand(exportCmd)
+	root.AddCommand(importCmd)
 	root.AddCommand(validateCmd)
+	root.AddCommand(lintCmd)
 	root.AddCommand(planCmd)
 	root.AddCommand(applyCmd)
+	root.AddCommand(undoCmd)
+	root.AddCommand(backupCmd)
+	root.AddCommand(restoreCmd)
+	root.AddCommand(watchCmd)
+	root.AddCommand(serveCmd)
+	root.AddCommand(stateCmd)
 
 	return root
 }
 
+// parseKinds parses an --only list of kinds.
+func parseKinds(values []string) (map[string]bool, error) {
+	kinds := map[string]bool{}
+	for _, v := range values {
+		k := strings.ToLower(strings.TrimSpace(v))
+		switch k {
+		case "projects", "labels", "filters", "tasks":
+			kinds[k] = true
+		default:
+			return nil, fmt.Errorf("invalid --only value %q (expected projects, labels, filters, tasks)", v)
+		}
+	}
+	return kinds, nil
+}
+
+// fetchSnapshot reads remote state using the selected --snapshot-mode, and the completed tasks the
+// one_shot tasks of cfg need (cfg is nil when the snapshot is not planned against a config).
+func fetchSnapshot(ctx context.Context, mode, token string, cfg *config.TodoistConfig, v1c *v1.Client, syncC *sync.Client) (*reconcile.Snapshot, error) {
+	snap, err := readSnapshot(ctx, mode, token, v1c, syncC)
+	if err != nil {
+		return nil, err
+	}
+	if err := reconcile.FetchCompletedTasks(ctx, v1c, cfg, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// readSnapshot reads remote state using the selected --snapshot-mode.
+func readSnapshot(ctx context.Context, mode, token string, v1c *v1.Client, syncC *sync.Client) (*reconcile.Snapshot, error) {
+	switch mode {
+	case "", "rest":
+		return reconcile.FetchSnapshot(ctx, v1c, syncC)
+	case "sync":
+		return reconcile.FetchSnapshotSync(ctx, syncC)
+	case "incremental":
+		account := tokenFingerprint(token)
+		path, err := syncCachePath(account)
+		if err != nil {
+			return nil, err
+		}
+		cache, err := sync.LoadCache(path, account)
+		if err != nil {
+			return nil, err
+		}
+		snap, err := reconcile.FetchSnapshotCached(ctx, syncC, cache)
+		if err != nil {
+			return nil, err
+		}
+		if err := cache.Save(path); err != nil {
+			return nil, err
+		}
+		return snap, nil
+	default:
+		return nil, fmt.Errorf("unknown --snapshot-mode %q (expected rest, sync or incremental)", mode)
+	}
+}
+
+// syncCachePath returns the incremental sync cache for an account:
+//
+//	~/.config/todoist/cache/sync-<account>.json
+func syncCachePath(account string) (string, error) {
+	dir, err := config.ConfigDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache", "sync-"+account+".json"), nil
+}
+
+// tokenFingerprint identifies an account without storing its token.
+func tokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
 func confirmApply(in io.Reader, errOut io.Writer) (bool, error) {
-	fmt.Fprint(errOut, "Apply these changes? [y/N]: ")
+	return confirm(in, errOut, "Apply these changes?")
+}
+
+// confirm asks a yes/no question on errOut; anything but y/yes (including EOF) is no.
+func confirm(in io.Reader, errOut io.Writer, question string) (bool, error) {
+	fmt.Fprintf(errOut, "%s [y/N]: ", question)
 	var resp string
 	if _, err := fmt.Fscanln(in, &resp); err != nil {
 		// If user hits enter without typing, Fscanln returns error (unexpected newline).
@@ -311,3 +1292,62 @@ func confirmApply(in io.Reader, errOut io.Writer) (bool, error) {
 	s := strings.TrimSpace(strings.ToLower(resp))
 	return s == "y" || s == "yes", nil
 }
+
+// loadState loads the state file for cfg (honoring --state) and returns it with its path.
+func loadState(flagPath string, cfg *config.TodoistConfig) (*state.State, string, error) {
+	p := flagPath
+	if p == "" {
+		var err error
+		p, err = state.DefaultPath(cfg.Metadata.Name)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	st, err := state.Load(p, cfg.Metadata.Name)
+	if err != nil {
+		return nil, "", err
+	}
+	return st, p, nil
+}
+
+// writeJournal saves an apply journal. A failure is only a warning: the apply already happened.
+func writeJournal(errOut io.Writer, dir string, j *reconcile.Journal) {
+	if len(j.Entries) == 0 {
+		return
+	}
+	if dir == "" {
+		var err error
+		if dir, err = reconcile.DefaultJournalDir(); err != nil {
+			fmt.Fprintf(errOut, "warning: journal not written: %v\n", err)
+			return
+		}
+	}
+	path, err := reconcile.WriteJournal(dir, j)
+	if err != nil {
+		fmt.Fprintf(errOut, "warning: journal not written: %v\n", err)
+		return
+	}
+	fmt.Fprintf(errOut, "Journal: %s\n", path)
+}
+
+func markUndone(path string, j *reconcile.Journal) error {
+	now := time.Now().UTC()
+	j.UndoneAt = &now
+	return reconcile.SaveJournal(path, j)
+}
+
+func recordState(st *state.State, path string, cfg *config.TodoistConfig, snap *reconcile.Snapshot) error {
+	if err := reconcile.RecordState(st, cfg, snap); err != nil {
+		return err
+	}
+	return st.Save(path)
+}
+
+// parseStateAddress splits "kind/name" (name may itself contain slashes).
+func parseStateAddress(s string) (string, string, error) {
+	kind, name, ok := strings.Cut(s, "/")
+	if !ok || kind == "" || name == "" {
+		return "", "", fmt.Errorf("invalid state address %q (expected <kind>/<name>, e.g. project/Work)", s)
+	}
+	return kind, name, nil
+}
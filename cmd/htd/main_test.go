@@ -3,7 +3,10 @@ package main
 import (
 	"bytes"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
 )
 
 func TestValidate_ExamplesConfig(t *testing.T) {
@@ -48,3 +51,51 @@ func TestValidate_MissingFile(t *testing.T) {
 	}
 }
 
+func TestState_ListAndRemove(t *testing.T) {
+	example := filepath.Join("..", "..", "examples", "todoist.yaml")
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	st := state.New("example")
+	st.Set("project", "Work", "P1")
+	st.Set("label", "waiting", "L1")
+	if err := st.Save(statePath); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	cmd := newRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"state", "rm", "project/Work", "-f", example, "--state", statePath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("state rm failed: %v", err)
+	}
+
+	cmd = newRootCmd()
+	out.Reset()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"state", "list", "-f", example, "--state", statePath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("state list failed: %v", err)
+	}
+	if strings.Contains(out.String(), "Work") || !strings.Contains(out.String(), "waiting") {
+		t.Fatalf("unexpected state list output: %q", out.String())
+	}
+}
+
+func TestLint_OfflineExamplesConfig(t *testing.T) {
+	example := filepath.Join("..", "..", "examples", "todoist.yaml")
+
+	cmd := newRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"lint", "--offline", "-f", example})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("lint failed: %v (output %q)", err, out.String())
+	}
+	if got := out.String(); got != "OK\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/filterquery"
 )
 
 const (
@@ -37,6 +40,7 @@ type Spec struct {
 
 type PruneSpec struct {
 	Projects bool `yaml:"projects"`
+	Sections bool `yaml:"sections"`
 	Labels   bool `yaml:"labels"`
 	Filters  bool `yaml:"filters"`
 	Tasks    bool `yaml:"tasks"`
@@ -49,6 +53,15 @@ type ProjectSpec struct {
 	Color      *string `yaml:"color,omitempty"`
 	IsFavorite *bool   `yaml:"is_favorite,omitempty"`
 	ViewStyle  *string `yaml:"view_style,omitempty"`
+
+	// Sections are only managed when the key is present (nil means "leave remote sections alone").
+	Sections []SectionSpec `yaml:"sections,omitempty"`
+}
+
+type SectionSpec struct {
+	ID    *string `yaml:"id,omitempty"`
+	Name  string  `yaml:"name"`
+	Order *int    `yaml:"order,omitempty"`
 }
 
 type LabelSpec struct {
@@ -74,41 +87,135 @@ type TaskDueSpec struct {
 type TaskSpec struct {
 	ID          *string     `yaml:"id,omitempty"`
 	Key         string      `yaml:"key,omitempty"`
-	Type        *string     `yaml:"type,omitempty"` // recurring_template (MVP)
+	Type        *string     `yaml:"type,omitempty"` // recurring_template or one_shot
 	Content     string      `yaml:"content"`
 	Description *string     `yaml:"description,omitempty"`
 	Project     *string     `yaml:"project,omitempty"` // project name
+	Section     *string     `yaml:"section,omitempty"` // section name within project
 	Labels      []string    `yaml:"labels,omitempty"`
 	Priority    *int        `yaml:"priority,omitempty"` // 1..4
 	Due         TaskDueSpec `yaml:"due,omitempty"`
+
+	// Reminders are only managed when the key is present (nil means "leave remote reminders alone").
+	Reminders []TaskReminderSpec `yaml:"reminders,omitempty"`
+	// Subtasks are created under this task in list order; they live in its project.
+	Subtasks []TaskSpec `yaml:"subtasks,omitempty"`
+}
+
+// DeclaredTask is a task or subtask from the config with its place in the subtask tree.
+type DeclaredTask struct {
+	TaskSpec
+	Path   string    // e.g. "spec.tasks[0].subtasks[1]"
+	Parent *TaskSpec // nil for top-level tasks
+	Order  int       // 1-indexed position among the parent's subtasks; 0 for top-level tasks
+}
+
+// AllTasks returns every declared task, each parent before its subtasks.
+func (s Spec) AllTasks() []DeclaredTask {
+	var out []DeclaredTask
+	var walk func(tasks []TaskSpec, parent *TaskSpec, path string)
+	walk = func(tasks []TaskSpec, parent *TaskSpec, path string) {
+		for i := range tasks {
+			d := DeclaredTask{TaskSpec: tasks[i], Path: fmt.Sprintf("%s[%d]", path, i), Parent: parent}
+			if parent != nil {
+				d.Order = i + 1
+			}
+			out = append(out, d)
+			walk(tasks[i].Subtasks, &tasks[i], d.Path+".subtasks")
+		}
+	}
+	walk(s.Tasks, nil, "spec.tasks")
+	return out
+}
+
+// TaskReminderSpec is a reminder either relative to the task's due time or at a fixed time.
+type TaskReminderSpec struct {
+	MinutesBefore *int    `yaml:"minutes_before,omitempty"`
+	At            *string `yaml:"at,omitempty"` // RFC 3339, or "2006-01-02T15:04[:05]" in the account's timezone
 }
 
+// reminderLayouts are the accepted forms of TaskReminderSpec.At without a zone.
+var reminderLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// AtDate returns At as Todoist stores absolute reminders: "2006-01-02T15:04:05Z" (UTC) for times
+// with a zone or offset, "2006-01-02T15:04:05" (floating) for times without.
+func (r TaskReminderSpec) AtDate() (string, error) {
+	if r.At == nil {
+		return "", fmt.Errorf("reminder has no at")
+	}
+	if t, err := time.Parse(time.RFC3339, *r.At); err == nil {
+		return t.UTC().Format("2006-01-02T15:04:05Z"), nil
+	}
+	for _, layout := range reminderLayouts {
+		if t, err := time.Parse(layout, *r.At); err == nil {
+			return t.Format("2006-01-02T15:04:05"), nil
+		}
+	}
+	return "", fmt.Errorf("invalid reminder time %q (expected e.g. 2026-10-20T09:00 or 2026-10-20T09:00:00+02:00)", *r.At)
+}
+
+// Load reads a config from a file, a directory (every *.yaml/*.yml file in it) or a glob, following
+// `include:` entries, and returns the merged, normalized and validated result.
 func Load(path string) (*TodoistConfig, error) {
+	files, err := expandConfigPath(path)
+	if err != nil {
+		return nil, err
+	}
+	l := newLoader()
+	for _, f := range files {
+		if err := l.load(f); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := l.merge()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseFile decodes a single config file. Accept two wire formats:
+// 1) "envelope": {apiVersion, kind, metadata, spec} (old/default)
+// 2) "simple": {name, prune, projects, labels, filters}
+//
+// The simple format avoids Kubernetes-like conventions while keeping backwards compatibility.
+// Either format may list other files under a top-level `include:` key.
+func parseFile(path string) (*configFile, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read config %q: %w", path, err)
 	}
-	// Accept two wire formats:
-	// 1) "envelope": {apiVersion, kind, metadata, spec} (old/default)
-	// 2) "simple": {name, prune, projects, labels, filters}
-	//
-	// The simple format avoids Kubernetes-like conventions while keeping backwards compatibility.
 	var top map[string]any
 	if err := yaml.Unmarshal(b, &top); err != nil {
 		return nil, fmt.Errorf("parse yaml %q: %w", path, err)
 	}
 
-	var cfg TodoistConfig
+	f := &configFile{path: path}
+	var head struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(b, &head); err != nil {
+		return nil, fmt.Errorf("parse yaml %q: include must be a list of paths: %w", path, err)
+	}
+	f.include = head.Include
+
 	if _, hasSpec := top["spec"]; hasSpec || top["apiVersion"] != nil || top["kind"] != nil || top["metadata"] != nil {
-		if err := yaml.Unmarshal(b, &cfg); err != nil {
+		if err := yaml.Unmarshal(b, &f.cfg); err != nil {
 			return nil, fmt.Errorf("parse yaml %q: %w", path, err)
 		}
+		if spec, ok := top["spec"].(map[string]any); ok {
+			_, f.hasPrune = spec["prune"]
+		}
 	} else {
 		var sc simpleConfig
 		if err := yaml.Unmarshal(b, &sc); err != nil {
 			return nil, fmt.Errorf("parse yaml %q: %w", path, err)
 		}
-		cfg = TodoistConfig{
+		f.cfg = TodoistConfig{
 			Metadata: Metadata{Name: sc.Name},
 			Spec: Spec{
 				Projects: sc.Projects,
@@ -118,12 +225,9 @@ func Load(path string) (*TodoistConfig, error) {
 				Prune:    sc.Prune,
 			},
 		}
+		_, f.hasPrune = top["prune"]
 	}
-	cfg.Normalize()
-	if err := cfg.Validate(); err != nil {
-		return nil, err
-	}
-	return &cfg, nil
+	return f, nil
 }
 
 type simpleConfig struct {
@@ -162,6 +266,19 @@ func (c *TodoistConfig) Normalize() {
 			col := strings.TrimSpace(*c.Spec.Projects[i].Color)
 			c.Spec.Projects[i].Color = &col
 		}
+		for j := range c.Spec.Projects[i].Sections {
+			sec := &c.Spec.Projects[i].Sections[j]
+			sec.Name = strings.TrimSpace(sec.Name)
+			if sec.ID != nil {
+				id := strings.TrimSpace(*sec.ID)
+				sec.ID = &id
+			}
+			// Default order to list position (1-indexed), same as filters.
+			if sec.Order == nil {
+				ord := j + 1
+				sec.Order = &ord
+			}
+		}
 	}
 	for i := range c.Spec.Labels {
 		c.Spec.Labels[i].Name = strings.TrimSpace(c.Spec.Labels[i].Name)
@@ -191,32 +308,48 @@ func (c *TodoistConfig) Normalize() {
 			c.Spec.Filters[i].Order = &ord
 		}
 	}
-	for i := range c.Spec.Tasks {
-		c.Spec.Tasks[i].Key = strings.TrimSpace(c.Spec.Tasks[i].Key)
-		c.Spec.Tasks[i].Content = strings.TrimSpace(c.Spec.Tasks[i].Content)
-		if c.Spec.Tasks[i].ID != nil {
-			id := strings.TrimSpace(*c.Spec.Tasks[i].ID)
-			c.Spec.Tasks[i].ID = &id
+	normalizeTasks(c.Spec.Tasks)
+}
+
+func normalizeTasks(tasks []TaskSpec) {
+	for i := range tasks {
+		t := &tasks[i]
+		t.Key = strings.TrimSpace(t.Key)
+		t.Content = strings.TrimSpace(t.Content)
+		if t.ID != nil {
+			id := strings.TrimSpace(*t.ID)
+			t.ID = &id
 		}
-		if c.Spec.Tasks[i].Type != nil {
-			typ := strings.TrimSpace(*c.Spec.Tasks[i].Type)
-			c.Spec.Tasks[i].Type = &typ
+		if t.Type != nil {
+			typ := strings.TrimSpace(*t.Type)
+			t.Type = &typ
 		}
-		if c.Spec.Tasks[i].Description != nil {
-			d := strings.TrimSpace(*c.Spec.Tasks[i].Description)
-			c.Spec.Tasks[i].Description = &d
+		if t.Description != nil {
+			d := strings.TrimSpace(*t.Description)
+			t.Description = &d
 		}
-		if c.Spec.Tasks[i].Project != nil {
-			p := strings.TrimSpace(*c.Spec.Tasks[i].Project)
-			c.Spec.Tasks[i].Project = &p
+		if t.Project != nil {
+			p := strings.TrimSpace(*t.Project)
+			t.Project = &p
 		}
-		if c.Spec.Tasks[i].Due.String != nil {
-			ds := strings.TrimSpace(*c.Spec.Tasks[i].Due.String)
-			c.Spec.Tasks[i].Due.String = &ds
+		if t.Section != nil {
+			sec := strings.TrimSpace(*t.Section)
+			t.Section = &sec
 		}
-		for j := range c.Spec.Tasks[i].Labels {
-			c.Spec.Tasks[i].Labels[j] = strings.TrimSpace(c.Spec.Tasks[i].Labels[j])
+		if t.Due.String != nil {
+			ds := strings.TrimSpace(*t.Due.String)
+			t.Due.String = &ds
 		}
+		for j := range t.Labels {
+			t.Labels[j] = strings.TrimSpace(t.Labels[j])
+		}
+		for j, r := range t.Reminders {
+			if r.At != nil {
+				at := strings.TrimSpace(*r.At)
+				t.Reminders[j].At = &at
+			}
+		}
+		normalizeTasks(t.Subtasks)
 	}
 }
 
@@ -274,6 +407,35 @@ func (c *TodoistConfig) Validate() error {
 		errs = append(errs, err)
 	}
 
+	// Sections: names unique within their project, order positive.
+	sectionIDs := map[string]struct{}{}
+	for i, p := range c.Spec.Projects {
+		sectionNames := make(map[string]struct{}, len(p.Sections))
+		for j, sec := range p.Sections {
+			if sec.Name == "" {
+				errs = append(errs, fmt.Errorf("spec.projects[%d].sections[%d].name is required", i, j))
+				continue
+			}
+			if _, ok := sectionNames[sec.Name]; ok {
+				errs = append(errs, fmt.Errorf("duplicate section name %q in project %q", sec.Name, p.Name))
+			} else {
+				sectionNames[sec.Name] = struct{}{}
+			}
+			if sec.ID != nil {
+				if *sec.ID == "" {
+					errs = append(errs, fmt.Errorf("spec.projects[%d].sections[%d] (%q).id cannot be empty", i, j, sec.Name))
+				} else if _, ok := sectionIDs[*sec.ID]; ok {
+					errs = append(errs, fmt.Errorf("duplicate section id %q", *sec.ID))
+				} else {
+					sectionIDs[*sec.ID] = struct{}{}
+				}
+			}
+			if sec.Order == nil || *sec.Order <= 0 {
+				errs = append(errs, fmt.Errorf("spec.projects[%d].sections[%d] (%q).order must be >= 1", i, j, sec.Name))
+			}
+		}
+	}
+
 	// Labels: names unique.
 	labelNames := make(map[string]struct{}, len(c.Spec.Labels))
 	labelIDs := make(map[string]struct{}, len(c.Spec.Labels))
@@ -322,6 +484,8 @@ func (c *TodoistConfig) Validate() error {
 		}
 		if f.Query == "" {
 			errs = append(errs, fmt.Errorf("spec.filters[%d] (%q).query is required", i, f.Name))
+		} else if _, err := filterquery.Parse(f.Query); err != nil {
+			errs = append(errs, fmt.Errorf("spec.filters[%d] (%q).query is invalid: %w", i, f.Name, err))
 		}
 		if f.Order == nil || *f.Order <= 0 {
 			errs = append(errs, fmt.Errorf("spec.filters[%d] (%q).order must be >= 1", i, f.Name))
@@ -329,18 +493,20 @@ func (c *TodoistConfig) Validate() error {
 	}
 
 	// Tasks: require stable identity (id or key) and validate recurring template constraints.
-	taskIDs := make(map[string]struct{}, len(c.Spec.Tasks))
-	taskKeys := make(map[string]struct{}, len(c.Spec.Tasks))
-	for i, t := range c.Spec.Tasks {
+	// Subtasks are validated like tasks and share their id/key namespace.
+	tasks := c.Spec.AllTasks()
+	taskIDs := make(map[string]struct{}, len(tasks))
+	taskKeys := make(map[string]struct{}, len(tasks))
+	for _, t := range tasks {
 		if t.Content == "" {
-			errs = append(errs, fmt.Errorf("spec.tasks[%d].content is required", i))
+			errs = append(errs, fmt.Errorf("%s.content is required", t.Path))
 		}
 		if t.ID == nil && t.Key == "" {
-			errs = append(errs, fmt.Errorf("spec.tasks[%d] requires either id or key", i))
+			errs = append(errs, fmt.Errorf("%s requires either id or key", t.Path))
 		}
 		if t.ID != nil {
 			if *t.ID == "" {
-				errs = append(errs, fmt.Errorf("spec.tasks[%d].id cannot be empty", i))
+				errs = append(errs, fmt.Errorf("%s.id cannot be empty", t.Path))
 			} else if _, ok := taskIDs[*t.ID]; ok {
 				errs = append(errs, fmt.Errorf("duplicate task id %q", *t.ID))
 			} else {
@@ -354,22 +520,67 @@ func (c *TodoistConfig) Validate() error {
 				taskKeys[t.Key] = struct{}{}
 			}
 		}
-		if t.Type != nil && *t.Type != "" && *t.Type != "recurring_template" {
-			errs = append(errs, fmt.Errorf("spec.tasks[%d] (%q).type must be recurring_template when set", i, t.Content))
+		if t.Parent != nil && t.Project != nil {
+			errs = append(errs, fmt.Errorf("%s (%q).project cannot be set; subtasks live in their parent's project", t.Path, t.Content))
+		}
+		if t.Section != nil {
+			switch {
+			case t.Parent != nil:
+				errs = append(errs, fmt.Errorf("%s (%q).section cannot be set; subtasks live in their parent's section", t.Path, t.Content))
+			case *t.Section == "":
+				errs = append(errs, fmt.Errorf("%s (%q).section cannot be empty", t.Path, t.Content))
+			case t.Project == nil || *t.Project == "":
+				errs = append(errs, fmt.Errorf("%s (%q).section requires project", t.Path, t.Content))
+			}
+		}
+		if t.Type != nil && *t.Type != "" && *t.Type != "recurring_template" && *t.Type != "one_shot" {
+			errs = append(errs, fmt.Errorf("%s (%q).type must be recurring_template or one_shot when set", t.Path, t.Content))
 		}
 		if t.Type != nil && *t.Type == "recurring_template" && (t.Due.String == nil || *t.Due.String == "") {
-			errs = append(errs, fmt.Errorf("spec.tasks[%d] (%q) recurring_template requires due.string", i, t.Content))
+			errs = append(errs, fmt.Errorf("%s (%q) recurring_template requires due.string", t.Path, t.Content))
+		}
+		if t.Type != nil && *t.Type == "one_shot" && t.Due.String != nil && isRecurringDue(*t.Due.String) {
+			errs = append(errs, fmt.Errorf("%s (%q) one_shot cannot have a recurring due.string", t.Path, t.Content))
 		}
 		if t.Priority != nil && (*t.Priority < 1 || *t.Priority > 4) {
-			errs = append(errs, fmt.Errorf("spec.tasks[%d] (%q).priority must be in [1,4]", i, t.Content))
+			errs = append(errs, fmt.Errorf("%s (%q).priority must be in [1,4]", t.Path, t.Content))
 		}
 		for j, l := range t.Labels {
 			if l == "" {
-				errs = append(errs, fmt.Errorf("spec.tasks[%d].labels[%d] cannot be empty", i, j))
+				errs = append(errs, fmt.Errorf("%s.labels[%d] cannot be empty", t.Path, j))
 			}
 		}
 		if t.Due.String != nil && *t.Due.String == "" {
-			errs = append(errs, fmt.Errorf("spec.tasks[%d] (%q).due.string cannot be empty when set", i, t.Content))
+			errs = append(errs, fmt.Errorf("%s (%q).due.string cannot be empty when set", t.Path, t.Content))
+		}
+		seenReminders := map[string]int{}
+		for j, r := range t.Reminders {
+			key := ""
+			switch {
+			case r.MinutesBefore != nil:
+				key = fmt.Sprintf("minutes_before %d", *r.MinutesBefore)
+			case r.At != nil:
+				if at, err := r.AtDate(); err == nil {
+					key = "at " + at
+				}
+			}
+			if prev, ok := seenReminders[key]; ok && key != "" {
+				errs = append(errs, fmt.Errorf("%s (%q).reminders[%d] duplicates reminders[%d]", t.Path, t.Content, j, prev))
+			} else if key != "" {
+				seenReminders[key] = j
+			}
+			switch {
+			case (r.MinutesBefore == nil) == (r.At == nil):
+				errs = append(errs, fmt.Errorf("%s (%q).reminders[%d] requires exactly one of minutes_before or at", t.Path, t.Content, j))
+			case r.MinutesBefore != nil && *r.MinutesBefore < 0:
+				errs = append(errs, fmt.Errorf("%s (%q).reminders[%d].minutes_before cannot be negative", t.Path, t.Content, j))
+			case r.MinutesBefore != nil && (t.Due.String == nil || *t.Due.String == ""):
+				errs = append(errs, fmt.Errorf("%s (%q).reminders[%d] is relative to the due time and requires due.string", t.Path, t.Content, j))
+			case r.At != nil:
+				if _, err := r.AtDate(); err != nil {
+					errs = append(errs, fmt.Errorf("%s (%q).reminders[%d]: %w", t.Path, t.Content, j, err))
+				}
+			}
 		}
 	}
 
@@ -431,6 +642,17 @@ func validateProjectAcyclic(projects []ProjectSpec) error {
 	return nil
 }
 
+// isRecurringDue reports whether a due string repeats: it starts with "every" or with one of the
+// synonyms Todoist accepts for it ("daily", "weekly", ...).
+func isRecurringDue(s string) bool {
+	first, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(s)), " ")
+	switch first {
+	case "daily", "weekly", "monthly", "yearly", "annually":
+		return true
+	}
+	return strings.HasPrefix(first, "every")
+}
+
 func reverseStrings(s []string) {
 	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
 		s[i], s[j] = s[j], s[i]
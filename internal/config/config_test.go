@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -72,3 +74,242 @@ prune:
 		t.Fatalf("Load: %v", err)
 	}
 }
+
+func TestValidate_TaskReminders(t *testing.T) {
+	due := "every month on the 1st at 9am"
+	at := " 2026-11-01T09:00 "
+	cfg := &TodoistConfig{
+		Metadata: Metadata{Name: "t"},
+		Spec: Spec{Tasks: []TaskSpec{{
+			Key:       "rent",
+			Content:   "Pay rent",
+			Due:       TaskDueSpec{String: &due},
+			Reminders: []TaskReminderSpec{{MinutesBefore: new(int)}, {At: &at}},
+		}}},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got, _ := cfg.Spec.Tasks[0].Reminders[1].AtDate(); got != "2026-11-01T09:00:00" {
+		t.Fatalf("unexpected floating reminder date %q", got)
+	}
+
+	for name, mutate := range map[string]func(t *TaskSpec){
+		"both set": func(t *TaskSpec) { t.Reminders[0].At = &at },
+		"negative": func(t *TaskSpec) { n := -5; t.Reminders[0].MinutesBefore = &n },
+		"no due":   func(t *TaskSpec) { t.Due.String = nil },
+		"bad time": func(t *TaskSpec) { bad := "tomorrow"; t.Reminders[1].At = &bad },
+		"duplicate": func(t *TaskSpec) {
+			same := "2026-11-01 09:00"
+			t.Reminders = append(t.Reminders, TaskReminderSpec{At: &same})
+		},
+		"neither field": func(t *TaskSpec) { t.Reminders = append(t.Reminders, TaskReminderSpec{}) },
+	} {
+		c := *cfg
+		task := cfg.Spec.Tasks[0]
+		task.Reminders = append([]TaskReminderSpec(nil), task.Reminders...)
+		mutate(&task)
+		c.Spec.Tasks = []TaskSpec{task}
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
+
+func TestValidate_Subtasks(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "subtasks.yaml")
+	if err := os.WriteFile(p, []byte(`
+name: t
+tasks:
+  - key: review
+    content: Weekly review
+    project: Work
+    subtasks:
+      - key: review-inbox
+        content: " Inbox zero "
+      - key: review-plan
+        content: Plan week
+        subtasks:
+          - key: review-plan-cal
+            content: Check calendar
+`), 0o600); err != nil {
+		t.Fatalf("write temp config: %v", err)
+	}
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var got []string
+	for _, d := range cfg.Spec.AllTasks() {
+		parent := ""
+		if d.Parent != nil {
+			parent = d.Parent.Key
+		}
+		got = append(got, fmt.Sprintf("%s %q parent=%s order=%d", d.Path, d.Content, parent, d.Order))
+	}
+	want := []string{
+		`spec.tasks[0] "Weekly review" parent= order=0`,
+		`spec.tasks[0].subtasks[0] "Inbox zero" parent=review order=1`,
+		`spec.tasks[0].subtasks[1] "Plan week" parent=review order=2`,
+		`spec.tasks[0].subtasks[1].subtasks[0] "Check calendar" parent=review-plan order=1`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected tasks:\n%s", strings.Join(got, "\n"))
+	}
+
+	project := "Home"
+	cfg.Spec.Tasks[0].Subtasks[1].Project = &project
+	cfg.Spec.Tasks[0].Subtasks[0].Key = "review"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `spec.tasks[0].subtasks[1] ("Plan week").project cannot be set`) || !strings.Contains(err.Error(), `duplicate task key "review"`) {
+		t.Fatalf("expected subtask project and duplicate key errors, got %v", err)
+	}
+}
+
+func TestValidate_Sections(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "sections.yaml")
+	if err := os.WriteFile(p, []byte(`
+name: t
+projects:
+  - name: Homelab
+    sections:
+      - name: Backlog
+      - name: Doing
+        order: 5
+  - name: Work
+    sections:
+      - name: Backlog
+`), 0o600); err != nil {
+		t.Fatalf("write temp config: %v", err)
+	}
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	secs := cfg.Spec.Projects[0].Sections
+	if *secs[0].Order != 1 || *secs[1].Order != 5 {
+		t.Fatalf("unexpected section orders: %d, %d", *secs[0].Order, *secs[1].Order)
+	}
+
+	ord := 3
+	cfg.Spec.Projects[0].Sections = append(cfg.Spec.Projects[0].Sections, SectionSpec{Name: "Doing", Order: &ord})
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("expected duplicate section name error")
+	}
+}
+
+func TestLoad_MultiFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return p
+	}
+
+	main := write("todoist.yaml", `
+name: split
+include:
+  - parts/*.yaml
+prune:
+  projects: true
+projects:
+  - name: Work
+`)
+	write("parts/labels.yaml", `
+labels:
+  - name: waiting
+`)
+	write("parts/filters.yaml", `
+filters:
+  - name: Waiting at work
+    query: "#Work & @waiting"
+`)
+
+	cfg, err := Load(main)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Metadata.Name != "split" || !cfg.Spec.Prune.Projects {
+		t.Fatalf("expected name and prune from the main file, got %#v", cfg)
+	}
+	if len(cfg.Spec.Projects) != 1 || len(cfg.Spec.Labels) != 1 || len(cfg.Spec.Filters) != 1 {
+		t.Fatalf("expected merged spec, got %#v", cfg.Spec)
+	}
+
+	// A directory loads every YAML file in it (parts/ is reached via include, and only once).
+	if _, err := Load(dir); err != nil {
+		t.Fatalf("Load(dir): %v", err)
+	}
+
+	dup := write("parts/more-labels.yaml", `
+labels:
+  - name: waiting
+`)
+	_, err = Load(main)
+	if err == nil {
+		t.Fatalf("expected duplicate label error")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "labels.yaml") || !strings.Contains(msg, filepath.Base(dup)) {
+		t.Fatalf("expected both file names in error, got %q", msg)
+	}
+}
+
+func TestValidate_TaskSection(t *testing.T) {
+	section, empty := " Doing ", " "
+	cfg := &TodoistConfig{
+		Metadata: Metadata{Name: "t"},
+		Spec: Spec{Tasks: []TaskSpec{
+			{Key: "review", Content: "Weekly review", Project: &section, Section: &section, Subtasks: []TaskSpec{
+				{Key: "review-inbox", Content: "Inbox zero", Section: &section},
+			}},
+			{Key: "loose", Content: "Loose", Section: &section},
+			{Key: "blank", Content: "Blank", Project: &section, Section: &empty},
+		}},
+	}
+	cfg.Normalize()
+	if got := *cfg.Spec.Tasks[0].Section; got != "Doing" {
+		t.Fatalf("expected section to be trimmed, got %q", got)
+	}
+	err := cfg.Validate()
+	for _, want := range []string{
+		`spec.tasks[0].subtasks[0] ("Inbox zero").section cannot be set`,
+		`spec.tasks[1] ("Loose").section requires project`,
+		`spec.tasks[2] ("Blank").section cannot be empty`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "spec.tasks[0] (") {
+		t.Fatalf("unexpected error for a task with project and section: %v", err)
+	}
+}
+
+func TestValidate_OneShotTask(t *testing.T) {
+	oneShot, once, recurring := "one_shot", "next friday", "Every week"
+	cfg := &TodoistConfig{
+		Metadata: Metadata{Name: "t"},
+		Spec: Spec{Tasks: []TaskSpec{
+			{Key: "certs", Type: &oneShot, Content: "Rotate homelab certs", Due: TaskDueSpec{String: &once}},
+			{Key: "setup", Type: &oneShot, Content: "Set up backups"},
+		}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	for _, due := range []string{recurring, "daily", "Weekly on monday", "annually"} {
+		cfg.Spec.Tasks[1].Due.String = &due
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `("Set up backups") one_shot cannot have a recurring due.string`) {
+			t.Fatalf("expected recurring due error for %q, got %v", due, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// configFile is one parsed file of a (possibly multi-file) config.
+type configFile struct {
+	path     string
+	cfg      TodoistConfig
+	include  []string
+	hasPrune bool
+}
+
+// loader collects config files in load order, following includes and skipping files already seen.
+type loader struct {
+	files []*configFile
+	seen  map[string]bool
+	stack []string
+}
+
+func newLoader() *loader { return &loader{seen: map[string]bool{}} }
+
+func (l *loader) load(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolve config path %q: %w", path, err)
+	}
+	for _, p := range l.stack {
+		if p == abs {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(l.stack, " -> "), abs)
+		}
+	}
+	if l.seen[abs] {
+		return nil
+	}
+	l.seen[abs] = true
+
+	f, err := parseFile(path)
+	if err != nil {
+		return err
+	}
+	l.files = append(l.files, f)
+
+	l.stack = append(l.stack, abs)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+	for _, inc := range f.include {
+		inc = strings.TrimSpace(inc)
+		if inc == "" {
+			return fmt.Errorf("%s: include entries cannot be empty", path)
+		}
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(path), inc)
+		}
+		files, err := expandConfigPath(inc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, incFile := range files {
+			if err := l.load(incFile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge concatenates all files into one config. name and prune may be set in at most one file, and
+// every project, label, filter and task must be defined once; conflicts name both files.
+func (l *loader) merge() (*TodoistConfig, error) {
+	if len(l.files) == 1 {
+		return &l.files[0].cfg, nil
+	}
+
+	var out TodoistConfig
+	var errs []error
+	var nameFrom, pruneFrom string
+	defined := map[string]string{}
+	define := func(kind, name, file string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		k := kind + "\x00" + name
+		if prev, ok := defined[k]; ok {
+			errs = append(errs, fmt.Errorf("duplicate %s %q (defined in %s and %s)", kind, name, prev, file))
+			return
+		}
+		defined[k] = file
+	}
+
+	for _, f := range l.files {
+		c := f.cfg
+		if c.APIVersion != "" {
+			out.APIVersion = c.APIVersion
+		}
+		if c.Kind != "" {
+			out.Kind = c.Kind
+		}
+		if name := strings.TrimSpace(c.Metadata.Name); name != "" {
+			if nameFrom != "" && name != strings.TrimSpace(out.Metadata.Name) {
+				errs = append(errs, fmt.Errorf("name set to %q in %s and %q in %s; set it in one file", out.Metadata.Name, nameFrom, c.Metadata.Name, f.path))
+			} else if nameFrom == "" {
+				out.Metadata.Name = c.Metadata.Name
+				nameFrom = f.path
+			}
+		}
+		if f.hasPrune {
+			if pruneFrom != "" {
+				errs = append(errs, fmt.Errorf("prune set in both %s and %s; set it in one file", pruneFrom, f.path))
+			} else {
+				out.Spec.Prune = c.Spec.Prune
+				pruneFrom = f.path
+			}
+		}
+
+		for _, p := range c.Spec.Projects {
+			define("project", p.Name, f.path)
+			if p.ID != nil {
+				define("project id", *p.ID, f.path)
+			}
+			for _, sec := range p.Sections {
+				if sec.ID != nil {
+					define("section id", *sec.ID, f.path)
+				}
+			}
+		}
+		for _, lb := range c.Spec.Labels {
+			define("label", lb.Name, f.path)
+			if lb.ID != nil {
+				define("label id", *lb.ID, f.path)
+			}
+		}
+		for _, fl := range c.Spec.Filters {
+			define("filter", fl.Name, f.path)
+			if fl.ID != nil {
+				define("filter id", *fl.ID, f.path)
+			}
+		}
+		for _, t := range c.Spec.AllTasks() {
+			define("task key", t.Key, f.path)
+			if t.ID != nil {
+				define("task id", *t.ID, f.path)
+			}
+		}
+
+		out.Spec.Projects = append(out.Spec.Projects, c.Spec.Projects...)
+		out.Spec.Labels = append(out.Spec.Labels, c.Spec.Labels...)
+		out.Spec.Filters = append(out.Spec.Filters, c.Spec.Filters...)
+		out.Spec.Tasks = append(out.Spec.Tasks, c.Spec.Tasks...)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &out, nil
+}
+
+// expandConfigPath turns a -f/include argument into config files: a directory yields its
+// *.yaml/*.yml files (sorted, not recursive), a glob its sorted matches, anything else itself.
+func expandConfigPath(path string) ([]string, error) {
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config glob %q: %w", path, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("config glob %q matched no files", path)
+		}
+		sort.Strings(matches)
+		return matches, nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %q: %w", path, err)
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config dir %q: %w", path, err)
+	}
+	var files []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		files = append(files, filepath.Join(path, e.Name()))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("config dir %q contains no .yaml/.yml files", path)
+	}
+	return files, nil
+}
@@ -3,17 +3,24 @@ package export
 import (
 	"fmt"
 	"sort"
+	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/erauner/homelab-todoist-declarative/internal/config"
 	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
 )
 
 type Options struct {
 	Full         bool
 	IncludeInbox bool
 	IncludeIDs   bool
+
+	// Tasks exports recurring tasks and tasks already managed by htd (with an HTD_KEY: marker).
+	Tasks bool
 }
 
 // SimpleConfig is the preferred wire format for htd configs (no apiVersion/kind/metadata/spec envelope).
@@ -23,6 +30,7 @@ type SimpleConfig struct {
 	Projects []config.ProjectSpec `yaml:"projects" json:"projects"`
 	Labels   []config.LabelSpec   `yaml:"labels" json:"labels"`
 	Filters  []config.FilterSpec  `yaml:"filters" json:"filters"`
+	Tasks    []config.TaskSpec    `yaml:"tasks,omitempty" json:"tasks,omitempty"`
 }
 
 func FromSnapshot(name string, snap *reconcile.Snapshot, opts Options) (*SimpleConfig, error) {
@@ -178,9 +186,185 @@ func FromSnapshot(name string, snap *reconcile.Snapshot, opts Options) (*SimpleC
 		out.Filters = append(out.Filters, fs)
 	}
 
+	if opts.Tasks {
+		// Subtasks of exported tasks (e.g. the steps of a recurring checklist) come along.
+		var include func(t v1.Task, managed bool) bool
+		include = func(t v1.Task, managed bool) bool {
+			if managed || (t.Due != nil && t.Due.IsRecurring) {
+				return true
+			}
+			if t.ParentID == nil {
+				return false
+			}
+			parent, ok := snap.TaskByID(*t.ParentID)
+			if !ok {
+				return false
+			}
+			_, parentManaged := reconcile.ManagedTaskKey(parent.Description)
+			return include(parent, parentManaged)
+		}
+		out.Tasks = exportTasks(snap, opts, map[string]bool{}, include)
+	}
+
 	return out, nil
 }
 
+// exportTasks emits the tasks selected by include, ordered by project and content, with selected
+// subtasks nested under their parent in child order. Managed tasks keep their key; others get a key
+// derived from their content (unique among used) and are pinned by id, because they have no
+// HTD_KEY: marker yet (see reconcile.Options.AdoptTasks).
+func exportTasks(snap *reconcile.Snapshot, opts Options, used map[string]bool, include func(t v1.Task, managed bool) bool) []config.TaskSpec {
+	type task struct {
+		v1.Task
+		project string
+		key     string
+		managed bool
+	}
+	var tasks []task
+	for _, t := range snap.Tasks {
+		key, managed := reconcile.ManagedTaskKey(t.Description)
+		if !include(t, managed) {
+			continue
+		}
+		project, _ := snap.ProjectNameByID(t.ProjectID)
+		if managed {
+			used[key] = true
+		}
+		tasks = append(tasks, task{Task: t, project: project, key: key, managed: managed})
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].project != tasks[j].project {
+			return tasks[i].project < tasks[j].project
+		}
+		if tasks[i].Content != tasks[j].Content {
+			return tasks[i].Content < tasks[j].Content
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+
+	out := make([]config.TaskSpec, 0, len(tasks))
+	for _, t := range tasks {
+		ts := config.TaskSpec{Key: t.key, Content: t.Content}
+		if !t.managed {
+			ts.Key = uniqueTaskKey(t.Content, used)
+		}
+		if !t.managed || opts.IncludeIDs {
+			id := t.ID
+			ts.ID = &id
+		}
+		if t.Due != nil && t.Due.IsRecurring {
+			typ := "recurring_template"
+			ts.Type = &typ
+		}
+		if d := reconcile.TaskDescriptionSansManagedKey(t.Description); d != "" {
+			ts.Description = &d
+		}
+		if t.project != "" {
+			p := t.project
+			ts.Project = &p
+			if t.SectionID != nil {
+				if sec, ok := snap.SectionByID(*t.SectionID); ok {
+					name := sec.Name
+					ts.Section = &name
+				}
+			}
+		}
+		if len(t.Labels) > 0 {
+			ts.Labels = append([]string(nil), t.Labels...)
+			sort.Strings(ts.Labels)
+		}
+		if t.Priority > 1 {
+			p := t.Priority
+			ts.Priority = &p
+		}
+		if t.Due != nil && t.Due.String != "" {
+			ds := t.Due.String
+			ts.Due.String = &ds
+		}
+		ts.Reminders = exportReminders(snap.RemindersForTask(t.ID), ts.Due.String != nil)
+		out = append(out, ts)
+	}
+
+	index := map[string]int{}
+	for i, t := range tasks {
+		index[t.ID] = i
+	}
+	children := map[int][]int{}
+	var roots []int
+	for i, t := range tasks {
+		if t.ParentID != nil {
+			if pi, ok := index[*t.ParentID]; ok {
+				children[pi] = append(children[pi], i)
+				continue
+			}
+		}
+		roots = append(roots, i)
+	}
+	var nest func(i int) config.TaskSpec
+	nest = func(i int) config.TaskSpec {
+		ts := out[i]
+		kids := children[i]
+		sort.SliceStable(kids, func(a, b int) bool { return tasks[kids[a]].ChildOrder < tasks[kids[b]].ChildOrder })
+		for _, c := range kids {
+			sub := nest(c)
+			sub.Project, sub.Section = nil, nil
+			ts.Subtasks = append(ts.Subtasks, sub)
+		}
+		return ts
+	}
+	nested := make([]config.TaskSpec, 0, len(roots))
+	for _, i := range roots {
+		nested = append(nested, nest(i))
+	}
+	return nested
+}
+
+// exportReminders returns the relative (only when the task has a due date) and absolute reminders
+// of a task, or nil when it has none so the exported task leaves reminders unmanaged.
+func exportReminders(reminders []sync.Reminder, hasDue bool) []config.TaskReminderSpec {
+	var out []config.TaskReminderSpec
+	for _, r := range reminders {
+		switch {
+		case r.Type == "relative" && hasDue:
+			m := r.MinuteOffset
+			out = append(out, config.TaskReminderSpec{MinutesBefore: &m})
+		case r.Type == "absolute" && r.Due != nil:
+			at := r.Due.Date
+			out = append(out, config.TaskReminderSpec{At: &at})
+		}
+	}
+	return out
+}
+
+// uniqueTaskKey derives a key from content ("Weekly review!" -> "weekly-review"), adding a numeric
+// suffix when the key is taken.
+func uniqueTaskKey(content string, used map[string]bool) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(content) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	base := strings.TrimRight(b.String(), "-")
+	if r := []rune(base); len(r) > 40 {
+		base = strings.TrimRight(string(r[:40]), "-")
+	}
+	if base == "" {
+		base = "task"
+	}
+	key := base
+	for n := 2; used[key]; n++ {
+		key = fmt.Sprintf("%s-%d", base, n)
+	}
+	used[key] = true
+	return key
+}
+
 func (c *SimpleConfig) ToYAML() ([]byte, error) {
 	return yaml.Marshal(c)
 }
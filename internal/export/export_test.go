@@ -1,9 +1,17 @@
 package export
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
 	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/fake"
+	todoisthttp "github.com/erauner/homelab-todoist-declarative/internal/todoist/http"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
 )
@@ -98,3 +106,135 @@ func TestFromSnapshot_Full(t *testing.T) {
 		t.Fatalf("expected filter favorite true, got %#v", cfg.Filters[0].IsFavorite)
 	}
 }
+
+func TestFromSnapshot_TasksRoundTrip(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	home := srv.AddProject(v1.Project{Name: "Home"})
+	srv.AddTask(v1.Task{Content: "Water plants!", ProjectID: home.ID, Labels: []string{"home", "chores"}, Priority: 3, Due: &v1.Due{String: "every 3 days", IsRecurring: true}})
+	sunday := srv.AddTask(v1.Task{Content: "Water plants", ProjectID: home.ID, Due: &v1.Due{String: "every sunday", IsRecurring: true}})
+	srv.AddReminder(sync.Reminder{ItemID: sunday.ID, Type: "relative", MinuteOffset: 30})
+	srv.AddReminder(sync.Reminder{ItemID: sunday.ID, Type: "absolute", Due: &sync.ReminderDue{Date: "2026-11-01T09:00:00Z"}})
+	srv.AddTask(v1.Task{Content: "Check soil", ParentID: &sunday.ID, ChildOrder: 1})
+	maintenance := srv.AddSection(v1.Section{Name: "Maintenance", ProjectID: home.ID})
+	srv.AddTask(v1.Task{Content: "Patch servers", Description: "Check the NAS too.\nHTD_KEY:patch", ProjectID: home.ID, SectionID: &maintenance.ID})
+	srv.AddTask(v1.Task{Content: "Buy milk", ProjectID: home.ID})
+	srv.AddLabel(v1.Label{Name: "home"})
+	srv.AddLabel(v1.Label{Name: "chores"})
+
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	clients := reconcile.Clients{V1: v1.New(h), Sync: sync.New(h)}
+	ctx := context.Background()
+	snap, err := reconcile.FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	exported, err := FromSnapshot("tasks", snap, Options{Tasks: true})
+	if err != nil {
+		t.Fatalf("FromSnapshot: %v", err)
+	}
+
+	var got []string
+	for _, ts := range exported.Tasks {
+		s := fmt.Sprintf("%s %q project=%s", ts.Key, ts.Content, *ts.Project)
+		if ts.Section != nil {
+			s += " section=" + *ts.Section
+		}
+		if ts.ID != nil {
+			s += " id"
+		}
+		if ts.Type != nil {
+			s += " " + *ts.Type + " " + *ts.Due.String
+		}
+		if ts.Priority != nil {
+			s += fmt.Sprintf(" p%d", *ts.Priority)
+		}
+		if len(ts.Labels) > 0 {
+			s += fmt.Sprintf(" %v", ts.Labels)
+		}
+		if ts.Description != nil {
+			s += fmt.Sprintf(" desc=%q", *ts.Description)
+		}
+		for _, r := range ts.Reminders {
+			s += " " + reconcile.ReminderName("reminder", r)
+		}
+		for _, sub := range ts.Subtasks {
+			s += fmt.Sprintf(" subtask=%s", sub.Key)
+			if sub.Project != nil || sub.Section != nil || sub.ID == nil {
+				s += "(unexpected project or missing id)"
+			}
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		`patch "Patch servers" project=Home section=Maintenance desc="Check the NAS too."`,
+		`water-plants "Water plants" project=Home id recurring_template every sunday reminder/30 min before reminder/at 2026-11-01T09:00:00Z subtask=check-soil`,
+		`water-plants-2 "Water plants!" project=Home id recurring_template every 3 days p3 [chores home]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected tasks:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	b, err := exported.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("exported config does not load: %v", err)
+	}
+
+	// Without adoption the exported tasks already match; the missing markers are only noted.
+	plan, err := reconcile.BuildPlan(cfg, snap, reconcile.Options{})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.TotalChanges() != 0 || !hasNote(plan, "--adopt-tasks") {
+		t.Fatalf("expected no changes and an adoption note, got %+v", plan)
+	}
+
+	plan, err = reconcile.BuildPlan(cfg, snap, reconcile.Options{AdoptTasks: true})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.Update != 3 || plan.Summary.TotalChanges() != 3 {
+		t.Fatalf("expected three key updates, got %+v", plan.Operations)
+	}
+	if _, err := reconcile.Apply(ctx, cfg, snap, plan, clients, reconcile.Options{AdoptTasks: true}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	keys := map[string]bool{}
+	for _, task := range srv.Tasks() {
+		if key, ok := reconcile.ManagedTaskKey(task.Description); ok {
+			keys[key] = true
+		}
+	}
+	if !keys["water-plants"] || !keys["water-plants-2"] || !keys["patch"] || !keys["check-soil"] {
+		t.Fatalf("expected all exported tasks to carry markers, got %v", keys)
+	}
+
+	snap, err = reconcile.FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	plan, err = reconcile.BuildPlan(cfg, snap, reconcile.Options{AdoptTasks: true})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.TotalChanges() != 0 || hasNote(plan, "--adopt-tasks") {
+		t.Fatalf("expected a clean plan after adoption, got %+v", plan)
+	}
+}
+
+func hasNote(plan *reconcile.Plan, substr string) bool {
+	for _, n := range plan.Notes {
+		if strings.Contains(n, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package export
+
+import (
+	"fmt"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// Unmanaged exports the remote resources cfg does not declare (see reconcile.Unmanaged), as specs
+// to append to a config. Unlike FromSnapshot it includes every unmanaged task, recurring or not;
+// tasks without an HTD_KEY: marker get a key that is unused in cfg and remotely, and their id.
+func Unmanaged(cfg *config.TodoistConfig, snap *reconcile.Snapshot, st *state.State, opts Options) (*SimpleConfig, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is nil")
+	}
+	unmanaged := reconcile.Unmanaged(cfg, snap, st)
+	all, err := FromSnapshot("import", snap, Options{Full: opts.Full, IncludeIDs: true})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &SimpleConfig{Name: cfg.Metadata.Name}
+	for _, p := range all.Projects {
+		if unmanaged[reconcile.KindProject][*p.ID] {
+			if !opts.IncludeIDs {
+				p.ID = nil
+			}
+			out.Projects = append(out.Projects, p)
+		}
+	}
+	for _, l := range all.Labels {
+		if unmanaged[reconcile.KindLabel][*l.ID] {
+			if !opts.IncludeIDs {
+				l.ID = nil
+			}
+			out.Labels = append(out.Labels, l)
+		}
+	}
+	for _, f := range all.Filters {
+		if unmanaged[reconcile.KindFilter][*f.ID] {
+			if !opts.IncludeIDs {
+				f.ID = nil
+			}
+			out.Filters = append(out.Filters, f)
+		}
+	}
+
+	used := map[string]bool{}
+	for _, t := range cfg.Spec.AllTasks() {
+		used[t.Key] = true
+	}
+	for _, t := range snap.Tasks {
+		if key, ok := reconcile.ManagedTaskKey(t.Description); ok {
+			used[key] = true
+		}
+	}
+	out.Tasks = exportTasks(snap, opts, used, func(t v1.Task, _ bool) bool {
+		return unmanaged[reconcile.KindTask][t.ID]
+	})
+	return out, nil
+}
+
+// Select keeps the resources for which keep returns true (key is only set for tasks) and returns
+// notes for what it kept on top: the unselected parents of selected projects and subtasks, which
+// a config cannot leave out.
+func (c *SimpleConfig) Select(keep func(kind reconcile.Kind, name, key string) bool) []string {
+	byName := map[string]config.ProjectSpec{}
+	for _, p := range c.Projects {
+		byName[p.Name] = p
+	}
+	kept := map[string]bool{}
+	for _, p := range c.Projects {
+		if keep(reconcile.KindProject, p.Name, "") {
+			kept[p.Name] = true
+		}
+	}
+	var notes []string
+	for _, p := range c.Projects {
+		if !kept[p.Name] {
+			continue
+		}
+		for child := p; child.Parent != nil; {
+			parent, ok := byName[*child.Parent]
+			if !ok || kept[parent.Name] {
+				break
+			}
+			kept[parent.Name] = true
+			notes = append(notes, fmt.Sprintf("project %q is included as the parent of %q", parent.Name, child.Name))
+			child = parent
+		}
+	}
+	projects := c.Projects[:0]
+	for _, p := range c.Projects {
+		if kept[p.Name] {
+			projects = append(projects, p)
+		}
+	}
+	c.Projects = projects
+
+	labels := c.Labels[:0]
+	for _, l := range c.Labels {
+		if keep(reconcile.KindLabel, l.Name, "") {
+			labels = append(labels, l)
+		}
+	}
+	c.Labels = labels
+
+	filters := c.Filters[:0]
+	for _, f := range c.Filters {
+		if keep(reconcile.KindFilter, f.Name, "") {
+			filters = append(filters, f)
+		}
+	}
+	c.Filters = filters
+
+	c.Tasks = selectTasks(c.Tasks, keep, &notes)
+	return notes
+}
+
+// selectTasks keeps the tasks for which keep returns true, walking subtasks, and the unselected
+// parents of selected subtasks (noting them), since a subtask is declared under its parent.
+func selectTasks(tasks []config.TaskSpec, keep func(kind reconcile.Kind, name, key string) bool, notes *[]string) []config.TaskSpec {
+	var out []config.TaskSpec
+	for _, t := range tasks {
+		kept := keep(reconcile.KindTask, t.Content, t.Key)
+		t.Subtasks = selectTasks(t.Subtasks, keep, notes)
+		if kept {
+			out = append(out, t)
+			continue
+		}
+		if len(t.Subtasks) > 0 {
+			*notes = append(*notes, fmt.Sprintf("task %q is included as the parent of %q", t.Content, t.Subtasks[0].Content))
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Count is the number of resources in c, subtasks included.
+func (c *SimpleConfig) Count() int {
+	return len(c.Projects) + len(c.Labels) + len(c.Filters) + len(config.Spec{Tasks: c.Tasks}.AllTasks())
+}
@@ -0,0 +1,215 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/fake"
+	todoisthttp "github.com/erauner/homelab-todoist-declarative/internal/todoist/http"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+func TestUnmanaged_ImportIntoConfig(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	srv.AddProject(v1.Project{Name: "Homelab"})
+	personal := srv.AddProject(v1.Project{Name: "Personal"})
+	garden := srv.AddProject(v1.Project{Name: "Garden", ParentID: &personal.ID})
+	srv.AddLabel(v1.Label{Name: "waiting"})
+	srv.AddLabel(v1.Label{Name: "someday"})
+	srv.AddTask(v1.Task{Content: "Water plants", ProjectID: garden.ID, Due: &v1.Due{String: "every 3 days", IsRecurring: true}})
+	srv.AddTask(v1.Task{Content: "Buy milk", ProjectID: garden.ID})
+	srv.AddTask(v1.Task{Content: "Patch servers", Description: "HTD_KEY:water-plants"})
+
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	clients := reconcile.Clients{V1: v1.New(h), Sync: sync.New(h)}
+	ctx := context.Background()
+	snap, err := reconcile.FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+
+	existing := `name: homelab
+projects:
+  - name: Homelab # rack + NAS
+labels:
+  - name: waiting
+`
+	file := filepath.Join(t.TempDir(), "todoist.yaml")
+	if err := os.WriteFile(file, []byte(existing), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	cfg, err := config.Load(file)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	found, err := Unmanaged(cfg, snap, nil, Options{})
+	if err != nil {
+		t.Fatalf("Unmanaged: %v", err)
+	}
+	if len(found.Projects) != 2 || len(found.Labels) != 1 || len(found.Tasks) != 3 {
+		t.Fatalf("unexpected unmanaged resources: %+v", found)
+	}
+	notes := found.Select(func(kind reconcile.Kind, name, key string) bool {
+		ok, _ := path.Match("[GW]*", name)
+		return ok
+	})
+	if len(notes) != 1 || !strings.Contains(notes[0], `project "Personal" is included as the parent of "Garden"`) {
+		t.Fatalf("unexpected notes: %v", notes)
+	}
+
+	res, err := MergeInto([]byte(existing), found, Options{})
+	if err != nil {
+		t.Fatalf("MergeInto: %v", err)
+	}
+	// The remote marker "water-plants" is taken, so the recurring task gets the next key.
+	wantPrefix := `name: homelab
+projects:
+  - name: Homelab # rack + NAS
+  - name: Personal
+  - name: Garden
+    parent: Personal
+labels:
+  - name: waiting
+tasks:
+  - id: "`
+	if got := string(res.YAML); !strings.HasPrefix(got, wantPrefix) || !strings.Contains(got, "key: water-plants-2\n") || strings.Contains(got, "Buy milk") {
+		t.Fatalf("unexpected merge result:\n%s", got)
+	}
+	if err := os.WriteFile(file, res.YAML, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	cfg, err = config.Load(file)
+	if err != nil {
+		t.Fatalf("imported config does not load: %v", err)
+	}
+
+	opts := reconcile.Options{AdoptTasks: true, Targets: []reconcile.Target{{Kind: reconcile.KindTask, Pattern: "water-plants-2"}}}
+	plan, err := reconcile.BuildPlan(cfg, snap, opts)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.TotalChanges() != 1 || plan.Operations[0].Changes[0].Field != "key" {
+		t.Fatalf("expected one key update, got %+v", plan.Operations)
+	}
+	if _, err := reconcile.Apply(ctx, cfg, snap, plan, clients, opts); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	snap, err = reconcile.FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	if _, ok := snap.TaskByKey("water-plants-2"); !ok {
+		t.Fatalf("expected the imported task to carry its marker")
+	}
+	plan, err = reconcile.BuildPlan(cfg, snap, reconcile.Options{})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.TotalChanges() != 0 {
+		t.Fatalf("expected a clean plan after import, got %+v", plan.Operations)
+	}
+}
+
+func TestUnmanaged_ImportSubtasks(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	homelab := srv.AddProject(v1.Project{Name: "Homelab"})
+	nas := srv.AddTask(v1.Task{Content: "Rebuild NAS", ProjectID: homelab.ID})
+	srv.AddTask(v1.Task{Content: "Order disks", ProjectID: homelab.ID, ParentID: &nas.ID, ChildOrder: 1})
+	srv.AddTask(v1.Task{Content: "Swap disks", ProjectID: homelab.ID, ParentID: &nas.ID, ChildOrder: 2})
+	srv.AddTask(v1.Task{Content: "Patch servers", ProjectID: homelab.ID})
+
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	clients := reconcile.Clients{V1: v1.New(h), Sync: sync.New(h)}
+	ctx := context.Background()
+	snap, err := reconcile.FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+
+	existing := `name: homelab
+projects:
+  - name: Homelab
+`
+	file := filepath.Join(t.TempDir(), "todoist.yaml")
+	if err := os.WriteFile(file, []byte(existing), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	cfg, err := config.Load(file)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	found, err := Unmanaged(cfg, snap, nil, Options{})
+	if err != nil {
+		t.Fatalf("Unmanaged: %v", err)
+	}
+	if found.Count() != 4 {
+		t.Fatalf("expected 4 unmanaged tasks, got %d: %+v", found.Count(), found.Tasks)
+	}
+	notes := found.Select(func(kind reconcile.Kind, name, key string) bool {
+		return kind == reconcile.KindTask && name == "Swap disks"
+	})
+	if len(notes) != 1 || !strings.Contains(notes[0], `task "Rebuild NAS" is included as the parent of "Swap disks"`) {
+		t.Fatalf("unexpected notes: %v", notes)
+	}
+	if found.Count() != 2 || len(found.Tasks) != 1 || len(found.Tasks[0].Subtasks) != 1 || found.Tasks[0].Subtasks[0].Content != "Swap disks" {
+		t.Fatalf("unexpected selection: %+v", found.Tasks)
+	}
+
+	res, err := MergeInto([]byte(existing), found, Options{})
+	if err != nil {
+		t.Fatalf("MergeInto: %v", err)
+	}
+	if err := os.WriteFile(file, res.YAML, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	cfg, err = config.Load(file)
+	if err != nil {
+		t.Fatalf("imported config does not load: %v\n%s", err, res.YAML)
+	}
+
+	opts := reconcile.Options{AdoptTasks: true}
+	for _, d := range (config.Spec{Tasks: found.Tasks}).AllTasks() {
+		opts.Targets = append(opts.Targets, reconcile.Target{Kind: reconcile.KindTask, Pattern: d.Key})
+	}
+	plan, err := reconcile.BuildPlan(cfg, snap, opts)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	// "Swap disks" is the only declared subtask, so it also moves to the top of its parent.
+	keyUpdates := 0
+	for _, op := range plan.Operations {
+		if op.Action == reconcile.ActionUpdate && op.Changes[0].Field == "key" {
+			keyUpdates++
+		}
+	}
+	if keyUpdates != 2 {
+		t.Fatalf("expected key updates for the parent and its subtask, got %+v", plan.Operations)
+	}
+	if _, err := reconcile.Apply(ctx, cfg, snap, plan, clients, opts); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	snap, err = reconcile.FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	plan, err = reconcile.BuildPlan(cfg, snap, reconcile.Options{})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.TotalChanges() != 0 {
+		t.Fatalf("expected a clean plan after import, got %+v", plan.Operations)
+	}
+}
@@ -0,0 +1,344 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeResult is the merged document and what changed in it.
+type MergeResult struct {
+	YAML    []byte
+	Updated int      // existing entries with at least one changed field
+	Added   int      // remote resources appended to the document
+	Notes   []string // entries kept although they do not exist remotely
+}
+
+// blankLineMarker is a temporary head comment standing in for a blank line: yaml.v3 keeps
+// comments but drops blank lines, so they are marked before encoding and restored after.
+const blankLineMarker = "#htd:blank-line"
+
+var blankLineMarkerRe = regexp.MustCompile(`(?m)^[ \t]*` + blankLineMarker + `$`)
+
+// MergeInto merges exported into an existing config document (simple or envelope format) instead
+// of rendering a fresh one. Entries are matched by id, then by name; changed fields are updated in
+// place and new remote resources are appended. Comments, anchors, key order, blank lines and
+// fields the export does not manage (e.g. sections, or colors without --full) are left untouched.
+// Tasks are matched by id, then by key, and so are the subtasks of a matched task.
+// Only this document is merged: resources defined in included files are appended as new.
+func MergeInto(existing []byte, exported *SimpleConfig, opts Options) (*MergeResult, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(existing, &doc); err != nil {
+		return nil, fmt.Errorf("parse existing config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("existing config is not a YAML mapping")
+	}
+	indent := detectIndent(root)
+	markBlankLines(&doc, strings.Split(string(existing), "\n"))
+
+	// Envelope configs keep their resources under spec.
+	resources := root
+	if spec := mappingValue(root, "spec"); spec != nil && spec.Kind == yaml.MappingNode {
+		resources = spec
+	}
+
+	res := &MergeResult{}
+	kinds := []struct {
+		key   string
+		kind  string
+		items any
+		match string // identifying field besides id
+		owned []string
+	}{
+		{"projects", "project", exported.Projects, "name", ownedKeys(opts, []string{"name", "parent"}, "color", "is_favorite", "view_style")},
+		{"labels", "label", exported.Labels, "name", ownedKeys(opts, []string{"name"}, "color", "is_favorite")},
+		{"filters", "filter", exported.Filters, "name", ownedKeys(opts, []string{"name", "query", "order"}, "color", "is_favorite")},
+		{"tasks", "task", exported.Tasks, "key", ownedKeys(opts, []string{"key", "type", "content", "description", "project", "section", "labels", "priority", "due"})},
+	}
+	for _, k := range kinds {
+		var want yaml.Node
+		if err := want.Encode(k.items); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", k.key, err)
+		}
+		if want.Kind != yaml.SequenceNode || len(want.Content) == 0 {
+			continue
+		}
+		seq := mappingValue(resources, k.key)
+		if seq == nil || seq.Kind != yaml.SequenceNode || seq.Tag == "!!null" {
+			if seq == nil {
+				resources.Content = append(resources.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k.key},
+					&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"})
+			} else {
+				*seq = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", HeadComment: seq.HeadComment, LineComment: seq.LineComment}
+			}
+			seq = mappingValue(resources, k.key)
+		}
+		mergeSequence(seq, &want, k.kind, k.match, k.owned, res)
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(indent)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("encode merged config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode merged config: %w", err)
+	}
+	res.YAML = blankLineMarkerRe.ReplaceAll(b.Bytes(), nil)
+	return res, nil
+}
+
+// ownedKeys lists the fields an export always determines for a kind; an owned field missing from
+// the export (e.g. parent of a project moved to the top level) is removed from the entry.
+func ownedKeys(opts Options, keys []string, full ...string) []string {
+	if opts.IncludeIDs {
+		keys = append(keys, "id")
+	}
+	if opts.Full {
+		keys = append(keys, full...)
+	}
+	return keys
+}
+
+func mergeSequence(seq, want *yaml.Node, kind, match string, owned []string, res *MergeResult) {
+	matched := make([]bool, len(seq.Content))
+	find := func(key, value string) int {
+		if value == "" {
+			return -1
+		}
+		for i, item := range seq.Content {
+			if !matched[i] && scalarValue(mappingValue(item, key)) == value {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// New entries follow the spacing of the existing ones.
+	separate := len(seq.Content) > 1 && strings.HasPrefix(seq.Content[len(seq.Content)-1].HeadComment, blankLineMarker)
+	for _, w := range want.Content {
+		i := find("id", scalarValue(mappingValue(w, "id")))
+		if i < 0 {
+			i = find(match, scalarValue(mappingValue(w, match)))
+		}
+		if i < 0 {
+			if separate {
+				w.HeadComment = blankLineMarker
+			}
+			seq.Content = append(seq.Content, w)
+			matched = append(matched, true)
+			res.Added++
+			continue
+		}
+		matched[i] = true
+		if mergeMapping(seq.Content[i], w, owned) {
+			res.Updated++
+		}
+	}
+	for i, item := range seq.Content {
+		if !matched[i] {
+			name := scalarValue(mappingValue(item, "name"))
+			if name == "" {
+				name = scalarValue(mappingValue(item, "content"))
+			}
+			res.Notes = append(res.Notes, fmt.Sprintf("%s %q does not exist in Todoist (kept)", kind, name))
+		}
+	}
+}
+
+// mergeMapping updates dst's fields from src in place and reports whether anything changed.
+func mergeMapping(dst, src *yaml.Node, owned []string) bool {
+	if dst.Kind != yaml.MappingNode {
+		return false
+	}
+	changed := false
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i].Value, src.Content[i+1]
+		cur := mappingValue(dst, key)
+		if cur == nil {
+			dst.Content = append(dst.Content, src.Content[i], value)
+			changed = true
+			continue
+		}
+		if key == "subtasks" && cur.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode {
+			// Subtasks are merged like tasks, keeping their comments.
+			var sub MergeResult
+			mergeSequence(cur, value, "task", "key", owned, &sub)
+			changed = changed || sub.Added+sub.Updated > 0
+			continue
+		}
+		if mergeValue(cur, value) {
+			changed = true
+		}
+	}
+	for _, key := range owned {
+		if mappingValue(src, key) == nil && removeKey(dst, key) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// mergeValue updates cur to src and reports whether it changed. Scalars are updated in place
+// (keeping their quoting style and comments), mappings are merged and sequences of scalars replaced.
+func mergeValue(cur, src *yaml.Node) bool {
+	old := resolveAlias(cur)
+	if old.Kind == src.Kind {
+		switch src.Kind {
+		case yaml.ScalarNode:
+			if old.Value == src.Value {
+				return false
+			}
+		case yaml.MappingNode:
+			if cur.Kind != yaml.AliasNode {
+				return mergeMapping(cur, src, nil)
+			}
+		case yaml.SequenceNode:
+			if equalScalars(old.Content, src.Content) {
+				return false
+			}
+		}
+	}
+	switch {
+	case cur.Kind == yaml.AliasNode || cur.Kind != src.Kind:
+		// Aliased anchors are shared with other entries; only this entry changes.
+		*cur = yaml.Node{Kind: src.Kind, Tag: src.Tag, Value: src.Value, Content: src.Content,
+			HeadComment: cur.HeadComment, LineComment: cur.LineComment, FootComment: cur.FootComment}
+	case src.Kind == yaml.ScalarNode:
+		cur.Value, cur.Tag = src.Value, src.Tag
+		if cur.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			cur.Style = 0
+		}
+	default:
+		cur.Content = src.Content
+	}
+	return true
+}
+
+// equalScalars compares sequences of scalars as sets (e.g. task labels), so a hand-chosen order
+// is not rewritten.
+func equalScalars(a, b []*yaml.Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := map[string]int{}
+	for i := range a {
+		x, y := resolveAlias(a[i]), resolveAlias(b[i])
+		if x.Kind != yaml.ScalarNode || y.Kind != yaml.ScalarNode {
+			return false
+		}
+		count[x.Value]++
+		count[y.Value]--
+	}
+	for _, n := range count {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func removeKey(m *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func scalarValue(n *yaml.Node) string {
+	n = resolveAlias(n)
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// detectIndent returns the indentation of the first nested block in the document (yaml.v3's
+// default of 4 if there is none).
+func detectIndent(root *yaml.Node) int {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if (value.Kind == yaml.MappingNode || value.Kind == yaml.SequenceNode) && value.Style&yaml.FlowStyle == 0 && value.Line > key.Line {
+			if d := value.Column - key.Column; d >= 2 {
+				return d
+			}
+			return 2
+		}
+	}
+	return 4
+}
+
+// markBlankLines prefixes the head comment of every mapping key and sequence item that follows a
+// blank line in the original source with blankLineMarker.
+func markBlankLines(doc *yaml.Node, lines []string) {
+	seen := map[int]bool{}
+	mark := func(n *yaml.Node) {
+		if n.Line <= 1 || seen[n.Line] {
+			return
+		}
+		seen[n.Line] = true
+		above := n.Line - 1 - strings.Count(n.HeadComment, "\n")
+		if n.HeadComment != "" {
+			above--
+		}
+		if above >= 1 && above <= len(lines) && strings.TrimSpace(lines[above-1]) == "" {
+			if n.HeadComment == "" {
+				n.HeadComment = blankLineMarker
+			} else {
+				n.HeadComment = blankLineMarker + "\n" + n.HeadComment
+			}
+		}
+	}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(c)
+			}
+		case yaml.SequenceNode:
+			for _, c := range n.Content {
+				mark(c)
+				walk(c)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				mark(n.Content[i])
+				walk(n.Content[i+1])
+			}
+		}
+	}
+	walk(doc)
+}
@@ -0,0 +1,129 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+)
+
+func TestMergeInto_PreservesLayout(t *testing.T) {
+	existing := `# Managed by htd.
+name: homelab
+
+prune:
+  projects: false
+
+colors:
+  work: &work red
+
+projects:
+  # Day job.
+  - name: Work
+    color: *work
+    sections:
+      - name: Doing
+
+  - name: Homelab # rack + NAS
+    parent: Personal
+
+  - name: Personal
+
+  - name: Archive
+
+labels:
+  - name: waiting
+filters:
+  - name: Work Focus
+    query: "#Work & today" # keep quoted
+    order: 1
+`
+	exported := &SimpleConfig{
+		Name: "export",
+		Projects: []config.ProjectSpec{
+			{Name: "Personal"},
+			{Name: "Work", Color: strPtr("blue")},
+			{Name: "Homelab"},
+			{Name: "Garden", Parent: strPtr("Personal")},
+		},
+		Labels: []config.LabelSpec{{Name: "waiting"}, {Name: "next"}},
+		Filters: []config.FilterSpec{
+			{Name: "Work Focus", Query: "#Work & (today | overdue)", Order: intPtr(1)},
+		},
+	}
+	res, err := MergeInto([]byte(existing), exported, Options{Full: true})
+	if err != nil {
+		t.Fatalf("MergeInto: %v", err)
+	}
+	want := `# Managed by htd.
+name: homelab
+
+prune:
+  projects: false
+
+colors:
+  work: &work red
+
+projects:
+  # Day job.
+  - name: Work
+    color: blue
+    sections:
+      - name: Doing
+
+  - name: Homelab # rack + NAS
+
+  - name: Personal
+
+  - name: Archive
+
+  - name: Garden
+    parent: Personal
+
+labels:
+  - name: waiting
+  - name: next
+filters:
+  - name: Work Focus
+    query: "#Work & (today | overdue)" # keep quoted
+    order: 1
+`
+	if got := string(res.YAML); got != want {
+		t.Fatalf("unexpected merge result:\n%s\nwant:\n%s", got, want)
+	}
+	if res.Updated != 3 || res.Added != 2 {
+		t.Fatalf("expected 3 updated and 2 added, got %d and %d", res.Updated, res.Added)
+	}
+	if len(res.Notes) != 1 || !strings.Contains(res.Notes[0], `project "Archive"`) {
+		t.Fatalf("unexpected notes: %v", res.Notes)
+	}
+}
+
+func TestMergeInto_EnvelopeMatchesByID(t *testing.T) {
+	existing := `apiVersion: homelab.todoist/v1
+kind: TodoistConfig
+metadata:
+    name: homelab
+spec:
+    labels:
+        - id: "42"
+          name: old-name
+`
+	exported := &SimpleConfig{
+		Labels: []config.LabelSpec{{ID: strPtr("42"), Name: "new-name"}},
+	}
+	res, err := MergeInto([]byte(existing), exported, Options{IncludeIDs: true})
+	if err != nil {
+		t.Fatalf("MergeInto: %v", err)
+	}
+	want := strings.Replace(existing, "old-name", "new-name", 1)
+	if got := string(res.YAML); got != want {
+		t.Fatalf("unexpected merge result:\n%s\nwant:\n%s", got, want)
+	}
+	if res.Updated != 1 || res.Added != 0 || len(res.Notes) != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func strPtr(s string) *string { return &s }
+func intPtr(i int) *int       { return &i }
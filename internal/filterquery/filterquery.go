@@ -0,0 +1,335 @@
+// Package filterquery parses the Todoist filter query language far enough to find the projects,
+// sections and labels a query references.
+//
+// Grammar (loosest binding first):
+//
+//	query  = expr { "," expr }          // comma-separated views
+//	expr   = and { "|" and }
+//	and    = unary { "&" unary }
+//	unary  = "!" unary | "(" expr ")" | term
+//	term   = "#" name | "##" name | "@" name | "/" name | keyword
+//
+// Keywords ("today", "p1", "7 days", "created before: -365 days", "search: foo", ...) are kept
+// verbatim and not interpreted. A backslash escapes the next character, e.g. "#Home\&Garden".
+package filterquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RefKind is the kind of named resource a term references.
+type RefKind string
+
+const (
+	RefProject RefKind = "project"
+	RefSection RefKind = "section"
+	RefLabel   RefKind = "label"
+)
+
+// Ref is a reference to a named project, section or label. Name may contain "*" wildcards.
+type Ref struct {
+	Kind RefKind
+	Name string
+	// Subprojects is set for "##project" (the project and all its sub-projects).
+	Subprojects bool
+}
+
+func (r Ref) String() string {
+	switch r.Kind {
+	case RefProject:
+		if r.Subprojects {
+			return "##" + r.Name
+		}
+		return "#" + r.Name
+	case RefSection:
+		return "/" + r.Name
+	case RefLabel:
+		return "@" + r.Name
+	}
+	return r.Name
+}
+
+// Expr is a node of a parsed query.
+type Expr interface{ isExpr() }
+
+// And matches tasks matching both sides.
+type And struct{ Left, Right Expr }
+
+// Or matches tasks matching either side.
+type Or struct{ Left, Right Expr }
+
+// Not negates X.
+type Not struct{ X Expr }
+
+// Term is a leaf: a reference (Ref != nil) or a keyword.
+type Term struct {
+	Ref     *Ref
+	Keyword string
+}
+
+func (And) isExpr()  {}
+func (Or) isExpr()   {}
+func (Not) isExpr()  {}
+func (Term) isExpr() {}
+
+// Query is a parsed filter: one expression per comma-separated view.
+type Query struct {
+	Views []Expr
+}
+
+// Refs returns every project/section/label reference in the query, in order of appearance.
+func (q *Query) Refs() []Ref {
+	var out []Ref
+	var walk func(Expr)
+	walk = func(e Expr) {
+		switch n := e.(type) {
+		case And:
+			walk(n.Left)
+			walk(n.Right)
+		case Or:
+			walk(n.Left)
+			walk(n.Right)
+		case Not:
+			walk(n.X)
+		case Term:
+			if n.Ref != nil {
+				out = append(out, *n.Ref)
+			}
+		}
+	}
+	for _, v := range q.Views {
+		walk(v)
+	}
+	return out
+}
+
+// Parse parses a filter query.
+func Parse(query string) (*Query, error) {
+	toks, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	q := &Query{}
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		q.Views = append(q.Views, e)
+		t := p.next()
+		switch t.kind {
+		case tokEOF:
+			return q, nil
+		case tokComma:
+			continue
+		default:
+			return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+		}
+	}
+}
+
+// Match reports whether name matches pattern case-insensitively, where "*" matches any run of
+// characters (as Todoist does for "@home*" or "#Work*").
+func Match(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, parts[len(parts)-1])
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokComma
+	tokTerm
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+	// literal is set when the term's first character was escaped, so "\#tag" is a keyword.
+	literal bool
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var operators = map[rune]tokKind{
+	'&': tokAnd,
+	'|': tokOr,
+	'!': tokNot,
+	'(': tokLParen,
+	')': tokRParen,
+	',': tokComma,
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	var term strings.Builder
+	termStart := -1
+	literal := false
+	flush := func() {
+		if termStart < 0 {
+			return
+		}
+		if text := strings.TrimSpace(term.String()); text != "" {
+			toks = append(toks, token{kind: tokTerm, text: text, pos: termStart, literal: literal})
+		}
+		term.Reset()
+		termStart = -1
+		literal = false
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\\' {
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("dangling escape at end of query")
+			}
+			if termStart < 0 {
+				termStart = i
+				literal = true
+			}
+			i++
+			term.WriteRune(runes[i])
+			continue
+		}
+		if k, ok := operators[r]; ok {
+			flush()
+			toks = append(toks, token{kind: k, text: string(r), pos: i})
+			continue
+		}
+		if termStart < 0 {
+			if r == ' ' || r == '\t' || r == '\n' {
+				continue
+			}
+			termStart = i
+		}
+		term.WriteRune(r)
+	}
+	flush()
+	toks = append(toks, token{kind: tokEOF, pos: len(runes)})
+	return toks, nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expr() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNot:
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{X: x}, nil
+	case tokLParen:
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, fmt.Errorf("expected \")\" to close \"(\" at offset %d, got %s", t.pos, c)
+		}
+		return e, nil
+	case tokTerm:
+		return parseTerm(t)
+	}
+	return nil, fmt.Errorf("expected a term at offset %d, got %s", t.pos, t)
+}
+
+func parseTerm(t token) (Expr, error) {
+	text := t.text
+	var ref *Ref
+	switch {
+	case t.literal:
+		return Term{Keyword: text}, nil
+	case strings.HasPrefix(text, "##"):
+		ref = &Ref{Kind: RefProject, Name: text[2:], Subprojects: true}
+	case strings.HasPrefix(text, "#"):
+		ref = &Ref{Kind: RefProject, Name: text[1:]}
+	case strings.HasPrefix(text, "@"):
+		ref = &Ref{Kind: RefLabel, Name: text[1:]}
+	case strings.HasPrefix(text, "/"):
+		ref = &Ref{Kind: RefSection, Name: text[1:]}
+	default:
+		return Term{Keyword: text}, nil
+	}
+	ref.Name = strings.TrimSpace(ref.Name)
+	if ref.Name == "" {
+		return nil, fmt.Errorf("empty %s name at offset %d", ref.Kind, t.pos)
+	}
+	return Term{Ref: ref}, nil
+}
@@ -0,0 +1,68 @@
+package filterquery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse_Refs(t *testing.T) {
+	cases := []struct {
+		query string
+		want  []Ref
+	}{
+		{"(today | overdue) & #Work", []Ref{{Kind: RefProject, Name: "Work"}}},
+		{"7 days & @waiting", []Ref{{Kind: RefLabel, Name: "waiting"}}},
+		{"p1 & overdue, p4 & today", nil},
+		{"created before: -365 days", nil},
+		{"##Home Lab & !/* & @home*", []Ref{
+			{Kind: RefProject, Name: "Home Lab", Subprojects: true},
+			{Kind: RefSection, Name: "*"},
+			{Kind: RefLabel, Name: "home*"},
+		}},
+		{`#Home\&Garden | \#notaproject`, []Ref{{Kind: RefProject, Name: "Home&Garden"}}},
+	}
+	for _, tc := range cases {
+		q, err := Parse(tc.query)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.query, err)
+		}
+		if got := q.Refs(); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("Parse(%q).Refs() = %#v, want %#v", tc.query, got, tc.want)
+		}
+	}
+
+	q, err := Parse("p1 & overdue, p4 & today")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(q.Views) != 2 {
+		t.Fatalf("expected 2 views, got %d", len(q.Views))
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, q := range []string{"", "today &", "(today | overdue", "today)", "# & p1", "p1 ,", `today\`} {
+		if _, err := Parse(q); err == nil {
+			t.Fatalf("expected error for %q", q)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"work", "Work", true},
+		{"Work*", "Work Projects", true},
+		{"*lab", "Homelab", true},
+		{"h*e*b", "Homelab", true},
+		{"Wrok", "Work", false},
+		{"*", "", true},
+	}
+	for _, tc := range cases {
+		if got := Match(tc.pattern, tc.name); got != tc.want {
+			t.Fatalf("Match(%q, %q) = %t, want %t", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Package metrics keeps htd's Prometheus metrics and renders them in the text exposition format
+// (for a /metrics endpoint or a node_exporter textfile collector).
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+)
+
+var (
+	kinds   = []reconcile.Kind{reconcile.KindProject, reconcile.KindSection, reconcile.KindLabel, reconcile.KindFilter, reconcile.KindTask, reconcile.KindReminder}
+	actions = []reconcile.Action{reconcile.ActionCreate, reconcile.ActionUpdate, reconcile.ActionMove, reconcile.ActionDelete, reconcile.ActionReorder}
+
+	// durationBuckets are upper bounds in seconds for htd_http_request_duration_seconds.
+	durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+// KindCounts are the object counts of one kind from the latest check.
+type KindCounts struct {
+	Managed   int
+	Unmanaged int
+	Pending   map[reconcile.Action]int
+}
+
+// Metrics is safe for concurrent use. It implements todoisthttp.Observer.
+type Metrics struct {
+	mu sync.Mutex
+
+	config      string
+	counts      map[reconcile.Kind]KindCounts
+	lastSuccess time.Time
+	checks      map[string]int // by result: ok, error
+
+	requests  map[requestKey]int
+	durations map[endpointKey]*histogram
+	retries   map[endpointKey]map[string]int // by reason: HTTP status or "network"
+}
+
+type endpointKey struct{ method, endpoint string }
+
+type requestKey struct {
+	endpointKey
+	code string
+}
+
+type histogram struct {
+	buckets []int // cumulative is computed at render time
+	count   int
+	sum     float64
+}
+
+func New() *Metrics {
+	return &Metrics{
+		counts:    map[reconcile.Kind]KindCounts{},
+		checks:    map[string]int{},
+		requests:  map[requestKey]int{},
+		durations: map[endpointKey]*histogram{},
+		retries:   map[endpointKey]map[string]int{},
+	}
+}
+
+// ObservePlan records the result of a successful check.
+func (m *Metrics) ObservePlan(cfg *config.TodoistConfig, snap *reconcile.Snapshot, plan *reconcile.Plan, at time.Time) {
+	counts := Count(cfg, snap, plan)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = cfg.Metadata.Name
+	m.counts = counts
+	m.lastSuccess = at
+	m.checks["ok"]++
+}
+
+// ObserveError records a failed check; the gauges keep the values of the last successful one.
+func (m *Metrics) ObserveError() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks["error"]++
+}
+
+func (m *Metrics) ObserveRequest(method, path string, status int, d time.Duration, err error) {
+	k := endpointKey{method: method, endpoint: endpoint(path)}
+	code := strconv.Itoa(status)
+	if err != nil {
+		code = "error"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{endpointKey: k, code: code}]++
+	h := m.durations[k]
+	if h == nil {
+		h = &histogram{buckets: make([]int, len(durationBuckets))}
+		m.durations[k] = h
+	}
+	secs := d.Seconds()
+	for i, le := range durationBuckets {
+		if secs <= le {
+			h.buckets[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += secs
+}
+
+func (m *Metrics) ObserveRetry(method, path string, status int, err error) {
+	k := endpointKey{method: method, endpoint: endpoint(path)}
+	reason := strconv.Itoa(status)
+	if err != nil || status == 0 {
+		reason = "network"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.retries[k] == nil {
+		m.retries[k] = map[string]int{}
+	}
+	m.retries[k][reason]++
+}
+
+// Count derives per-kind counts. An object is managed when the config declares it and it exists
+// remotely (i.e. the plan does not create it); every other remote object is unmanaged.
+func Count(cfg *config.TodoistConfig, snap *reconcile.Snapshot, plan *reconcile.Plan) map[reconcile.Kind]KindCounts {
+	declared := map[reconcile.Kind]int{
+		reconcile.KindProject: len(cfg.Spec.Projects),
+		reconcile.KindLabel:   len(cfg.Spec.Labels),
+		reconcile.KindFilter:  len(cfg.Spec.Filters),
+		reconcile.KindTask:    len(cfg.Spec.AllTasks()),
+	}
+	for _, p := range cfg.Spec.Projects {
+		declared[reconcile.KindSection] += len(p.Sections)
+	}
+	for _, t := range cfg.Spec.AllTasks() {
+		declared[reconcile.KindReminder] += len(t.Reminders)
+	}
+	remote := map[reconcile.Kind]int{
+		reconcile.KindProject:  len(snap.Projects),
+		reconcile.KindSection:  len(snap.Sections),
+		reconcile.KindLabel:    len(snap.Labels),
+		reconcile.KindFilter:   len(snap.Filters),
+		reconcile.KindTask:     len(snap.Tasks),
+		reconcile.KindReminder: len(snap.Reminders),
+	}
+
+	out := map[reconcile.Kind]KindCounts{}
+	for _, k := range kinds {
+		out[k] = KindCounts{Pending: map[reconcile.Action]int{}}
+	}
+	for _, op := range plan.Operations {
+		out[op.Kind].Pending[op.Action]++
+	}
+	for _, k := range kinds {
+		c := out[k]
+		c.Managed = declared[k] - c.Pending[reconcile.ActionCreate]
+		if c.Managed < 0 {
+			c.Managed = 0
+		}
+		c.Unmanaged = remote[k] - c.Managed
+		if c.Unmanaged < 0 {
+			c.Unmanaged = 0
+		}
+		out[k] = c
+	}
+	return out
+}
+
+// Write renders all metrics in the Prometheus text exposition format.
+func (m *Metrics) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b bytes.Buffer
+	if len(m.counts) > 0 {
+		header(&b, "htd_managed_objects", "gauge", "Objects declared in the config that exist remotely.")
+		for _, k := range kinds {
+			fmt.Fprintf(&b, "htd_managed_objects{config=%q,kind=%q} %d\n", m.config, k, m.counts[k].Managed)
+		}
+		header(&b, "htd_unmanaged_objects", "gauge", "Remote objects not declared in the config.")
+		for _, k := range kinds {
+			fmt.Fprintf(&b, "htd_unmanaged_objects{config=%q,kind=%q} %d\n", m.config, k, m.counts[k].Unmanaged)
+		}
+		header(&b, "htd_pending_operations", "gauge", "Operations in the latest plan.")
+		for _, k := range kinds {
+			for _, a := range actions {
+				fmt.Fprintf(&b, "htd_pending_operations{action=%q,config=%q,kind=%q} %d\n", a, m.config, k, m.counts[k].Pending[a])
+			}
+		}
+	}
+	if !m.lastSuccess.IsZero() {
+		header(&b, "htd_last_success_timestamp_seconds", "gauge", "Unix time of the last successful check.")
+		fmt.Fprintf(&b, "htd_last_success_timestamp_seconds %d\n", m.lastSuccess.Unix())
+	}
+	if len(m.checks) > 0 {
+		header(&b, "htd_checks_total", "counter", "Checks (snapshot + plan) by result.")
+		for _, r := range sortedKeys(m.checks) {
+			fmt.Fprintf(&b, "htd_checks_total{result=%q} %d\n", r, m.checks[r])
+		}
+	}
+
+	if len(m.requests) > 0 {
+		header(&b, "htd_http_requests_total", "counter", "Todoist API request attempts by status code.")
+		keys := make([]requestKey, 0, len(m.requests))
+		for k := range m.requests {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].endpointKey != keys[j].endpointKey {
+				return keys[i].endpointKey.less(keys[j].endpointKey)
+			}
+			return keys[i].code < keys[j].code
+		})
+		for _, k := range keys {
+			fmt.Fprintf(&b, "htd_http_requests_total{code=%q,endpoint=%q,method=%q} %d\n", k.code, k.endpoint, k.method, m.requests[k])
+		}
+
+		header(&b, "htd_http_request_duration_seconds", "histogram", "Todoist API request attempt latency.")
+		for _, k := range sortedEndpoints(m.durations) {
+			h := m.durations[k]
+			labels := fmt.Sprintf("endpoint=%q,method=%q", k.endpoint, k.method)
+			cum := 0
+			for i, le := range durationBuckets {
+				cum += h.buckets[i]
+				fmt.Fprintf(&b, "htd_http_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, formatFloat(le), cum)
+			}
+			fmt.Fprintf(&b, "htd_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+			fmt.Fprintf(&b, "htd_http_request_duration_seconds_sum{%s} %s\n", labels, formatFloat(h.sum))
+			fmt.Fprintf(&b, "htd_http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+		}
+	}
+
+	if len(m.retries) > 0 {
+		header(&b, "htd_http_retries_total", "counter", "Todoist API retries by reason (429, 5xx status or network).")
+		for _, k := range sortedEndpoints(m.retries) {
+			for _, reason := range sortedKeys(m.retries[k]) {
+				fmt.Fprintf(&b, "htd_http_retries_total{endpoint=%q,method=%q,reason=%q} %d\n", k.endpoint, k.method, reason, m.retries[k][reason])
+			}
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Handler serves the metrics for Prometheus to scrape.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := m.Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WriteTextfile writes the metrics for node_exporter's textfile collector. The file is replaced
+// atomically so the collector never reads a partial file.
+func (m *Metrics) WriteTextfile(path string) error {
+	var b bytes.Buffer
+	if err := m.Write(&b); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("write metrics textfile: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write metrics textfile: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write metrics textfile: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write metrics textfile: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write metrics textfile: %w", err)
+	}
+	return nil
+}
+
+// endpoint reduces a request path to its resource (e.g. /api/v1/projects/123/archive ->
+// /api/v1/projects) to keep label cardinality bounded.
+func endpoint(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+func (k endpointKey) less(o endpointKey) bool {
+	if k.endpoint != o.endpoint {
+		return k.endpoint < o.endpoint
+	}
+	return k.method < o.method
+}
+
+func sortedEndpoints[V any](m map[endpointKey]V) []endpointKey {
+	keys := make([]endpointKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+	return keys
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func header(b *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
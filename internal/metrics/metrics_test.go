@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/fake"
+	todoisthttp "github.com/erauner/homelab-todoist-declarative/internal/todoist/http"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+func TestMetrics_Exposition(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	srv.AddProject(v1.Project{Name: "Work", Color: "blue"})
+	srv.AddProject(v1.Project{Name: "Scratch"})
+	srv.AddLabel(v1.Label{Name: "next"})
+	srv.AddLabel(v1.Label{Name: "someday"})
+
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "homelab"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{{Name: "Work", Color: strPtr("red")}, {Name: "Homelab"}},
+			Labels:   []config.LabelSpec{{Name: "next"}},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	m := New()
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()), todoisthttp.WithObserver(m))
+	snap, err := reconcile.FetchSnapshot(context.Background(), v1.New(h), sync.New(h))
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	plan, err := reconcile.BuildPlan(cfg, snap, reconcile.Options{})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	m.ObservePlan(cfg, snap, plan, time.Unix(1700000000, 0))
+	m.ObserveRetry(http.MethodPost, "/api/v1/sync", http.StatusTooManyRequests, nil)
+	m.ObserveRetry(http.MethodPost, "/api/v1/sync", http.StatusTooManyRequests, nil)
+
+	var b bytes.Buffer
+	if err := m.Write(&b); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := b.String()
+	// The fake account has an Inbox, so Scratch and Inbox are unmanaged projects.
+	for _, want := range []string{
+		`htd_managed_objects{config="homelab",kind="project"} 1`,
+		`htd_unmanaged_objects{config="homelab",kind="project"} 2`,
+		`htd_managed_objects{config="homelab",kind="label"} 1`,
+		`htd_unmanaged_objects{config="homelab",kind="label"} 1`,
+		`htd_pending_operations{action="create",config="homelab",kind="project"} 1`,
+		`htd_pending_operations{action="update",config="homelab",kind="project"} 1`,
+		`htd_pending_operations{action="delete",config="homelab",kind="label"} 0`,
+		`htd_last_success_timestamp_seconds 1700000000`,
+		`htd_checks_total{result="ok"} 1`,
+		`htd_http_requests_total{code="200",endpoint="/api/v1/projects",method="GET"} 1`,
+		`htd_http_request_duration_seconds_count{endpoint="/api/v1/projects",method="GET"} 1`,
+		`htd_http_retries_total{endpoint="/api/v1/sync",method="POST",reason="429"} 2`,
+		"# TYPE htd_http_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetrics_WriteTextfile(t *testing.T) {
+	m := New()
+	m.ObserveError()
+	path := filepath.Join(t.TempDir(), "htd.prom")
+	if err := m.WriteTextfile(path); err != nil {
+		t.Fatalf("WriteTextfile: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(b), `htd_checks_total{result="error"} 1`) {
+		t.Fatalf("unexpected textfile:\n%s", b)
+	}
+	if strings.Contains(string(b), "htd_last_success_timestamp_seconds") {
+		t.Fatalf("expected no last success before a successful check:\n%s", b)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("expected only the textfile, got %d entries", len(entries))
+	}
+}
+
+func strPtr(s string) *string { return &s }
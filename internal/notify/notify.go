@@ -0,0 +1,175 @@
+// Package notify posts drift summaries to webhooks (generic JSON, Discord and ntfy).
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+)
+
+// Event describes drift found by `htd watch`. Applied is set when non-destructive operations
+// were applied automatically.
+type Event struct {
+	Config     string                 `json:"config"`
+	Time       time.Time              `json:"time"`
+	Summary    reconcile.Summary      `json:"summary"`
+	Operations []reconcile.Operation  `json:"operations"`
+	Applied    *reconcile.ApplyResult `json:"applied,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Title is a one-line headline for the event.
+func (e Event) Title() string {
+	return fmt.Sprintf("htd: drift in %s (%d change(s))", e.Config, e.Summary.TotalChanges())
+}
+
+// Text renders the event as plain text, one operation per line.
+func (e Event) Text() string {
+	var b strings.Builder
+	s := e.Summary
+	fmt.Fprintf(&b, "%d to create, %d to update, %d to move, %d to delete, %d to reorder.\n",
+		s.Create, s.Update, s.Move, s.Delete, s.Reorder)
+	for _, op := range e.Operations {
+		fmt.Fprintf(&b, "%s %s %q\n", op.Kind, op.Action, op.Name)
+	}
+	if e.Applied != nil {
+		fmt.Fprintf(&b, "Auto-applied %d operation(s)", len(e.Applied.Applied)-e.Applied.Failed-e.Applied.Skipped)
+		if e.Applied.Failed > 0 || e.Applied.Skipped > 0 {
+			fmt.Fprintf(&b, " (%d failed, %d skipped)", e.Applied.Failed, e.Applied.Skipped)
+		}
+		b.WriteString(".\n")
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", e.Error)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Notifier delivers an event somewhere.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// Webhook POSTs the event as JSON.
+type Webhook struct {
+	URL  string
+	HTTP *http.Client
+}
+
+func (w *Webhook) Notify(ctx context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	return post(ctx, w.HTTP, w.URL, "application/json", b, nil)
+}
+
+// discordMaxContent is Discord's limit for a message's content.
+const discordMaxContent = 2000
+
+// Discord posts the event as a Discord webhook message.
+type Discord struct {
+	URL  string
+	HTTP *http.Client
+}
+
+func (d *Discord) Notify(ctx context.Context, e Event) error {
+	content := "**" + e.Title() + "**\n" + e.Text()
+	if r := []rune(content); len(r) > discordMaxContent {
+		content = string(r[:discordMaxContent-3]) + "..."
+	}
+	b, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return fmt.Errorf("marshal discord message: %w", err)
+	}
+	return post(ctx, d.HTTP, d.URL, "application/json", b, nil)
+}
+
+// Ntfy publishes the event to an ntfy topic URL (e.g. https://ntfy.sh/homelab).
+type Ntfy struct {
+	URL  string
+	HTTP *http.Client
+}
+
+func (n *Ntfy) Notify(ctx context.Context, e Event) error {
+	headers := map[string]string{"Title": e.Title(), "Tags": "warning"}
+	if e.Error != "" {
+		headers["Priority"] = "high"
+	}
+	return post(ctx, n.HTTP, n.URL, "text/plain; charset=utf-8", []byte(e.Text()), headers)
+}
+
+// Multi sends to every notifier, even if some fail.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, e Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Parse builds a notifier from a spec of the form <type>=<url>, where type is webhook, discord
+// or ntfy. A bare URL is a generic webhook.
+func Parse(spec string, h *http.Client) (Notifier, error) {
+	kind, url, ok := strings.Cut(spec, "=")
+	if !ok || strings.Contains(kind, "/") {
+		kind, url = "webhook", spec
+	}
+	url = strings.TrimSpace(url)
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("invalid notifier %q: expected <webhook|discord|ntfy>=<http(s) url>", spec)
+	}
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "webhook":
+		return &Webhook{URL: url, HTTP: h}, nil
+	case "discord":
+		return &Discord{URL: url, HTTP: h}, nil
+	case "ntfy":
+		return &Ntfy{URL: url, HTTP: h}, nil
+	default:
+		return nil, fmt.Errorf("invalid notifier %q: unknown type %q (expected webhook, discord or ntfy)", spec, kind)
+	}
+}
+
+func post(ctx context.Context, h *http.Client, url, contentType string, body []byte, headers map[string]string) error {
+	if h == nil {
+		h = &http.Client{Timeout: 30 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := h.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify %s: %w", redact(url), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notify %s: HTTP %d: %s", redact(url), resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// redact drops the path of a webhook URL from error messages; Discord embeds its secret there.
+func redact(url string) string {
+	scheme, rest, _ := strings.Cut(url, "://")
+	host, _, _ := strings.Cut(rest, "/")
+	return scheme + "://" + host
+}
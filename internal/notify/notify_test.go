@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+)
+
+type received struct {
+	header http.Header
+	body   string
+}
+
+func newReceiver(t *testing.T, status int) (*httptest.Server, *[]received) {
+	t.Helper()
+	var got []received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = append(got, received{header: r.Header.Clone(), body: string(b)})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func testEvent() Event {
+	return Event{
+		Config:  "homelab",
+		Summary: reconcile.Summary{Update: 1, Delete: 1},
+		Operations: []reconcile.Operation{
+			{Kind: reconcile.KindProject, Action: reconcile.ActionUpdate, Name: "Work"},
+			{Kind: reconcile.KindLabel, Action: reconcile.ActionDelete, Name: "stale"},
+		},
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	srv, got := newReceiver(t, http.StatusNoContent)
+	ctx := context.Background()
+
+	for _, spec := range []string{srv.URL + "/hook", "discord=" + srv.URL + "/discord", "ntfy=" + srv.URL + "/homelab"} {
+		n, err := Parse(spec, srv.Client())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", spec, err)
+		}
+		if err := n.Notify(ctx, testEvent()); err != nil {
+			t.Fatalf("Notify(%q): %v", spec, err)
+		}
+	}
+	if len(*got) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(*got))
+	}
+
+	var ev Event
+	if err := json.Unmarshal([]byte((*got)[0].body), &ev); err != nil {
+		t.Fatalf("webhook body: %v", err)
+	}
+	if ev.Config != "homelab" || ev.Summary.TotalChanges() != 2 || len(ev.Operations) != 2 {
+		t.Fatalf("unexpected webhook event: %+v", ev)
+	}
+
+	var msg map[string]string
+	if err := json.Unmarshal([]byte((*got)[1].body), &msg); err != nil {
+		t.Fatalf("discord body: %v", err)
+	}
+	if !strings.HasPrefix(msg["content"], "**htd: drift in homelab (2 change(s))**") || !strings.Contains(msg["content"], `label delete "stale"`) {
+		t.Fatalf("unexpected discord content: %q", msg["content"])
+	}
+
+	ntfy := (*got)[2]
+	if ntfy.header.Get("Title") != "htd: drift in homelab (2 change(s))" {
+		t.Fatalf("unexpected ntfy title: %q", ntfy.header.Get("Title"))
+	}
+	if !strings.Contains(ntfy.body, `project update "Work"`) {
+		t.Fatalf("unexpected ntfy body: %q", ntfy.body)
+	}
+}
+
+func TestNotify_ErrorHidesWebhookPath(t *testing.T) {
+	srv, _ := newReceiver(t, http.StatusUnauthorized)
+	n := &Discord{URL: srv.URL + "/api/webhooks/123/secret", HTTP: srv.Client()}
+	err := n.Notify(context.Background(), testEvent())
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"slack=https://example.com", "discord=example.com", ""} {
+		if _, err := Parse(spec, nil); err == nil {
+			t.Fatalf("Parse(%q): expected an error", spec)
+		}
+	}
+	n, err := Parse("https://example.com/hook?token=abc", nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if w, ok := n.(*Webhook); !ok || w.URL != "https://example.com/hook?token=abc" {
+		t.Fatalf("expected a generic webhook, got %#v", n)
+	}
+}
@@ -0,0 +1,163 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+)
+
+// JUnit XML as understood by Jenkins' junit step (and most other CI test reporters).
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// PrintPlanJUnit reports a plan with one testcase per resource: every resource declared in cfg
+// (nil cfg: none) plus every other resource the plan touches. A testcase fails when the resource
+// has pending operations, i.e. drift.
+func PrintPlanJUnit(w io.Writer, cfg *config.TodoistConfig, plan *reconcile.Plan) error {
+	type resource struct {
+		kind reconcile.Kind
+		name string
+	}
+	var order []resource
+	ops := map[resource][]reconcile.Operation{}
+	add := func(r resource) {
+		if _, ok := ops[r]; !ok {
+			ops[r] = nil
+			order = append(order, r)
+		}
+	}
+	if cfg != nil {
+		for _, p := range cfg.Spec.Projects {
+			add(resource{reconcile.KindProject, p.Name})
+			for _, s := range p.Sections {
+				add(resource{reconcile.KindSection, p.Name + "/" + s.Name})
+			}
+		}
+		for _, l := range cfg.Spec.Labels {
+			add(resource{reconcile.KindLabel, l.Name})
+		}
+		for _, f := range cfg.Spec.Filters {
+			add(resource{reconcile.KindFilter, f.Name})
+		}
+		for _, t := range cfg.Spec.AllTasks() {
+			add(resource{reconcile.KindTask, t.Content})
+			for _, r := range t.Reminders {
+				add(resource{reconcile.KindReminder, reconcile.ReminderName(t.Content, r)})
+			}
+		}
+	}
+	if plan != nil {
+		for _, op := range plan.Operations {
+			r := resource{op.Kind, op.Name}
+			add(r)
+			ops[r] = append(ops[r], op)
+		}
+	}
+
+	suite := junitSuite{Name: "htd plan"}
+	if cfg != nil {
+		suite.Name += " " + cfg.Metadata.Name
+	}
+	for _, r := range order {
+		c := junitCase{ClassName: "plan." + string(r.kind), Name: r.name}
+		if pending := ops[r]; len(pending) > 0 {
+			var actions, body []string
+			for _, op := range pending {
+				actions = append(actions, string(op.Action))
+				body = append(body, fmt.Sprintf("%s %s %q", symbol(op.Action), op.Action, op.Name))
+				for _, ch := range op.Changes {
+					body = append(body, fmt.Sprintf("  - %s: %s -> %s", ch.Field, ch.From, ch.To))
+				}
+			}
+			c.Failure = &junitFailure{
+				Message: "drift: pending " + strings.Join(actions, ", "),
+				Type:    "drift",
+				Body:    strings.Join(body, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	return writeJUnit(w, suite)
+}
+
+// PrintValidateJUnit reports a config validation with one failing testcase per error (the errors
+// joined by config.Validate are reported separately), or a single passing testcase.
+func PrintValidateJUnit(w io.Writer, path string, err error) error {
+	suite := junitSuite{Name: "htd validate"}
+	if err == nil {
+		suite.Cases = append(suite.Cases, junitCase{ClassName: "validate", Name: path})
+		return writeJUnit(w, suite)
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, e := range errs {
+		msg := e.Error()
+		suite.Cases = append(suite.Cases, junitCase{
+			ClassName: "validate",
+			Name:      msg,
+			Failure:   &junitFailure{Message: msg, Type: "validation", Body: path + ": " + msg},
+		})
+		suite.Failures++
+	}
+	return writeJUnit(w, suite)
+}
+
+func printApplyResultJUnit(w io.Writer, res *reconcile.ApplyResult) error {
+	suite := junitSuite{Name: "htd apply"}
+	if res != nil {
+		for _, r := range res.Applied {
+			c := junitCase{ClassName: "apply." + string(r.Kind), Name: fmt.Sprintf("%s %s", r.Action, r.Name)}
+			if r.Status != "ok" {
+				c.Failure = &junitFailure{Message: r.Status, Type: "apply"}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+	}
+	return writeJUnit(w, suite)
+}
+
+func writeJUnit(w io.Writer, suite junitSuite) error {
+	suite.Tests = len(suite.Cases)
+	b, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
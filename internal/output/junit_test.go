@@ -0,0 +1,94 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+)
+
+func decodeJUnit(t *testing.T, b []byte) junitSuite {
+	t.Helper()
+	var suites junitSuites
+	if err := xml.Unmarshal(b, &suites); err != nil {
+		t.Fatalf("decode junit: %v\n%s", err, b)
+	}
+	if len(suites.Suites) != 1 {
+		t.Fatalf("expected one testsuite, got %d", len(suites.Suites))
+	}
+	return suites.Suites[0]
+}
+
+func TestPrintPlanJUnit(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "homelab"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{{Name: "Work", Sections: []config.SectionSpec{{Name: "Doing"}}}, {Name: "Homelab"}},
+			Labels:   []config.LabelSpec{{Name: "next"}},
+		},
+	}
+	plan := &reconcile.Plan{Operations: []reconcile.Operation{
+		{Kind: reconcile.KindProject, Action: reconcile.ActionUpdate, Name: "Work", Changes: []reconcile.Change{{Field: "color", From: "blue", To: "red"}}},
+		{Kind: reconcile.KindProject, Action: reconcile.ActionMove, Name: "Work"},
+		{Kind: reconcile.KindLabel, Action: reconcile.ActionDelete, Name: "stale"},
+	}}
+
+	var b bytes.Buffer
+	if err := PrintPlanJUnit(&b, cfg, plan); err != nil {
+		t.Fatalf("PrintPlanJUnit: %v", err)
+	}
+	suite := decodeJUnit(t, b.Bytes())
+	if suite.Name != "htd plan homelab" || suite.Tests != 5 || suite.Failures != 2 {
+		t.Fatalf("unexpected suite: %s tests=%d failures=%d", suite.Name, suite.Tests, suite.Failures)
+	}
+	var got []string
+	for _, c := range suite.Cases {
+		s := c.ClassName + " " + c.Name
+		if c.Failure != nil {
+			s += ": " + c.Failure.Message
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"plan.project Work: drift: pending update, move",
+		"plan.section Work/Doing",
+		"plan.project Homelab",
+		"plan.label next",
+		"plan.label stale: drift: pending delete",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if body := suite.Cases[0].Failure.Body; body != "~ update \"Work\"\n  - color: blue -> red\n~ move \"Work\"" {
+		t.Fatalf("unexpected failure body: %q", body)
+	}
+}
+
+func TestPrintValidateJUnit(t *testing.T) {
+	var b bytes.Buffer
+	err := errors.Join(errors.New("name is required"), errors.New(`duplicate project name "A"`))
+	if err := PrintValidateJUnit(&b, "todoist.yaml", err); err != nil {
+		t.Fatalf("PrintValidateJUnit: %v", err)
+	}
+	suite := decodeJUnit(t, b.Bytes())
+	if suite.Tests != 2 || suite.Failures != 2 || suite.Cases[1].Name != `duplicate project name "A"` {
+		t.Fatalf("unexpected suite: %+v", suite)
+	}
+
+	b.Reset()
+	if err := PrintValidateJUnit(&b, "todoist.yaml", nil); err != nil {
+		t.Fatalf("PrintValidateJUnit: %v", err)
+	}
+	suite = decodeJUnit(t, b.Bytes())
+	if suite.Tests != 1 || suite.Failures != 0 || suite.Cases[0].Name != "todoist.yaml" {
+		t.Fatalf("unexpected suite: %+v", suite)
+	}
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+)
+
+// printPlanMarkdown renders the plan for a PR comment: the summary, then one collapsible table
+// per kind with field-level diffs, then the notes.
+func printPlanMarkdown(w io.Writer, plan *reconcile.Plan) error {
+	if plan == nil || len(plan.Operations) == 0 {
+		fmt.Fprintln(w, "### Plan: no changes")
+		printNotesMarkdown(w, plan)
+		return nil
+	}
+
+	fmt.Fprintf(w, "### Plan: %d change(s)\n\n", plan.Summary.TotalChanges())
+	fmt.Fprintln(w, summaryMarkdown(plan.Summary))
+
+	// Operations are already grouped by kind; each group becomes one <details> block.
+	for start := 0; start < len(plan.Operations); {
+		kind := plan.Operations[start].Kind
+		end := start
+		for end < len(plan.Operations) && plan.Operations[end].Kind == kind {
+			end++
+		}
+		ops := plan.Operations[start:end]
+		start = end
+
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "<details open>\n<summary><b>%s</b> (%d)</summary>\n\n", kindHeading(kind), len(ops))
+		fmt.Fprintln(w, "| | Action | Name | Changes |")
+		fmt.Fprintln(w, "|---|---|---|---|")
+		for _, op := range ops {
+			var changes []string
+			for _, ch := range op.Changes {
+				changes = append(changes, fmt.Sprintf("%s: %s -> %s", mdCode(ch.Field), mdValue(ch.From), mdValue(ch.To)))
+			}
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", symbol(op.Action), op.Action, mdCode(op.Name), strings.Join(changes, "<br>"))
+		}
+		fmt.Fprintln(w, "\n</details>")
+	}
+
+	printNotesMarkdown(w, plan)
+	return nil
+}
+
+func printNotesMarkdown(w io.Writer, plan *reconcile.Plan) {
+	if plan == nil || len(plan.Notes) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n**Notes:**")
+	for _, n := range plan.Notes {
+		fmt.Fprintf(w, "- %s\n", n)
+	}
+}
+
+func printApplyResultMarkdown(w io.Writer, res *reconcile.ApplyResult) error {
+	if res == nil || len(res.Applied) == 0 {
+		fmt.Fprintln(w, "### Apply: no changes")
+		return nil
+	}
+	fmt.Fprintf(w, "### Apply: %d operation(s)\n\n", len(res.Applied))
+	fmt.Fprintln(w, summaryMarkdown(res.Summary))
+	if res.Failed > 0 || res.Skipped > 0 {
+		fmt.Fprintf(w, "\n**Errors:** %d failed, %d skipped.\n", res.Failed, res.Skipped)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| | Kind | Action | Name | Status |")
+	fmt.Fprintln(w, "|---|---|---|---|---|")
+	for _, r := range res.Applied {
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", symbol(r.Action), r.Kind, r.Action, mdCode(r.Name), mdText(r.Status))
+	}
+	return nil
+}
+
+func summaryMarkdown(s reconcile.Summary) string {
+	return fmt.Sprintf("**Summary:** %d to create, %d to update, %d to move, %d to delete, %d to reorder.",
+		s.Create, s.Update, s.Move, s.Delete, s.Reorder)
+}
+
+// mdValue renders a changed value; empty values are shown explicitly.
+func mdValue(s string) string {
+	if s == "" {
+		return "_(empty)_"
+	}
+	return mdCode(s)
+}
+
+// mdCode renders s as inline code that is safe inside a table cell. HTML does not render in
+// code spans, so newlines are shown as \n.
+func mdCode(s string) string {
+	s = strings.ReplaceAll(mdCell(s), "\n", `\n`)
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// mdText escapes s for use as plain text inside a table cell.
+func mdText(s string) string {
+	r := strings.NewReplacer("<", "&lt;", ">", "&gt;", "*", `\*`, "_", `\_`)
+	return strings.ReplaceAll(r.Replace(mdCell(s)), "\n", "<br>")
+}
+
+// mdCell escapes pipes so s stays in its table cell.
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,70 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+)
+
+func TestPrintPlan_Markdown(t *testing.T) {
+	plan := &reconcile.Plan{
+		Operations: []reconcile.Operation{
+			{Kind: reconcile.KindProject, Action: reconcile.ActionCreate, Name: "Homelab"},
+			{Kind: reconcile.KindProject, Action: reconcile.ActionUpdate, Name: "Work", Changes: []reconcile.Change{
+				{Field: "color", From: "blue", To: "red"},
+				{Field: "view_style", From: "", To: "board"},
+			}},
+			{Kind: reconcile.KindFilter, Action: reconcile.ActionUpdate, Name: "Work | Today", Changes: []reconcile.Change{
+				{Field: "query", From: "#Work", To: "#Work & today"},
+			}},
+		},
+		Summary: reconcile.Summary{Create: 1, Update: 2},
+		Notes:   []string{"label \"x\" is unmanaged"},
+	}
+	var b bytes.Buffer
+	if err := PrintPlan(&b, plan, Options{Format: FormatMarkdown}); err != nil {
+		t.Fatalf("PrintPlan: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"### Plan: 3 change(s)\n",
+		"**Summary:** 1 to create, 2 to update, 0 to move, 0 to delete, 0 to reorder.\n",
+		"<details open>\n<summary><b>Projects</b> (2)</summary>\n\n| | Action | Name | Changes |\n",
+		"| + | create | `Homelab` |  |\n",
+		"| ~ | update | `Work` | `color`: `blue` -> `red`<br>`view_style`: _(empty)_ -> `board` |\n",
+		"<summary><b>Filters</b> (1)</summary>",
+		"| ~ | update | `Work \\| Today` | `query`: `#Work` -> `#Work & today` |\n",
+		"**Notes:**\n- label \"x\" is unmanaged\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "</details>") != 2 {
+		t.Errorf("expected one collapsible block per kind:\n%s", out)
+	}
+}
+
+func TestPrintPlan_MarkdownNoChanges(t *testing.T) {
+	var b bytes.Buffer
+	if err := PrintPlan(&b, &reconcile.Plan{}, Options{Format: FormatMarkdown}); err != nil {
+		t.Fatalf("PrintPlan: %v", err)
+	}
+	if b.String() != "### Plan: no changes\n" {
+		t.Fatalf("unexpected output: %q", b.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatText, "text": FormatText, "JSON": FormatJSON, "markdown": FormatMarkdown} {
+		got, err := ParseFormat(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("html"); err == nil {
+		t.Fatalf("expected an error for an unknown format")
+	}
+}
@@ -5,19 +5,50 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
 )
 
+// Format selects how results are rendered. Markdown and JUnit apply to plans and apply results
+// (and JUnit to validation, see PrintValidateJUnit); other output falls back to text.
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+	FormatJUnit    Format = "junit"
+)
+
+// ParseFormat validates a --format value ("" means text).
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatJSON, FormatMarkdown, FormatJUnit:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown --format %q (expected text, json, markdown or junit)", s)
+	}
+}
+
 type Options struct {
-	JSON bool
+	Format Format
 }
 
 func PrintPlan(w io.Writer, plan *reconcile.Plan, opts Options) error {
-	if opts.JSON {
+	switch opts.Format {
+	case FormatJSON:
 		enc := json.NewEncoder(w)
 		enc.SetIndent("", "  ")
 		return enc.Encode(plan)
+	case FormatMarkdown:
+		return printPlanMarkdown(w, plan)
+	case FormatJUnit:
+		// Without the config only resources with changes are known; see PrintPlanJUnit.
+		return PrintPlanJUnit(w, nil, plan)
 	}
 
 	fmt.Fprintln(w, "Plan:")
@@ -60,10 +91,15 @@ func PrintPlan(w io.Writer, plan *reconcile.Plan, opts Options) error {
 }
 
 func PrintApplyResult(w io.Writer, res *reconcile.ApplyResult, opts Options) error {
-	if opts.JSON {
+	switch opts.Format {
+	case FormatJSON:
 		enc := json.NewEncoder(w)
 		enc.SetIndent("", "  ")
 		return enc.Encode(res)
+	case FormatMarkdown:
+		return printApplyResultMarkdown(w, res)
+	case FormatJUnit:
+		return printApplyResultJUnit(w, res)
 	}
 	if res == nil {
 		fmt.Fprintln(w, "No results.")
@@ -85,9 +121,33 @@ func PrintApplyResult(w io.Writer, res *reconcile.ApplyResult, opts Options) err
 	fmt.Fprintln(w)
 	fmt.Fprintf(w, "Summary: %d to create, %d to update, %d to move, %d to delete, %d to reorder.\n",
 		res.Summary.Create, res.Summary.Update, res.Summary.Move, res.Summary.Delete, res.Summary.Reorder)
+	if res.Failed > 0 || res.Skipped > 0 {
+		fmt.Fprintf(w, "Errors: %d failed, %d skipped.\n", res.Failed, res.Skipped)
+	}
 	return nil
 }
 
+func PrintState(w io.Writer, resources []state.Resource, opts Options) error {
+	if opts.Format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if resources == nil {
+			resources = []state.Resource{}
+		}
+		return enc.Encode(resources)
+	}
+	if len(resources) == 0 {
+		fmt.Fprintln(w, "No state entries.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tNAME\tID")
+	for _, r := range resources {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Kind, r.Name, r.ID)
+	}
+	return tw.Flush()
+}
+
 func symbol(action reconcile.Action) string {
 	switch action {
 	case reconcile.ActionCreate:
@@ -103,12 +163,16 @@ func kindHeading(k reconcile.Kind) string {
 	switch k {
 	case reconcile.KindProject:
 		return "Projects"
+	case reconcile.KindSection:
+		return "Sections"
 	case reconcile.KindLabel:
 		return "Labels"
 	case reconcile.KindFilter:
 		return "Filters"
 	case reconcile.KindTask:
 		return "Tasks"
+	case reconcile.KindReminder:
+		return "Reminders"
 	default:
 		s := string(k)
 		if s == "" {
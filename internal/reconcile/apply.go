@@ -2,6 +2,7 @@ package reconcile
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 
@@ -17,6 +18,9 @@ type Clients struct {
 	Sync *todoistsync.Client
 }
 
+// Apply executes plan against Todoist. It stops at the first failed operation unless
+// opts.ContinueOnError is set; either way the returned result lists every operation attempted
+// so far, and is non-nil whenever err comes from Todoist rather than from invalid input.
 func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan *Plan, clients Clients, opts Options) (*ApplyResult, error) {
 	if cfg == nil || snap == nil || plan == nil {
 		return nil, fmt.Errorf("cfg/snapshot/plan must be non-nil")
@@ -26,12 +30,19 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 	}
 
 	res := &ApplyResult{Summary: plan.Summary}
+	run := newApplyRun(res, opts.ContinueOnError)
 
 	// Precompute project name -> id map (updated as we create).
 	projectNameToID := map[string]string{}
 	for _, p := range snap.Projects {
 		projectNameToID[p.Name] = p.ID
 	}
+	// Renamed projects are referenced by their new name (parents, sections, tasks).
+	for _, op := range filterOps(plan.Operations, KindProject, ActionUpdate) {
+		if op.ProjectPayload != nil && hasChange(op, "name") {
+			projectNameToID[op.ProjectPayload.DesiredName] = op.ID
+		}
+	}
 
 	// --- Projects: Create (topological by parent)
 	projectCreates := filterOps(plan.Operations, KindProject, ActionCreate)
@@ -42,13 +53,16 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 	for _, op := range sortedCreates {
 		payload := op.ProjectPayload
 		if payload == nil {
-			return nil, fmt.Errorf("project create op missing payload for %q", op.Name)
+			return res, fmt.Errorf("project create op missing payload for %q", op.Name)
+		}
+		if payload.ParentName != nil && run.skipIfBlocked(op, KindProject, *payload.ParentName) {
+			continue
 		}
 		var parentID *string
 		if payload.ParentName != nil {
 			pid, ok := projectNameToID[*payload.ParentName]
 			if !ok {
-				return nil, fmt.Errorf("project %q parent %q not found (create ordering bug)", op.Name, *payload.ParentName)
+				return res, fmt.Errorf("project %q parent %q not found (create ordering bug)", op.Name, *payload.ParentName)
 			}
 			parentID = &pid
 		}
@@ -60,19 +74,20 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 			ViewStyle:  payload.ViewStyle,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("create project %q: %w", op.Name, err)
+			if err := run.fail(op, fmt.Errorf("create project %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
 		}
 		projectNameToID[created.Name] = created.ID
-		res.Applied = append(res.Applied, OperationResult{Kind: KindProject, Action: ActionCreate, Name: op.Name, ID: created.ID, Status: "ok"})
+		run.ok(op, created.ID)
 	}
 
 	// --- Projects: Update (Unified API)
-	projectUpdates := filterOps(plan.Operations, KindProject, ActionUpdate)
-	sort.Slice(projectUpdates, func(i, j int) bool { return projectUpdates[i].Name < projectUpdates[j].Name })
-	for _, op := range projectUpdates {
+	for _, op := range sortedOps(plan.Operations, KindProject, ActionUpdate) {
 		payload := op.ProjectPayload
 		if payload == nil {
-			return nil, fmt.Errorf("project update op missing payload for %q", op.Name)
+			return res, fmt.Errorf("project update op missing payload for %q", op.Name)
 		}
 		req := v1.UpdateProjectRequest{}
 		for _, ch := range op.Changes {
@@ -88,54 +103,120 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 				req.ViewStyle = payload.ViewStyle
 			}
 		}
-		_, err := clients.V1.UpdateProject(ctx, op.ID, req)
-		if err != nil {
-			return nil, fmt.Errorf("update project %q: %w", op.Name, err)
+		if _, err := clients.V1.UpdateProject(ctx, op.ID, req); err != nil {
+			if err := run.fail(op, fmt.Errorf("update project %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindProject, Action: ActionUpdate, Name: op.Name, ID: op.ID, Status: "ok"})
+		run.ok(op, op.ID)
 	}
 
 	// --- Projects: Move parent (sync)
-	projectMoves := filterOps(plan.Operations, KindProject, ActionMove)
-	sort.Slice(projectMoves, func(i, j int) bool { return projectMoves[i].Name < projectMoves[j].Name })
-	if len(projectMoves) > 0 {
-		var cmds []todoistsync.Command
-		for _, op := range projectMoves {
-			payload := op.ProjectPayload
-			if payload == nil {
-				return nil, fmt.Errorf("project move op missing payload for %q", op.Name)
+	var moveSteps []syncStep
+	for _, op := range sortedOps(plan.Operations, KindProject, ActionMove) {
+		payload := op.ProjectPayload
+		if payload == nil {
+			return res, fmt.Errorf("project move op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"id": op.ID}
+		if payload.ParentName == nil {
+			args["parent_id"] = nil
+		} else {
+			if run.skipIfBlocked(op, KindProject, *payload.ParentName) {
+				continue
 			}
-			args := map[string]any{"id": op.ID}
-			if payload.ParentName == nil {
-				args["parent_id"] = nil
-			} else {
-				pid, ok := projectNameToID[*payload.ParentName]
-				if !ok {
-					return nil, fmt.Errorf("move project %q: parent %q id not found", op.Name, *payload.ParentName)
-				}
-				args["parent_id"] = pid
+			pid, ok := projectNameToID[*payload.ParentName]
+			if !ok {
+				return res, fmt.Errorf("move project %q: parent %q id not found", op.Name, *payload.ParentName)
 			}
-			cmds = append(cmds, todoistsync.NewCommand("project_move", args))
+			args["parent_id"] = pid
+		}
+		moveSteps = append(moveSteps, syncStep{op: op, cmd: todoistsync.NewCommand("project_move", args)})
+	}
+	if _, err := run.runSteps(ctx, clients.Sync, "project_move", moveSteps); err != nil {
+		return res, err
+	}
+
+	// --- Sections: Create/Update (Unified API), Reorder (sync)
+	// Created sections by op name, for the tasks placed in them.
+	sectionIDs := map[string]string{}
+	for _, op := range sortedOps(plan.Operations, KindSection, ActionCreate) {
+		payload := op.SectionPayload
+		if payload == nil {
+			return res, fmt.Errorf("section create op missing payload for %q", op.Name)
+		}
+		if run.skipIfBlocked(op, KindProject, payload.ProjectName) {
+			continue
 		}
-		resp, err := clients.Sync.RunCommands(ctx, cmds)
+		projectID, ok := projectNameToID[payload.ProjectName]
+		if !ok {
+			return res, fmt.Errorf("section %q project %q not found (create ordering bug)", op.Name, payload.ProjectName)
+		}
+		req := v1.CreateSectionRequest{Name: payload.DesiredName, ProjectID: projectID}
+		if payload.Order > 0 {
+			ord := payload.Order
+			req.Order = &ord
+		}
+		created, err := clients.V1.CreateSection(ctx, req)
 		if err != nil {
-			return nil, fmt.Errorf("sync project_move: %w", err)
+			if err := run.fail(op, fmt.Errorf("create section %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
+		}
+		sectionIDs[op.Name] = created.ID
+		run.ok(op, created.ID)
+	}
+
+	for _, op := range sortedOps(plan.Operations, KindSection, ActionUpdate) {
+		payload := op.SectionPayload
+		if payload == nil {
+			return res, fmt.Errorf("section update op missing payload for %q", op.Name)
+		}
+		n := payload.DesiredName
+		if _, err := clients.V1.UpdateSection(ctx, op.ID, v1.UpdateSectionRequest{Name: &n}); err != nil {
+			if err := run.fail(op, fmt.Errorf("update section %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
+		}
+		run.ok(op, op.ID)
+	}
+
+	sectionReorders := sortedOps(plan.Operations, KindSection, ActionReorder)
+	if len(sectionReorders) > 0 {
+		// One section_reorder command per project.
+		byProject := map[string][]map[string]any{}
+		var projectOrder []string
+		for _, op := range sectionReorders {
+			payload := op.SectionPayload
+			if payload == nil {
+				return res, fmt.Errorf("section reorder op missing payload for %q", op.Name)
+			}
+			if _, ok := byProject[payload.ProjectName]; !ok {
+				projectOrder = append(projectOrder, payload.ProjectName)
+			}
+			byProject[payload.ProjectName] = append(byProject[payload.ProjectName], map[string]any{"id": op.ID, "section_order": payload.Order})
+		}
+		cmdByProject := map[string]todoistsync.Command{}
+		for _, name := range projectOrder {
+			cmdByProject[name] = todoistsync.NewCommand("section_reorder", map[string]any{"sections": byProject[name]})
 		}
-		if err := todoistsync.RequireAllOK(resp, cmds); err != nil {
-			return nil, fmt.Errorf("sync project_move statuses: %w", err)
+		var steps []syncStep
+		for _, op := range sectionReorders {
+			steps = append(steps, syncStep{op: op, cmd: cmdByProject[op.SectionPayload.ProjectName]})
 		}
-		for _, op := range projectMoves {
-			res.Applied = append(res.Applied, OperationResult{Kind: KindProject, Action: ActionMove, Name: op.Name, ID: op.ID, Status: "ok"})
+		if _, err := run.runSteps(ctx, clients.Sync, "section_reorder", steps); err != nil {
+			return res, err
 		}
 	}
 
 	// --- Labels
-	labelCreates := filterOps(plan.Operations, KindLabel, ActionCreate)
-	sort.Slice(labelCreates, func(i, j int) bool { return labelCreates[i].Name < labelCreates[j].Name })
-	for _, op := range labelCreates {
+	for _, op := range sortedOps(plan.Operations, KindLabel, ActionCreate) {
 		payload := op.LabelPayload
 		if payload == nil {
-			return nil, fmt.Errorf("label create op missing payload for %q", op.Name)
+			return res, fmt.Errorf("label create op missing payload for %q", op.Name)
 		}
 		created, err := clients.V1.CreateLabel(ctx, v1.CreateLabelRequest{
 			Name:       payload.DesiredName,
@@ -143,17 +224,18 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 			IsFavorite: payload.IsFavorite,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("create label %q: %w", op.Name, err)
+			if err := run.fail(op, fmt.Errorf("create label %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindLabel, Action: ActionCreate, Name: op.Name, ID: created.ID, Status: "ok"})
+		run.ok(op, created.ID)
 	}
 
-	labelUpdates := filterOps(plan.Operations, KindLabel, ActionUpdate)
-	sort.Slice(labelUpdates, func(i, j int) bool { return labelUpdates[i].Name < labelUpdates[j].Name })
-	for _, op := range labelUpdates {
+	for _, op := range sortedOps(plan.Operations, KindLabel, ActionUpdate) {
 		payload := op.LabelPayload
 		if payload == nil {
-			return nil, fmt.Errorf("label update op missing payload for %q", op.Name)
+			return res, fmt.Errorf("label update op missing payload for %q", op.Name)
 		}
 		req := v1.UpdateLabelRequest{}
 		for _, ch := range op.Changes {
@@ -167,11 +249,20 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 				req.IsFavorite = payload.IsFavorite
 			}
 		}
-		_, err := clients.V1.UpdateLabel(ctx, op.ID, req)
-		if err != nil {
-			return nil, fmt.Errorf("update label %q: %w", op.Name, err)
+		if _, err := clients.V1.UpdateLabel(ctx, op.ID, req); err != nil {
+			err = fmt.Errorf("update label %q: %w", op.Name, err)
+			if hasChange(op, "name") {
+				// Tasks would otherwise recreate the label under its new name.
+				err = run.fail(op, err, payload.DesiredName)
+			} else {
+				err = run.fail(op, err)
+			}
+			if err != nil {
+				return res, err
+			}
+			continue
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindLabel, Action: ActionUpdate, Name: op.Name, ID: op.ID, Status: "ok"})
+		run.ok(op, op.ID)
 	}
 
 	// --- Filters (sync commands)
@@ -179,25 +270,22 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 	for _, f := range snap.Filters {
 		filterNameToID[f.Name] = f.ID
 	}
+	for _, op := range filterOps(plan.Operations, KindFilter, ActionUpdate) {
+		if op.FilterPayload != nil && hasChange(op, "name") {
+			filterNameToID[op.FilterPayload.DesiredName] = op.FilterPayload.RemoteID
+		}
+	}
 
-	filterCreates := filterOps(plan.Operations, KindFilter, ActionCreate)
-	filterUpdates := filterOps(plan.Operations, KindFilter, ActionUpdate)
-	filterDeletes := filterOps(plan.Operations, KindFilter, ActionDelete)
-
-	sort.Slice(filterCreates, func(i, j int) bool { return filterCreates[i].Name < filterCreates[j].Name })
-	sort.Slice(filterUpdates, func(i, j int) bool { return filterUpdates[i].Name < filterUpdates[j].Name })
-	sort.Slice(filterDeletes, func(i, j int) bool { return filterDeletes[i].Name < filterDeletes[j].Name })
-
-	var filterCmds []todoistsync.Command
-	tempIDToName := map[string]string{}
+	filterCreates := sortedOps(plan.Operations, KindFilter, ActionCreate)
+	filterUpdates := sortedOps(plan.Operations, KindFilter, ActionUpdate)
+	filterDeletes := sortedOps(plan.Operations, KindFilter, ActionDelete)
 
+	var filterSteps []syncStep
 	for _, op := range filterCreates {
 		payload := op.FilterPayload
 		if payload == nil {
-			return nil, fmt.Errorf("filter create op missing payload for %q", op.Name)
+			return res, fmt.Errorf("filter create op missing payload for %q", op.Name)
 		}
-		tempID := uuid.NewString()
-		tempIDToName[tempID] = op.Name
 		args := map[string]any{"name": payload.DesiredName, "query": payload.Query}
 		if payload.Color != nil {
 			args["color"] = *payload.Color
@@ -208,12 +296,12 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 		if payload.Order > 0 {
 			args["item_order"] = payload.Order
 		}
-		filterCmds = append(filterCmds, todoistsync.NewTempIDCommand("filter_add", tempID, args))
+		filterSteps = append(filterSteps, syncStep{op: op, cmd: todoistsync.NewTempIDCommand("filter_add", uuid.NewString(), args)})
 	}
 	for _, op := range filterUpdates {
 		payload := op.FilterPayload
 		if payload == nil {
-			return nil, fmt.Errorf("filter update op missing payload for %q", op.Name)
+			return res, fmt.Errorf("filter update op missing payload for %q", op.Name)
 		}
 		args := map[string]any{"id": payload.RemoteID}
 		for _, ch := range op.Changes {
@@ -234,139 +322,120 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 				args["item_order"] = payload.Order
 			}
 		}
-		filterCmds = append(filterCmds, todoistsync.NewCommand("filter_update", args))
+		filterSteps = append(filterSteps, syncStep{op: op, cmd: todoistsync.NewCommand("filter_update", args)})
 	}
 	for _, op := range filterDeletes {
 		payload := op.FilterPayload
 		if payload == nil {
-			return nil, fmt.Errorf("filter delete op missing payload for %q", op.Name)
+			return res, fmt.Errorf("filter delete op missing payload for %q", op.Name)
 		}
-		args := map[string]any{"id": payload.RemoteID}
-		filterCmds = append(filterCmds, todoistsync.NewCommand("filter_delete", args))
+		filterSteps = append(filterSteps, syncStep{op: op, cmd: todoistsync.NewCommand("filter_delete", map[string]any{"id": payload.RemoteID})})
 	}
-
-	if len(filterCmds) > 0 {
-		resp, err := clients.Sync.RunCommands(ctx, filterCmds)
+	if len(filterSteps) > 0 {
+		resp, err := run.runSteps(ctx, clients.Sync, "filter commands", filterSteps)
 		if err != nil {
-			return nil, fmt.Errorf("sync filter commands: %w", err)
-		}
-		if err := todoistsync.RequireAllOK(resp, filterCmds); err != nil {
-			return nil, fmt.Errorf("sync filter statuses: %w", err)
+			return res, err
 		}
-
 		// Map newly created filters.
-		for tempID, newID := range resp.TempIDMapping {
-			name, ok := tempIDToName[tempID]
-			if !ok {
-				continue
+		if resp != nil {
+			for _, st := range filterSteps {
+				if st.cmd.TempID == nil {
+					continue
+				}
+				if id, ok := resp.TempIDMapping[*st.cmd.TempID]; ok {
+					filterNameToID[st.op.Name] = id
+				}
 			}
-			filterNameToID[name] = newID
-		}
-
-		for _, op := range filterCreates {
-			res.Applied = append(res.Applied, OperationResult{Kind: KindFilter, Action: ActionCreate, Name: op.Name, Status: "ok"})
-		}
-		for _, op := range filterUpdates {
-			res.Applied = append(res.Applied, OperationResult{Kind: KindFilter, Action: ActionUpdate, Name: op.Name, ID: op.ID, Status: "ok"})
-		}
-		for _, op := range filterDeletes {
-			res.Applied = append(res.Applied, OperationResult{Kind: KindFilter, Action: ActionDelete, Name: op.Name, ID: op.ID, Status: "ok"})
 		}
 	}
 
 	// Apply filter order as a bulk command for determinism when there were create/update changes.
-	needFilterOrderUpdate := false
-	for _, op := range append(filterCreates, filterUpdates...) {
-		if op.Action == ActionCreate {
-			needFilterOrderUpdate = true
-			break
-		}
-		for _, ch := range op.Changes {
-			if ch.Field == "order" {
-				needFilterOrderUpdate = true
-				break
+	if needFilterReorder(filterCreates, filterUpdates) && len(cfg.Spec.Filters) > 0 {
+		reorder := Operation{Kind: KindFilter, Action: ActionReorder, Name: "filters"}
+		if blocked := run.failedKinds(KindFilter); blocked != "" {
+			run.skip(reorder, blocked)
+		} else {
+			idOrder, err := filterOrderMapping(cfg, filterNameToID, len(opts.Targets) > 0)
+			if err != nil {
+				return res, err
 			}
-		}
-		if needFilterOrderUpdate {
-			break
-		}
-	}
-	if needFilterOrderUpdate && len(cfg.Spec.Filters) > 0 {
-		idOrder := map[string]int{}
-		for _, f := range cfg.Spec.Filters {
-			id := ""
-			if f.ID != nil {
-				id = *f.ID
-			} else {
-				var ok bool
-				id, ok = filterNameToID[f.Name]
-				if !ok {
-					return nil, fmt.Errorf("filter %q id missing after create/update", f.Name)
-				}
+			steps := []syncStep{{op: reorder, cmd: todoistsync.NewCommand("filter_update_orders", map[string]any{"id_order_mapping": idOrder})}}
+			if _, err := run.runSteps(ctx, clients.Sync, "filter_update_orders", steps); err != nil {
+				return res, err
 			}
-			ord := 0
-			if f.Order != nil {
-				ord = *f.Order
-			}
-			idOrder[id] = ord
-		}
-		cmds := []todoistsync.Command{
-			todoistsync.NewCommand("filter_update_orders", map[string]any{"id_order_mapping": idOrder}),
-		}
-		resp, err := clients.Sync.RunCommands(ctx, cmds)
-		if err != nil {
-			return nil, fmt.Errorf("sync filter_update_orders: %w", err)
-		}
-		if err := todoistsync.RequireAllOK(resp, cmds); err != nil {
-			return nil, fmt.Errorf("sync filter_update_orders statuses: %w", err)
+			res.Summary.Reorder++
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindFilter, Action: ActionReorder, Name: "filters", Status: "ok"})
-		res.Summary.Reorder++
 	}
 
 	// --- Tasks (managed templates)
-	taskCreates := filterOps(plan.Operations, KindTask, ActionCreate)
-	sort.Slice(taskCreates, func(i, j int) bool { return taskCreates[i].Name < taskCreates[j].Name })
-	for _, op := range taskCreates {
+	// Created tasks by key, for their subtasks and reminders.
+	taskIDByKey := map[string]string{}
+	for _, op := range tasksParentFirst(sortedOps(plan.Operations, KindTask, ActionCreate)) {
 		payload := op.TaskPayload
 		if payload == nil {
-			return nil, fmt.Errorf("task create op missing payload for %q", op.Name)
+			return res, fmt.Errorf("task create op missing payload for %q", op.Name)
+		}
+		if run.skipTaskIfBlocked(op) || run.skipIfSectionBlocked(op) || run.skipIfParentBlocked(op) {
+			continue
+		}
+		parentID, err := taskParentID(op, taskIDByKey)
+		if err != nil {
+			return res, err
+		}
+		sectionID, err := taskSectionID(op, sectionIDs)
+		if err != nil {
+			return res, err
 		}
 		projectID := payload.ProjectID
 		if projectID == nil && payload.ProjectName != nil {
 			if pid, ok := projectNameToID[*payload.ProjectName]; ok {
 				projectID = &pid
 			} else {
-				return nil, fmt.Errorf("task %q references unknown project %q at apply time", op.Name, *payload.ProjectName)
+				return res, fmt.Errorf("task %q references unknown project %q at apply time", op.Name, *payload.ProjectName)
 			}
 		}
-		created, err := clients.V1.CreateTask(ctx, v1.CreateTaskRequest{
+		req := v1.CreateTaskRequest{
 			Content:     payload.DesiredName,
 			Description: payload.Description,
 			ProjectID:   projectID,
 			Labels:      payload.Labels,
 			Priority:    payload.Priority,
 			DueString:   payload.DueString,
-		})
+		}
+		if sectionID != "" {
+			req.SectionID = &sectionID
+		}
+		if parentID != "" {
+			order := payload.Order
+			req.ParentID, req.Order = &parentID, &order
+		}
+		created, err := clients.V1.CreateTask(ctx, req)
 		if err != nil {
-			return nil, fmt.Errorf("create task %q: %w", op.Name, err)
+			if err := run.fail(op, fmt.Errorf("create task %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
+		}
+		if payload.Key != "" {
+			taskIDByKey[payload.Key] = created.ID
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindTask, Action: ActionCreate, Name: op.Name, ID: created.ID, Status: "ok"})
+		run.ok(op, created.ID)
 	}
 
-	taskUpdates := filterOps(plan.Operations, KindTask, ActionUpdate)
-	sort.Slice(taskUpdates, func(i, j int) bool { return taskUpdates[i].Name < taskUpdates[j].Name })
-	for _, op := range taskUpdates {
+	for _, op := range sortedOps(plan.Operations, KindTask, ActionUpdate) {
 		payload := op.TaskPayload
 		if payload == nil {
-			return nil, fmt.Errorf("task update op missing payload for %q", op.Name)
+			return res, fmt.Errorf("task update op missing payload for %q", op.Name)
+		}
+		if run.skipTaskIfBlocked(op) {
+			continue
 		}
 		projectID := payload.ProjectID
 		if projectID == nil && payload.ProjectName != nil {
 			if pid, ok := projectNameToID[*payload.ProjectName]; ok {
 				projectID = &pid
 			} else {
-				return nil, fmt.Errorf("task %q references unknown project %q at apply time", op.Name, *payload.ProjectName)
+				return res, fmt.Errorf("task %q references unknown project %q at apply time", op.Name, *payload.ProjectName)
 			}
 		}
 		req := v1.UpdateTaskRequest{}
@@ -375,7 +444,7 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 			case "content":
 				v := payload.DesiredName
 				req.Content = &v
-			case "description":
+			case "description", "key":
 				req.Description = payload.Description
 			case "project":
 				req.ProjectID = projectID
@@ -388,33 +457,113 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 				req.DueString = payload.DueString
 			}
 		}
-		_, err := clients.V1.UpdateTask(ctx, op.ID, req)
+		if _, err := clients.V1.UpdateTask(ctx, op.ID, req); err != nil {
+			if err := run.fail(op, fmt.Errorf("update task %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
+		}
+		run.ok(op, op.ID)
+	}
+
+	// --- Task moves: Parent or section, Reorder (sync)
+	var taskMoveSteps []syncStep
+	for _, op := range sortedOps(plan.Operations, KindTask, ActionMove) {
+		if op.TaskPayload == nil {
+			return res, fmt.Errorf("task move op missing payload for %q", op.Name)
+		}
+		if run.skipIfSectionBlocked(op) || run.skipIfParentBlocked(op) {
+			continue
+		}
+		args, err := taskMoveArgs(op, taskIDByKey, sectionIDs)
 		if err != nil {
-			return nil, fmt.Errorf("update task %q: %w", op.Name, err)
+			return res, err
+		}
+		taskMoveSteps = append(taskMoveSteps, syncStep{op: op, cmd: todoistsync.NewCommand("item_move", args)})
+	}
+	for _, op := range sortedOps(plan.Operations, KindTask, ActionReorder) {
+		if op.TaskPayload == nil {
+			return res, fmt.Errorf("task reorder op missing payload for %q", op.Name)
+		}
+		if run.skipIfParentBlocked(op) {
+			continue
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindTask, Action: ActionUpdate, Name: op.Name, ID: op.ID, Status: "ok"})
+		taskMoveSteps = append(taskMoveSteps, syncStep{op: op, cmd: todoistsync.NewCommand("item_reorder", taskReorderArgs(op))})
+	}
+	if _, err := run.runSteps(ctx, clients.Sync, "task moves", taskMoveSteps); err != nil {
+		return res, err
 	}
 
-	// --- Deletes (labels, projects) last; project deletes child-first.
+	// --- Reminders (sync; there is no v1 endpoint)
+	var reminderSteps []syncStep
+	for _, op := range sortedOps(plan.Operations, KindReminder, ActionCreate) {
+		payload := op.ReminderPayload
+		if payload == nil {
+			return res, fmt.Errorf("reminder create op missing payload for %q", op.Name)
+		}
+		if run.skipIfBlocked(op, KindTask, payload.TaskName) {
+			continue
+		}
+		taskID := payload.TaskID
+		if taskID == "" {
+			id, ok := taskIDByKey[payload.TaskKey]
+			if !ok {
+				return res, fmt.Errorf("reminder %q: task %q not found (create ordering bug)", op.Name, payload.TaskName)
+			}
+			taskID = id
+		}
+		args := reminderArgs(payload)
+		args["item_id"] = taskID
+		reminderSteps = append(reminderSteps, syncStep{op: op, cmd: todoistsync.NewTempIDCommand("reminder_add", uuid.NewString(), args)})
+	}
+	for _, op := range sortedOps(plan.Operations, KindReminder, ActionUpdate) {
+		payload := op.ReminderPayload
+		if payload == nil {
+			return res, fmt.Errorf("reminder update op missing payload for %q", op.Name)
+		}
+		args := reminderArgs(payload)
+		args["id"] = op.ID
+		reminderSteps = append(reminderSteps, syncStep{op: op, cmd: todoistsync.NewCommand("reminder_update", args)})
+	}
+	for _, op := range sortedOps(plan.Operations, KindReminder, ActionDelete) {
+		reminderSteps = append(reminderSteps, syncStep{op: op, cmd: todoistsync.NewCommand("reminder_delete", map[string]any{"id": op.ID})})
+	}
+	if _, err := run.runSteps(ctx, clients.Sync, "reminders", reminderSteps); err != nil {
+		return res, err
+	}
+
+	// --- Deletes last: tasks, sections, labels, then projects child-first.
 	// Tasks (only managed tasks selected by planner)
-	taskDeletes := filterOps(plan.Operations, KindTask, ActionDelete)
-	sort.Slice(taskDeletes, func(i, j int) bool { return taskDeletes[i].Name < taskDeletes[j].Name })
-	for _, op := range taskDeletes {
+	for _, op := range sortedOps(plan.Operations, KindTask, ActionDelete) {
 		if err := clients.V1.DeleteTask(ctx, op.ID); err != nil {
-			return nil, fmt.Errorf("delete task %q: %w", op.Name, err)
+			if err := run.fail(op, fmt.Errorf("delete task %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindTask, Action: ActionDelete, Name: op.Name, ID: op.ID, Status: "ok"})
+		run.ok(op, op.ID)
+	}
+
+	// Sections (deleting a section also deletes its tasks in Todoist)
+	for _, op := range sortedOps(plan.Operations, KindSection, ActionDelete) {
+		if err := clients.V1.DeleteSection(ctx, op.ID); err != nil {
+			if err := run.fail(op, fmt.Errorf("delete section %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
+		}
+		run.ok(op, op.ID)
 	}
 
-	// --- Deletes (labels, projects) last; project deletes child-first.
 	// Labels
-	labelDeletes := filterOps(plan.Operations, KindLabel, ActionDelete)
-	sort.Slice(labelDeletes, func(i, j int) bool { return labelDeletes[i].Name < labelDeletes[j].Name })
-	for _, op := range labelDeletes {
+	for _, op := range sortedOps(plan.Operations, KindLabel, ActionDelete) {
 		if err := clients.V1.DeleteLabel(ctx, op.ID); err != nil {
-			return nil, fmt.Errorf("delete label %q: %w", op.Name, err)
+			if err := run.fail(op, fmt.Errorf("delete label %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindLabel, Action: ActionDelete, Name: op.Name, ID: op.ID, Status: "ok"})
+		run.ok(op, op.ID)
 	}
 
 	// Projects
@@ -422,12 +571,233 @@ func Apply(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan
 	projectDeletes = sortProjectsByDepthDesc(projectDeletes, snap)
 	for _, op := range projectDeletes {
 		if err := clients.V1.DeleteProject(ctx, op.ID); err != nil {
-			return nil, fmt.Errorf("delete project %q: %w", op.Name, err)
+			if err := run.fail(op, fmt.Errorf("delete project %q: %w", op.Name, err)); err != nil {
+				return res, err
+			}
+			continue
 		}
-		res.Applied = append(res.Applied, OperationResult{Kind: KindProject, Action: ActionDelete, Name: op.Name, ID: op.ID, Status: "ok"})
+		run.ok(op, op.ID)
+	}
+
+	return res, run.err()
+}
+
+// syncStep pairs a plan operation with the /sync command that implements it. Several steps may
+// share a command (e.g. one section_reorder per project) or an operation (item_update + item_move).
+type syncStep struct {
+	op  Operation
+	cmd todoistsync.Command
+}
+
+// applyRun records the outcome of each operation in an ApplyResult. Failed creates (and label
+// renames) block the operations that reference them by name, which are recorded as skipped.
+type applyRun struct {
+	res             *ApplyResult
+	continueOnError bool
+	blocked         map[string]string // kind/name -> reason
+	errs            []error
+}
+
+func newApplyRun(res *ApplyResult, continueOnError bool) *applyRun {
+	return &applyRun{res: res, continueOnError: continueOnError, blocked: map[string]string{}}
+}
+
+func (r *applyRun) ok(op Operation, id string) {
+	r.res.Applied = append(r.res.Applied, OperationResult{Kind: op.Kind, Action: op.Action, Name: op.Name, ID: id, Status: "ok"})
+}
+
+// fail records a failed operation. It returns err when apply should stop, nil to carry on.
+// Creates block their own name; extra names block too (e.g. a label's new name).
+func (r *applyRun) fail(op Operation, err error, blocks ...string) error {
+	r.res.Applied = append(r.res.Applied, OperationResult{Kind: op.Kind, Action: op.Action, Name: op.Name, ID: op.ID, Status: err.Error()})
+	r.res.Failed++
+	r.errs = append(r.errs, err)
+	reason := fmt.Sprintf("%s %s %q failed", op.Kind, op.Action, op.Name)
+	if op.Action == ActionCreate {
+		r.blocked[blockKey(op.Kind, op.Name)] = reason
+	}
+	for _, name := range blocks {
+		r.blocked[blockKey(op.Kind, name)] = reason
 	}
+	if r.continueOnError {
+		return nil
+	}
+	return err
+}
+
+// skip records an operation that was not attempted because something it depends on failed.
+// A skipped create blocks its own dependents in turn.
+func (r *applyRun) skip(op Operation, reason string) {
+	r.res.Applied = append(r.res.Applied, OperationResult{Kind: op.Kind, Action: op.Action, Name: op.Name, ID: op.ID, Status: "skipped: " + reason})
+	r.res.Skipped++
+	if op.Action == ActionCreate {
+		r.blocked[blockKey(op.Kind, op.Name)] = reason
+	}
+}
 
-	return res, nil
+// skipIfBlocked skips op if the named resource it depends on failed.
+func (r *applyRun) skipIfBlocked(op Operation, kind Kind, name string) bool {
+	reason, ok := r.blocked[blockKey(kind, name)]
+	if ok {
+		r.skip(op, reason)
+	}
+	return ok
+}
+
+// skipTaskIfBlocked skips a task operation whose project or labels failed.
+func (r *applyRun) skipTaskIfBlocked(op Operation) bool {
+	payload := op.TaskPayload
+	if payload.ProjectName != nil && r.skipIfBlocked(op, KindProject, *payload.ProjectName) {
+		return true
+	}
+	for _, l := range payload.Labels {
+		if r.skipIfBlocked(op, KindLabel, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipIfSectionBlocked skips a task operation whose section was to be created but was not.
+func (r *applyRun) skipIfSectionBlocked(op Operation) bool {
+	payload := op.TaskPayload
+	return payload.SectionID == nil && payload.SectionName != nil && payload.ProjectName != nil &&
+		r.skipIfBlocked(op, KindSection, sectionOpName(*payload.ProjectName, *payload.SectionName))
+}
+
+// skipIfParentBlocked skips a subtask operation whose parent was to be created but was not.
+func (r *applyRun) skipIfParentBlocked(op Operation) bool {
+	payload := op.TaskPayload
+	return payload.ParentID == nil && payload.ParentName != nil && r.skipIfBlocked(op, KindTask, *payload.ParentName)
+}
+
+// failedKinds returns a reason if any operation of kind failed or was skipped, else "".
+func (r *applyRun) failedKinds(kind Kind) string {
+	for _, ar := range r.res.Applied {
+		if ar.Kind == kind && ar.Status != "ok" {
+			return fmt.Sprintf("%s %s %q did not apply", ar.Kind, ar.Action, ar.Name)
+		}
+	}
+	return ""
+}
+
+// runSteps submits the steps' commands in one RunCommands call and records one result per
+// operation: ok, or the first error among its commands. Created IDs come from temp_id_mapping.
+func (r *applyRun) runSteps(ctx context.Context, c *todoistsync.Client, what string, steps []syncStep) (*todoistsync.SyncResponse, error) {
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	var cmds []todoistsync.Command
+	seen := map[string]bool{}
+	for _, st := range steps {
+		if !seen[st.cmd.UUID] {
+			seen[st.cmd.UUID] = true
+			cmds = append(cmds, st.cmd)
+		}
+	}
+	resp, runErr := c.RunCommands(ctx, cmds)
+
+	type outcome struct {
+		op  Operation
+		id  string
+		err error
+	}
+	var order []string
+	outcomes := map[string]*outcome{}
+	for _, st := range steps {
+		key := st.op.SortKey()
+		o, ok := outcomes[key]
+		if !ok {
+			o = &outcome{op: st.op, id: st.op.ID}
+			outcomes[key] = o
+			order = append(order, key)
+		}
+		if o.err != nil {
+			continue
+		}
+		if _, sent := statusOf(resp, st.cmd); !sent && runErr != nil {
+			o.err = fmt.Errorf("sync %s: %w", what, runErr)
+			continue
+		}
+		o.err = todoistsync.CommandError(resp, st.cmd)
+		if o.err == nil && st.cmd.TempID != nil {
+			o.id = resp.TempIDMapping[*st.cmd.TempID]
+		}
+	}
+	var stop error
+	for _, key := range order {
+		o := outcomes[key]
+		if o.err == nil {
+			r.ok(o.op, o.id)
+			continue
+		}
+		if err := r.fail(o.op, o.err); err != nil && stop == nil {
+			stop = err
+		}
+	}
+	return resp, stop
+}
+
+// err summarizes recorded failures once apply has run to the end.
+func (r *applyRun) err() error {
+	if len(r.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d operation(s) failed, %d skipped: %w", r.res.Failed, r.res.Skipped, errors.Join(r.errs...))
+}
+
+func statusOf(resp *todoistsync.SyncResponse, cmd todoistsync.Command) (any, bool) {
+	if resp == nil {
+		return nil, false
+	}
+	st, ok := resp.SyncStatus[cmd.UUID]
+	return st, ok
+}
+
+func blockKey(kind Kind, name string) string {
+	return string(kind) + "/" + name
+}
+
+// needFilterReorder reports whether filter orders must be re-sent: after any create, or when an
+// update changes the order.
+func needFilterReorder(creates, updates []Operation) bool {
+	if len(creates) > 0 {
+		return true
+	}
+	for _, op := range updates {
+		if hasChange(op, "order") {
+			return true
+		}
+	}
+	return false
+}
+
+// filterOrderMapping builds the filter_update_orders id_order_mapping for every config filter.
+// In a targeted apply, filters that do not exist yet are skipped; they get ordered on a later
+// full apply.
+func filterOrderMapping(cfg *config.TodoistConfig, filterNameToID map[string]string, targeted bool) (map[string]int, error) {
+	idOrder := map[string]int{}
+	for _, f := range cfg.Spec.Filters {
+		id := ""
+		if f.ID != nil {
+			id = *f.ID
+		} else {
+			var ok bool
+			id, ok = filterNameToID[f.Name]
+			if !ok {
+				if targeted {
+					continue
+				}
+				return nil, fmt.Errorf("filter %q id missing after create/update", f.Name)
+			}
+		}
+		ord := 0
+		if f.Order != nil {
+			ord = *f.Order
+		}
+		idOrder[id] = ord
+	}
+	return idOrder, nil
 }
 
 func filterOps(ops []Operation, kind Kind, action Action) []Operation {
@@ -440,6 +810,15 @@ func filterOps(ops []Operation, kind Kind, action Action) []Operation {
 	return out
 }
 
+func hasChange(op Operation, field string) bool {
+	for _, ch := range op.Changes {
+		if ch.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
 func topoSortProjectCreates(creates []Operation) ([]Operation, error) {
 	if len(creates) == 0 {
 		return nil, nil
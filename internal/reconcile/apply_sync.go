@@ -0,0 +1,464 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	todoistsync "github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+)
+
+// ApplySync applies plan by compiling every operation into /sync commands, in the same order as
+// Apply, and submitting them through RunCommands (which splits them into batches). Objects created
+// in the same apply are referenced by temp ID, e.g. a child project's parent_id or a task's
+// project_id. Filter orders are sent in a second request once new filter IDs are known.
+//
+// Todoist runs every command even if an earlier one fails (commands referencing a failed temp ID
+// fail too), so the result always covers the whole plan; opts.ContinueOnError only decides
+// whether the filter reorder is still attempted.
+func ApplySync(ctx context.Context, cfg *config.TodoistConfig, snap *Snapshot, plan *Plan, clients Clients, opts Options) (*ApplyResult, error) {
+	if cfg == nil || snap == nil || plan == nil {
+		return nil, fmt.Errorf("cfg/snapshot/plan must be non-nil")
+	}
+	if clients.Sync == nil {
+		return nil, fmt.Errorf("todoist sync client must be non-nil")
+	}
+
+	res := &ApplyResult{Summary: plan.Summary}
+	run := newApplyRun(res, opts.ContinueOnError)
+	var steps []syncStep
+	add := func(op Operation, cmdType string, args map[string]any) string {
+		if op.ID == "" && op.Action == ActionCreate {
+			tempID := uuid.NewString()
+			steps = append(steps, syncStep{op: op, cmd: todoistsync.NewTempIDCommand(cmdType, tempID, args)})
+			return tempID
+		}
+		steps = append(steps, syncStep{op: op, cmd: todoistsync.NewCommand(cmdType, args)})
+		return ""
+	}
+
+	// Project name -> real or temp ID.
+	projectNameToID := map[string]string{}
+	for _, p := range snap.Projects {
+		projectNameToID[p.Name] = p.ID
+	}
+	for _, op := range filterOps(plan.Operations, KindProject, ActionUpdate) {
+		if op.ProjectPayload != nil && hasChange(op, "name") {
+			projectNameToID[op.ProjectPayload.DesiredName] = op.ID
+		}
+	}
+	projectID := func(opName, name string) (string, error) {
+		id, ok := projectNameToID[name]
+		if !ok {
+			return "", fmt.Errorf("%s: project %q not found (create ordering bug)", opName, name)
+		}
+		return id, nil
+	}
+
+	// --- Projects
+	projectCreates, err := topoSortProjectCreates(filterOps(plan.Operations, KindProject, ActionCreate))
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range projectCreates {
+		payload := op.ProjectPayload
+		if payload == nil {
+			return nil, fmt.Errorf("project create op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"name": payload.DesiredName}
+		if payload.ParentName != nil {
+			pid, err := projectID("project "+op.Name, *payload.ParentName)
+			if err != nil {
+				return nil, err
+			}
+			args["parent_id"] = pid
+		}
+		if payload.Color != nil {
+			args["color"] = *payload.Color
+		}
+		if payload.IsFavorite != nil {
+			args["is_favorite"] = *payload.IsFavorite
+		}
+		if payload.ViewStyle != nil {
+			args["view_style"] = *payload.ViewStyle
+		}
+		projectNameToID[payload.DesiredName] = add(op, "project_add", args)
+	}
+	for _, op := range sortedOps(plan.Operations, KindProject, ActionUpdate) {
+		payload := op.ProjectPayload
+		if payload == nil {
+			return nil, fmt.Errorf("project update op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"id": op.ID}
+		for _, ch := range op.Changes {
+			switch ch.Field {
+			case "name":
+				args["name"] = payload.DesiredName
+			case "color":
+				if payload.Color != nil {
+					args["color"] = *payload.Color
+				}
+			case "is_favorite":
+				if payload.IsFavorite != nil {
+					args["is_favorite"] = *payload.IsFavorite
+				}
+			case "view_style":
+				if payload.ViewStyle != nil {
+					args["view_style"] = *payload.ViewStyle
+				}
+			}
+		}
+		add(op, "project_update", args)
+	}
+	for _, op := range sortedOps(plan.Operations, KindProject, ActionMove) {
+		payload := op.ProjectPayload
+		if payload == nil {
+			return nil, fmt.Errorf("project move op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"id": op.ID, "parent_id": nil}
+		if payload.ParentName != nil {
+			pid, err := projectID("move project "+op.Name, *payload.ParentName)
+			if err != nil {
+				return nil, err
+			}
+			args["parent_id"] = pid
+		}
+		add(op, "project_move", args)
+	}
+
+	// --- Sections
+	sectionIDs := map[string]string{} // temp IDs of created sections by op name, for their tasks
+	for _, op := range sortedOps(plan.Operations, KindSection, ActionCreate) {
+		payload := op.SectionPayload
+		if payload == nil {
+			return nil, fmt.Errorf("section create op missing payload for %q", op.Name)
+		}
+		pid, err := projectID("section "+op.Name, payload.ProjectName)
+		if err != nil {
+			return nil, err
+		}
+		args := map[string]any{"name": payload.DesiredName, "project_id": pid}
+		if payload.Order > 0 {
+			args["section_order"] = payload.Order
+		}
+		sectionIDs[op.Name] = add(op, "section_add", args)
+	}
+	for _, op := range sortedOps(plan.Operations, KindSection, ActionUpdate) {
+		payload := op.SectionPayload
+		if payload == nil {
+			return nil, fmt.Errorf("section update op missing payload for %q", op.Name)
+		}
+		add(op, "section_update", map[string]any{"id": op.ID, "name": payload.DesiredName})
+	}
+	for _, op := range sortedOps(plan.Operations, KindSection, ActionReorder) {
+		payload := op.SectionPayload
+		if payload == nil {
+			return nil, fmt.Errorf("section reorder op missing payload for %q", op.Name)
+		}
+		// One command per section keeps the result per operation; Todoist applies them in order.
+		add(op, "section_reorder", map[string]any{"sections": []map[string]any{{"id": op.ID, "section_order": payload.Order}}})
+	}
+
+	// --- Labels
+	for _, op := range sortedOps(plan.Operations, KindLabel, ActionCreate) {
+		payload := op.LabelPayload
+		if payload == nil {
+			return nil, fmt.Errorf("label create op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"name": payload.DesiredName}
+		if payload.Color != nil {
+			args["color"] = *payload.Color
+		}
+		if payload.IsFavorite != nil {
+			args["is_favorite"] = *payload.IsFavorite
+		}
+		add(op, "label_add", args)
+	}
+	for _, op := range sortedOps(plan.Operations, KindLabel, ActionUpdate) {
+		payload := op.LabelPayload
+		if payload == nil {
+			return nil, fmt.Errorf("label update op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"id": op.ID}
+		for _, ch := range op.Changes {
+			switch ch.Field {
+			case "name":
+				args["name"] = payload.DesiredName
+			case "color":
+				if payload.Color != nil {
+					args["color"] = *payload.Color
+				}
+			case "is_favorite":
+				if payload.IsFavorite != nil {
+					args["is_favorite"] = *payload.IsFavorite
+				}
+			}
+		}
+		add(op, "label_update", args)
+	}
+
+	// --- Filters
+	filterNameToID := map[string]string{}
+	for _, f := range snap.Filters {
+		filterNameToID[f.Name] = f.ID
+	}
+	filterCreates := sortedOps(plan.Operations, KindFilter, ActionCreate)
+	filterUpdates := sortedOps(plan.Operations, KindFilter, ActionUpdate)
+	for _, op := range filterCreates {
+		payload := op.FilterPayload
+		if payload == nil {
+			return nil, fmt.Errorf("filter create op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"name": payload.DesiredName, "query": payload.Query}
+		if payload.Color != nil {
+			args["color"] = *payload.Color
+		}
+		if payload.IsFavorite != nil {
+			args["is_favorite"] = *payload.IsFavorite
+		}
+		if payload.Order > 0 {
+			args["item_order"] = payload.Order
+		}
+		add(op, "filter_add", args)
+	}
+	for _, op := range filterUpdates {
+		payload := op.FilterPayload
+		if payload == nil {
+			return nil, fmt.Errorf("filter update op missing payload for %q", op.Name)
+		}
+		if hasChange(op, "name") {
+			filterNameToID[payload.DesiredName] = payload.RemoteID
+		}
+		args := map[string]any{"id": payload.RemoteID}
+		for _, ch := range op.Changes {
+			switch ch.Field {
+			case "name":
+				args["name"] = payload.DesiredName
+			case "query":
+				args["query"] = payload.Query
+			case "color":
+				if payload.Color != nil {
+					args["color"] = *payload.Color
+				}
+			case "is_favorite":
+				if payload.IsFavorite != nil {
+					args["is_favorite"] = *payload.IsFavorite
+				}
+			case "order":
+				args["item_order"] = payload.Order
+			}
+		}
+		add(op, "filter_update", args)
+	}
+	for _, op := range sortedOps(plan.Operations, KindFilter, ActionDelete) {
+		payload := op.FilterPayload
+		if payload == nil {
+			return nil, fmt.Errorf("filter delete op missing payload for %q", op.Name)
+		}
+		add(op, "filter_delete", map[string]any{"id": payload.RemoteID})
+	}
+
+	// --- Tasks
+	taskProjectID := func(op Operation) (string, error) {
+		payload := op.TaskPayload
+		if payload.ProjectID != nil {
+			return *payload.ProjectID, nil
+		}
+		if payload.ProjectName != nil {
+			id, ok := projectNameToID[*payload.ProjectName]
+			if !ok {
+				return "", fmt.Errorf("task %q references unknown project %q at apply time", op.Name, *payload.ProjectName)
+			}
+			return id, nil
+		}
+		return "", nil
+	}
+	taskIDByKey := map[string]string{} // temp IDs of created tasks, for their subtasks and reminders
+	for _, op := range tasksParentFirst(sortedOps(plan.Operations, KindTask, ActionCreate)) {
+		payload := op.TaskPayload
+		if payload == nil {
+			return nil, fmt.Errorf("task create op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"content": payload.DesiredName}
+		parentID, err := taskParentID(op, taskIDByKey)
+		if err != nil {
+			return nil, err
+		}
+		if parentID != "" {
+			args["parent_id"] = parentID
+			args["child_order"] = payload.Order
+		}
+		if payload.Description != nil {
+			args["description"] = *payload.Description
+		}
+		pid, err := taskProjectID(op)
+		if err != nil {
+			return nil, err
+		}
+		if pid != "" {
+			args["project_id"] = pid
+		}
+		sectionID, err := taskSectionID(op, sectionIDs)
+		if err != nil {
+			return nil, err
+		}
+		if sectionID != "" {
+			args["section_id"] = sectionID
+		}
+		if len(payload.Labels) > 0 {
+			args["labels"] = payload.Labels
+		}
+		if payload.Priority != nil {
+			args["priority"] = *payload.Priority
+		}
+		if payload.DueString != nil {
+			args["due"] = map[string]any{"string": *payload.DueString}
+		}
+		tempID := add(op, "item_add", args)
+		if payload.Key != "" {
+			taskIDByKey[payload.Key] = tempID
+		}
+	}
+	for _, op := range sortedOps(plan.Operations, KindTask, ActionUpdate) {
+		payload := op.TaskPayload
+		if payload == nil {
+			return nil, fmt.Errorf("task update op missing payload for %q", op.Name)
+		}
+		args := map[string]any{"id": op.ID}
+		for _, ch := range op.Changes {
+			switch ch.Field {
+			case "content":
+				args["content"] = payload.DesiredName
+			case "description", "key":
+				if payload.Description != nil {
+					args["description"] = *payload.Description
+				}
+			case "labels":
+				args["labels"] = append([]string{}, payload.Labels...)
+			case "priority":
+				if payload.Priority != nil {
+					args["priority"] = *payload.Priority
+				}
+			case "due.string":
+				if payload.DueString != nil {
+					args["due"] = map[string]any{"string": *payload.DueString}
+				}
+			}
+		}
+		if len(args) > 1 {
+			add(op, "item_update", args)
+		}
+		if hasChange(op, "project") {
+			// item_update cannot change the project; moves are a separate command.
+			pid, err := taskProjectID(op)
+			if err != nil {
+				return nil, err
+			}
+			add(op, "item_move", map[string]any{"id": op.ID, "project_id": pid})
+		}
+	}
+
+	for _, op := range sortedOps(plan.Operations, KindTask, ActionMove) {
+		if op.TaskPayload == nil {
+			return nil, fmt.Errorf("task move op missing payload for %q", op.Name)
+		}
+		args, err := taskMoveArgs(op, taskIDByKey, sectionIDs)
+		if err != nil {
+			return nil, err
+		}
+		add(op, "item_move", args)
+	}
+	for _, op := range sortedOps(plan.Operations, KindTask, ActionReorder) {
+		if op.TaskPayload == nil {
+			return nil, fmt.Errorf("task reorder op missing payload for %q", op.Name)
+		}
+		add(op, "item_reorder", taskReorderArgs(op))
+	}
+
+	// --- Reminders
+	for _, op := range sortedOps(plan.Operations, KindReminder, ActionCreate) {
+		payload := op.ReminderPayload
+		if payload == nil {
+			return nil, fmt.Errorf("reminder create op missing payload for %q", op.Name)
+		}
+		taskID := payload.TaskID
+		if taskID == "" {
+			id, ok := taskIDByKey[payload.TaskKey]
+			if !ok {
+				return nil, fmt.Errorf("reminder %q: task %q not found (create ordering bug)", op.Name, payload.TaskName)
+			}
+			taskID = id
+		}
+		args := reminderArgs(payload)
+		args["item_id"] = taskID
+		add(op, "reminder_add", args)
+	}
+	for _, op := range sortedOps(plan.Operations, KindReminder, ActionUpdate) {
+		payload := op.ReminderPayload
+		if payload == nil {
+			return nil, fmt.Errorf("reminder update op missing payload for %q", op.Name)
+		}
+		args := reminderArgs(payload)
+		args["id"] = op.ID
+		add(op, "reminder_update", args)
+	}
+	for _, op := range sortedOps(plan.Operations, KindReminder, ActionDelete) {
+		add(op, "reminder_delete", map[string]any{"id": op.ID})
+	}
+
+	// --- Deletes last: tasks, sections, labels, then projects child-first.
+	for _, op := range sortedOps(plan.Operations, KindTask, ActionDelete) {
+		add(op, "item_delete", map[string]any{"id": op.ID})
+	}
+	for _, op := range sortedOps(plan.Operations, KindSection, ActionDelete) {
+		add(op, "section_delete", map[string]any{"id": op.ID})
+	}
+	for _, op := range sortedOps(plan.Operations, KindLabel, ActionDelete) {
+		add(op, "label_delete", map[string]any{"id": op.ID})
+	}
+	for _, op := range sortProjectsByDepthDesc(filterOps(plan.Operations, KindProject, ActionDelete), snap) {
+		add(op, "project_delete", map[string]any{"id": op.ID})
+	}
+
+	resp, err := run.runSteps(ctx, clients.Sync, "apply", steps)
+	if err != nil {
+		return res, err
+	}
+	if resp != nil {
+		for _, st := range steps {
+			if st.op.Kind == KindFilter && st.op.Action == ActionCreate && st.cmd.TempID != nil {
+				if id, ok := resp.TempIDMapping[*st.cmd.TempID]; ok {
+					filterNameToID[st.op.Name] = id
+				}
+			}
+		}
+	}
+
+	if needFilterReorder(filterCreates, filterUpdates) && len(cfg.Spec.Filters) > 0 {
+		reorder := Operation{Kind: KindFilter, Action: ActionReorder, Name: "filters"}
+		if blocked := run.failedKinds(KindFilter); blocked != "" {
+			run.skip(reorder, blocked)
+		} else {
+			idOrder, err := filterOrderMapping(cfg, filterNameToID, len(opts.Targets) > 0)
+			if err != nil {
+				return res, err
+			}
+			steps := []syncStep{{op: reorder, cmd: todoistsync.NewCommand("filter_update_orders", map[string]any{"id_order_mapping": idOrder})}}
+			if _, err := run.runSteps(ctx, clients.Sync, "filter_update_orders", steps); err != nil {
+				return res, err
+			}
+			res.Summary.Reorder++
+		}
+	}
+	return res, run.err()
+}
+
+// sortedOps returns the operations of a kind/action ordered by name.
+func sortedOps(ops []Operation, kind Kind, action Action) []Operation {
+	out := filterOps(ops, kind, action)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
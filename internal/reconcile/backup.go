@@ -0,0 +1,237 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// BackupVersion is the on-disk format version written by `htd backup`.
+const BackupVersion = 1
+
+// restoreKeyPrefix keys unmanaged tasks that restore has to recreate, so a second restore finds
+// them instead of creating them again.
+const restoreKeyPrefix = "restore_"
+
+// Backup is a full copy of the account as htd sees it: every project (with hierarchy), section,
+// label, filter and active task, managed or not, with remote IDs.
+type Backup struct {
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"created_at"`
+	Projects  []v1.Project  `json:"projects"`
+	Sections  []v1.Section  `json:"sections"`
+	Labels    []v1.Label    `json:"labels"`
+	Filters   []sync.Filter `json:"filters"`
+	Tasks     []v1.Task     `json:"tasks"`
+}
+
+// NewBackup captures snap.
+func NewBackup(snap *Snapshot) *Backup {
+	return &Backup{
+		Version:   BackupVersion,
+		CreatedAt: time.Now().UTC(),
+		Projects:  append([]v1.Project{}, snap.Projects...),
+		Sections:  append([]v1.Section{}, snap.Sections...),
+		Labels:    append([]v1.Label{}, snap.Labels...),
+		Filters:   append([]sync.Filter{}, snap.Filters...),
+		Tasks:     append([]v1.Task{}, snap.Tasks...),
+	}
+}
+
+// WriteBackup writes b as indented JSON.
+func WriteBackup(path string, b *Backup) error {
+	out, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal backup: %w", err)
+	}
+	out = append(out, '\n')
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		return fmt.Errorf("write backup %q: %w", path, err)
+	}
+	return nil
+}
+
+// ReadBackup loads a backup written by WriteBackup.
+func ReadBackup(path string) (*Backup, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read backup %q: %w", path, err)
+	}
+	var b Backup
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, fmt.Errorf("parse backup %q: %w", path, err)
+	}
+	if b.Version != BackupVersion {
+		return nil, fmt.Errorf("backup %q has unsupported version %d (expected %d)", path, b.Version, BackupVersion)
+	}
+	return &b, nil
+}
+
+// RestoreConfig converts b into a config that BuildPlan can reconcile current towards. Objects
+// that still exist are pinned by ID, so renames and moves since the backup are reverted; missing
+// ones are matched by name (or task key) and otherwise created. Unmanaged tasks that must be
+// recreated get a restore_<old id> key. Subtasks are declared under their parent and tasks keep
+// their section. Prune gates are all on, so deletions still need --prune.
+func (b *Backup) RestoreConfig(current *Snapshot) (*config.TodoistConfig, []string) {
+	var notes []string
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "restore"},
+		Spec: config.Spec{
+			Prune: config.PruneSpec{Projects: true, Sections: true, Labels: true, Filters: true, Tasks: true},
+		},
+	}
+
+	projectNames := map[string]string{}
+	for _, p := range b.Projects {
+		projectNames[p.ID] = p.Name
+	}
+	sectionsByProject := map[string][]v1.Section{}
+	sectionsByID := map[string]v1.Section{}
+	for _, sec := range b.Sections {
+		sectionsByProject[sec.ProjectID] = append(sectionsByProject[sec.ProjectID], sec)
+		sectionsByID[sec.ID] = sec
+	}
+
+	for _, p := range b.Projects {
+		ps := config.ProjectSpec{
+			Name:       p.Name,
+			Color:      strValue(p.Color),
+			IsFavorite: boolValue(p.IsFavorite),
+			ViewStyle:  strValue(p.ViewStyle),
+			Sections:   []config.SectionSpec{},
+		}
+		if _, ok := current.ProjectByID(p.ID); ok {
+			ps.ID = strValue(p.ID)
+		}
+		if p.ParentID != nil {
+			if parent, ok := projectNames[*p.ParentID]; ok {
+				ps.Parent = strValue(parent)
+			}
+		}
+		secs := sectionsByProject[p.ID]
+		sort.SliceStable(secs, func(i, j int) bool { return secs[i].SectionOrder < secs[j].SectionOrder })
+		for _, sec := range secs {
+			ss := config.SectionSpec{Name: sec.Name}
+			if sec.SectionOrder > 0 {
+				ss.Order = intValue(sec.SectionOrder)
+			}
+			if cur, ok := current.SectionByID(sec.ID); ok && cur.ProjectID == sec.ProjectID {
+				ss.ID = strValue(sec.ID)
+			}
+			ps.Sections = append(ps.Sections, ss)
+		}
+		cfg.Spec.Projects = append(cfg.Spec.Projects, ps)
+	}
+
+	for _, l := range b.Labels {
+		ls := config.LabelSpec{Name: l.Name, Color: strValue(l.Color), IsFavorite: boolValue(l.IsFavorite)}
+		if _, ok := current.LabelByID(l.ID); ok {
+			ls.ID = strValue(l.ID)
+		}
+		cfg.Spec.Labels = append(cfg.Spec.Labels, ls)
+	}
+
+	filters := append([]sync.Filter(nil), b.Filters...)
+	sort.SliceStable(filters, func(i, j int) bool { return filters[i].ItemOrder < filters[j].ItemOrder })
+	for _, f := range filters {
+		fs := config.FilterSpec{Name: f.Name, Query: f.Query, Color: strValue(f.Color), IsFavorite: boolValue(f.IsFavorite)}
+		if f.ItemOrder > 0 {
+			fs.Order = intValue(f.ItemOrder)
+		}
+		if _, ok := current.FilterByID(f.ID); ok {
+			fs.ID = strValue(f.ID)
+		}
+		cfg.Spec.Filters = append(cfg.Spec.Filters, fs)
+	}
+
+	var recreated int
+	specs := make([]config.TaskSpec, 0, len(b.Tasks))
+	for _, t := range b.Tasks {
+		key, managed := ManagedTaskKey(t.Description)
+		desc := TaskDescriptionSansManagedKey(t.Description)
+		ts := config.TaskSpec{
+			Key:         key,
+			Content:     t.Content,
+			Description: &desc,
+			Labels:      append([]string{}, t.Labels...),
+			Priority:    intValue(t.Priority),
+		}
+		if name, ok := projectNames[t.ProjectID]; ok {
+			ts.Project = strValue(name)
+		}
+		if t.SectionID != nil {
+			if sec, ok := sectionsByID[*t.SectionID]; ok && sec.ProjectID == t.ProjectID {
+				ts.Section = strValue(sec.Name)
+			}
+		}
+		if t.Due != nil && t.Due.String != "" {
+			ts.Due.String = strValue(t.Due.String)
+		}
+		if _, ok := current.TaskByID(t.ID); ok {
+			ts.ID = strValue(t.ID)
+		} else if !managed {
+			ts.Key = restoreKeyPrefix + t.ID
+			if _, ok := current.TaskByKey(ts.Key); !ok {
+				recreated++
+			}
+		}
+		if t.Priority < 1 {
+			ts.Priority = nil
+		}
+		specs = append(specs, ts)
+	}
+	cfg.Spec.Tasks = nestRestoredTasks(b.Tasks, specs)
+	if recreated > 0 {
+		notes = append(notes, fmt.Sprintf("%d unmanaged tasks are recreated with a %s<old id> key (completed tasks count as missing)", recreated, restoreKeyPrefix))
+	}
+
+	cfg.Normalize()
+	return cfg, notes
+}
+
+// nestRestoredTasks declares each spec (built from the task at the same index) under its parent's
+// spec when the parent is in the backup, in child order, and keeps the others top-level.
+func nestRestoredTasks(tasks []v1.Task, specs []config.TaskSpec) []config.TaskSpec {
+	index := map[string]int{}
+	for i, t := range tasks {
+		index[t.ID] = i
+	}
+	children := map[int][]int{}
+	var roots []int
+	for i, t := range tasks {
+		if t.ParentID != nil {
+			if pi, ok := index[*t.ParentID]; ok {
+				children[pi] = append(children[pi], i)
+				continue
+			}
+		}
+		roots = append(roots, i)
+	}
+	var nest func(i int) config.TaskSpec
+	nest = func(i int) config.TaskSpec {
+		ts := specs[i]
+		kids := children[i]
+		sort.SliceStable(kids, func(a, b int) bool { return tasks[kids[a]].ChildOrder < tasks[kids[b]].ChildOrder })
+		for _, c := range kids {
+			sub := nest(c)
+			sub.Project, sub.Section = nil, nil
+			ts.Subtasks = append(ts.Subtasks, sub)
+		}
+		return ts
+	}
+	out := make([]config.TaskSpec, 0, len(roots))
+	for _, i := range roots {
+		out = append(out, nest(i))
+	}
+	return out
+}
+
+func strValue(s string) *string { return &s }
+func boolValue(b bool) *bool    { return &b }
+func intValue(n int) *int       { return &n }
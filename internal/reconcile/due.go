@@ -0,0 +1,77 @@
+package reconcile
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// dueMatches reports whether a remote due date satisfies a configured due string. Todoist moves the
+// date of a completed recurring task and may rewrite its string ("every day at 8:00am" becomes
+// "Every day at 8am"), so strings are compared by their normalized rule, not verbatim. Synonyms and
+// times are English, so due dates in another language only compare ignoring case and spacing.
+func dueMatches(remote *v1.Due, want string) bool {
+	if want == "" {
+		return remote == nil || remote.String == ""
+	}
+	if remote == nil {
+		return false
+	}
+	if remote.Lang != "" && remote.Lang != "en" {
+		return foldDueString(remote.String) == foldDueString(want)
+	}
+	return normalizeDueString(remote.String) == normalizeDueString(want)
+}
+
+var (
+	dueSynonyms = map[string]string{
+		"daily":    "every day",
+		"weekly":   "every week",
+		"monthly":  "every month",
+		"yearly":   "every year",
+		"annually": "every year",
+	}
+	// Whole words only: "biweekly" is not "bi" + "weekly".
+	dueSynonymWord = regexp.MustCompile(`\b(?:daily|weekly|monthly|yearly|annually)\b`)
+	dueEveryOne    = regexp.MustCompile(`\bevery 1\b`)
+	dueTime12h     = regexp.MustCompile(`\b(\d{1,2})(?::(\d{2}))?\s*(am|pm)\b`)
+	dueTime24h     = regexp.MustCompile(`\b(\d{1,2}):(\d{2})\b`)
+)
+
+// normalizeDueString canonicalizes a due string for comparison: case and whitespace, common
+// synonyms ("daily" -> "every day") and times ("8am", "8:00 am" and "08:00" -> "08:00").
+func normalizeDueString(s string) string {
+	s = foldDueString(s)
+	s = dueSynonymWord.ReplaceAllStringFunc(s, func(m string) string { return dueSynonyms[m] })
+	s = dueEveryOne.ReplaceAllString(s, "every")
+	s = dueTime12h.ReplaceAllStringFunc(s, func(m string) string {
+		parts := dueTime12h.FindStringSubmatch(m)
+		h, _ := strconv.Atoi(parts[1])
+		if h > 12 {
+			return m
+		}
+		h %= 12
+		if parts[3] == "pm" {
+			h += 12
+		}
+		mins := parts[2]
+		if mins == "" {
+			mins = "00"
+		}
+		return fmt.Sprintf("%02d:%s", h, mins)
+	})
+	s = dueTime24h.ReplaceAllStringFunc(s, func(m string) string {
+		parts := dueTime24h.FindStringSubmatch(m)
+		h, _ := strconv.Atoi(parts[1])
+		return fmt.Sprintf("%02d:%s", h, parts[2])
+	})
+	return strings.TrimSpace(s)
+}
+
+// foldDueString lowercases s and collapses its whitespace.
+func foldDueString(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
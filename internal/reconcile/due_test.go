@@ -0,0 +1,50 @@
+package reconcile
+
+import (
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+func TestDueMatches(t *testing.T) {
+	recurring := func(s string) *v1.Due { return &v1.Due{String: s, IsRecurring: true, Date: "2026-10-17"} }
+	for _, tc := range []struct {
+		remote *v1.Due
+		want   string
+		match  bool
+	}{
+		{recurring("Every day at 8am"), "every day at 8:00am", true},
+		{recurring("every  Day at 08:00"), "daily at 8 AM", true},
+		{recurring("every day at 20:00"), "every day at 8pm", true},
+		{recurring("every 1 week"), "weekly", true},
+		{recurring("every 12 days"), "every 1 day", false},
+		{recurring("every 1st"), "every 1st", true},
+		{recurring("every day at 8am"), "every day at 9am", false},
+		{recurring("every monday"), "every tuesday", false},
+		{&v1.Due{String: "jeden Tag um 8 Uhr", IsRecurring: true, Date: "2026-10-17", Lang: "de"}, "Jeden  Tag um 8 Uhr", true},
+		{&v1.Due{String: "jeden Tag um 8 Uhr", IsRecurring: true, Date: "2026-10-17", Lang: "de"}, "jeden Tag um 9 Uhr", false},
+		{&v1.Due{String: "Tomorrow", Date: "2026-10-17"}, "tomorrow", true},
+		{nil, "", true},
+		{nil, "every day", false},
+		{recurring("every day"), "", false},
+	} {
+		if got := dueMatches(tc.remote, tc.want); got != tc.match {
+			t.Errorf("dueMatches(%+v, %q) = %v, want %v", tc.remote, tc.want, got, tc.match)
+		}
+	}
+}
+
+func TestNormalizeDueString(t *testing.T) {
+	for in, want := range map[string]string{
+		"Daily at 8:00 AM":     "every day at 08:00",
+		"every 1 week":         "every week",
+		"biweekly":             "biweekly",
+		"Bimonthly on the 1st": "bimonthly on the 1st",
+		"semiannually":         "semiannually",
+		"weekly, at 9pm":       "every week, at 21:00",
+	} {
+		if got := normalizeDueString(in); got != want {
+			t.Errorf("normalizeDueString(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,935 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/fake"
+	todoisthttp "github.com/erauner/homelab-todoist-declarative/internal/todoist/http"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+func TestEndToEnd_ApplyIsIdempotent(t *testing.T) {
+	srv := fake.New(fake.WithPageSize(2))
+	defer srv.Close()
+	cfg := seedEndToEnd(t, srv)
+
+	clients := newClients(srv)
+	assertApplyIsIdempotent(t, srv, cfg, clients, Apply)
+}
+
+func TestEndToEnd_SyncApplyIsIdempotent(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	cfg := seedEndToEnd(t, srv)
+
+	// A tiny batch size forces temp IDs (e.g. Homelab's parent Personal) to cross requests.
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	clients := Clients{V1: v1.New(h), Sync: sync.New(h, sync.WithMaxCommandsPerSync(2))}
+	assertApplyIsIdempotent(t, srv, cfg, clients, ApplySync)
+}
+
+// seedEndToEnd fills srv with drifted remote state and returns the config to converge it to.
+func seedEndToEnd(t *testing.T, srv *fake.Server) *config.TodoistConfig {
+	t.Helper()
+	work := srv.AddProject(v1.Project{Name: "Work", Color: "blue"})
+	srv.AddProject(v1.Project{Name: "Homelab"})
+	srv.AddProject(v1.Project{Name: "Stale"})
+	srv.AddSection(v1.Section{ProjectID: work.ID, Name: "Doing", SectionOrder: 1})
+	srv.AddSection(v1.Section{ProjectID: work.ID, Name: "Old", SectionOrder: 2})
+	srv.AddLabel(v1.Label{Name: "waiting"})
+	srv.AddLabel(v1.Label{Name: "stale"})
+	srv.AddFilter(sync.Filter{Name: "Important", Query: "p1", ItemOrder: 1})
+	srv.AddFilter(sync.Filter{Name: "Stale", Query: "p4", ItemOrder: 2})
+	review := srv.AddTask(v1.Task{Content: "Old review", Description: "HTD_KEY:review", ProjectID: work.ID, Due: &v1.Due{String: "every friday", IsRecurring: true}})
+	srv.AddReminder(sync.Reminder{ItemID: review.ID, Type: "relative", MinuteOffset: 10})
+	srv.AddReminder(sync.Reminder{ItemID: review.ID, Type: "relative", MinuteOffset: 60})
+	srv.AddReminder(sync.Reminder{ItemID: review.ID, Type: "absolute", Due: &sync.ReminderDue{Date: "2026-01-01T09:00:00"}})
+	srv.AddTask(v1.Task{Content: "Buy milk"})
+
+	tmpl := "recurring_template"
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "e2e"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{
+				{Name: "Personal", Color: strPtr("green")},
+				{Name: "Homelab", Parent: strPtr("Personal")},
+				{Name: "Work", Color: strPtr("red"), Sections: []config.SectionSpec{{Name: "Backlog"}, {Name: "Doing"}}},
+			},
+			Labels: []config.LabelSpec{
+				{Name: "waiting", IsFavorite: boolPtr(true)},
+				{Name: "next"},
+			},
+			Filters: []config.FilterSpec{
+				{Name: "Important", Query: "p1 & today"},
+				{Name: "Waiting", Query: "@waiting"},
+			},
+			Tasks: []config.TaskSpec{
+				{Key: "review", Type: &tmpl, Content: "Weekly review", Project: strPtr("Work"), Labels: []string{"next"}, Due: config.TaskDueSpec{String: strPtr("every friday")},
+					Reminders: []config.TaskReminderSpec{{MinutesBefore: intPtr(60)}, {MinutesBefore: intPtr(30)}}},
+				{Key: "patch", Type: &tmpl, Content: "Patch servers", Project: strPtr("Homelab"), Due: config.TaskDueSpec{String: strPtr("every month")},
+					Reminders: []config.TaskReminderSpec{{MinutesBefore: intPtr(0)}, {At: strPtr("2026-11-01T09:00:00+01:00")}}},
+			},
+			Prune: config.PruneSpec{Projects: true, Sections: true, Labels: true, Filters: true, Tasks: true},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	return cfg
+}
+
+type applyFunc func(context.Context, *config.TodoistConfig, *Snapshot, *Plan, Clients, Options) (*ApplyResult, error)
+
+// newClients returns REST and /sync clients of srv.
+func newClients(srv *fake.Server) Clients {
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	return Clients{V1: v1.New(h), Sync: sync.New(h)}
+}
+
+// fetchSnapshot reads a REST snapshot through clients.
+func fetchSnapshot(t *testing.T, cfg *config.TodoistConfig, clients Clients) *Snapshot {
+	t.Helper()
+	snap, err := FetchSnapshot(context.Background(), clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	if err := FetchCompletedTasks(context.Background(), clients.V1, cfg, snap); err != nil {
+		t.Fatalf("FetchCompletedTasks: %v", err)
+	}
+	return snap
+}
+
+// planAndApply plans cfg against a fresh snapshot, checks the plan's summary against want and
+// applies the plan. It returns the snapshot and plan with the result, for a journal.
+func planAndApply(t *testing.T, cfg *config.TodoistConfig, clients Clients, apply applyFunc, opts Options, want Summary) (*Snapshot, *Plan, *ApplyResult) {
+	t.Helper()
+	snap := fetchSnapshot(t, cfg, clients)
+	plan, err := BuildPlan(cfg, snap, opts)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary != want {
+		t.Fatalf("unexpected summary %+v, want %+v: %+v", plan.Summary, want, plan.Operations)
+	}
+	res, err := apply(context.Background(), cfg, snap, plan, clients, opts)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	return snap, plan, res
+}
+
+// assertCleanPlan fails unless cfg plans no changes against snap, and returns the plan.
+func assertCleanPlan(t *testing.T, cfg *config.TodoistConfig, snap *Snapshot, opts Options) *Plan {
+	t.Helper()
+	plan, err := BuildPlan(cfg, snap, opts)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.TotalChanges() != 0 {
+		t.Fatalf("expected a clean plan, got %+v", plan.Operations)
+	}
+	return plan
+}
+
+func assertApplyIsIdempotent(t *testing.T, srv *fake.Server, cfg *config.TodoistConfig, clients Clients, apply applyFunc) {
+	t.Helper()
+	ctx := context.Background()
+	opts := Options{Prune: true}
+
+	snap, err := FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	plan, err := BuildPlan(cfg, snap, opts)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.TotalChanges() == 0 {
+		t.Fatalf("expected changes on first plan")
+	}
+	if _, err := apply(ctx, cfg, snap, plan, clients, opts); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	snap, err = FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot after apply: %v", err)
+	}
+	plan, err = BuildPlan(cfg, snap, opts)
+	if err != nil {
+		t.Fatalf("BuildPlan after apply: %v", err)
+	}
+	if n := plan.Summary.TotalChanges(); n != 0 {
+		t.Fatalf("expected no changes after apply, got %d: %#v", n, plan.Operations)
+	}
+
+	var names []string
+	for _, p := range srv.Projects() {
+		names = append(names, p.Name)
+	}
+	if len(names) != 4 {
+		t.Fatalf("expected Inbox + 3 managed projects after prune, got %v", names)
+	}
+
+	// Reminders: 60 kept, 10 updated to 30, the absolute one deleted; two added to the new task.
+	var reminders []string
+	for _, r := range srv.Reminders() {
+		if r.Type == "relative" {
+			reminders = append(reminders, fmt.Sprintf("%d", r.MinuteOffset))
+		} else {
+			reminders = append(reminders, r.Due.Date)
+		}
+	}
+	sort.Strings(reminders)
+	if got := strings.Join(reminders, ","); got != "0,2026-11-01T08:00:00Z,30,60" {
+		t.Fatalf("unexpected reminders after apply: %s", got)
+	}
+
+	// Unmanaged tasks survive task pruning.
+	var found bool
+	for _, task := range srv.Tasks() {
+		if task.Content == "Buy milk" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected unmanaged task to be kept, got %#v", srv.Tasks())
+	}
+}
+
+func TestEndToEnd_IncrementalSnapshotMatchesREST(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	work := srv.AddProject(v1.Project{Name: "Work"})
+	srv.AddSection(v1.Section{ProjectID: work.ID, Name: "Doing", SectionOrder: 1})
+	stale := srv.AddLabel(v1.Label{Name: "stale"})
+	srv.AddFilter(sync.Filter{Name: "Important", Query: "p1", ItemOrder: 1})
+	srv.AddTask(v1.Task{Content: "Review", Description: "HTD_KEY:review", ProjectID: work.ID})
+
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	v1c, syncc := v1.New(h), sync.New(h)
+	ctx := context.Background()
+	cachePath := filepath.Join(t.TempDir(), "sync.json")
+
+	assertSame := func(step string) {
+		t.Helper()
+		cache, err := sync.LoadCache(cachePath, "acct")
+		if err != nil {
+			t.Fatalf("%s: LoadCache: %v", step, err)
+		}
+		cached, err := FetchSnapshotCached(ctx, syncc, cache)
+		if err != nil {
+			t.Fatalf("%s: FetchSnapshotCached: %v", step, err)
+		}
+		if err := cache.Save(cachePath); err != nil {
+			t.Fatalf("%s: Save: %v", step, err)
+		}
+		rest, err := FetchSnapshot(ctx, v1c, syncc)
+		if err != nil {
+			t.Fatalf("%s: FetchSnapshot: %v", step, err)
+		}
+		a, _ := cached.Fingerprint()
+		b, _ := rest.Fingerprint()
+		if a != b {
+			t.Fatalf("%s: cached snapshot differs from REST\ncached: %#v\nrest:   %#v", step, cached, rest)
+		}
+	}
+
+	assertSame("initial full sync")
+
+	// Mutate through the API and check the delta is applied on top of the persisted cache.
+	if _, err := v1c.CreateProject(ctx, v1.CreateProjectRequest{Name: "Homelab"}); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	if err := v1c.DeleteLabel(ctx, stale.ID); err != nil {
+		t.Fatalf("DeleteLabel: %v", err)
+	}
+	name := "Work Stuff"
+	if _, err := v1c.UpdateProject(ctx, work.ID, v1.UpdateProjectRequest{Name: &name}); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	assertSame("incremental sync")
+
+	cache, err := sync.LoadCache(cachePath, "acct")
+	if err != nil {
+		t.Fatalf("LoadCache: %v", err)
+	}
+	if cache.SyncToken == "*" || len(cache.Projects) != 3 || len(cache.Labels) != 0 {
+		t.Fatalf("unexpected cache after delta: token=%q projects=%d labels=%d", cache.SyncToken, len(cache.Projects), len(cache.Labels))
+	}
+	if other, _ := sync.LoadCache(cachePath, "other-account"); other.SyncToken != "*" {
+		t.Fatalf("expected a cache for another account to be discarded")
+	}
+}
+
+func TestEndToEnd_SyncSnapshotIsOneRequest(t *testing.T) {
+	srv := fake.New(fake.WithPageSize(1))
+	defer srv.Close()
+	work := srv.AddProject(v1.Project{Name: "Work"})
+	srv.AddProject(v1.Project{Name: "Homelab", ParentID: &work.ID})
+	srv.AddSection(v1.Section{ProjectID: work.ID, Name: "Doing", SectionOrder: 1})
+	srv.AddLabel(v1.Label{Name: "waiting", IsFavorite: true})
+	srv.AddFilter(sync.Filter{Name: "Important", Query: "p1", ItemOrder: 1})
+	srv.AddTask(v1.Task{Content: "Review", Description: "HTD_KEY:review", ProjectID: work.ID, Labels: []string{"waiting"}, Due: &v1.Due{String: "every day", IsRecurring: true}})
+
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	ctx := context.Background()
+
+	before := srv.Requests()
+	viaSync, err := FetchSnapshotSync(ctx, sync.New(h))
+	if err != nil {
+		t.Fatalf("FetchSnapshotSync: %v", err)
+	}
+	if n := srv.Requests() - before; n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+
+	viaREST, err := FetchSnapshot(ctx, v1.New(h), sync.New(h))
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	a, _ := viaSync.Fingerprint()
+	b, _ := viaREST.Fingerprint()
+	if a != b {
+		t.Fatalf("sync snapshot differs from REST\nsync: %#v\nrest: %#v", viaSync, viaREST)
+	}
+}
+
+func TestEndToEnd_ContinueOnErrorSkipsDependents(t *testing.T) {
+	tmpl := "recurring_template"
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "e2e"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{
+				{Name: "Personal"},
+				{Name: "Homelab", Parent: strPtr("Personal")},
+			},
+			Labels: []config.LabelSpec{{Name: "next"}},
+			Tasks: []config.TaskSpec{
+				{Key: "patch", Type: &tmpl, Content: "Patch servers", Project: strPtr("Homelab"), Due: config.TaskDueSpec{String: strPtr("every month")}},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	statuses := func(res *ApplyResult) map[string]string {
+		out := map[string]string{}
+		for _, r := range res.Applied {
+			out[string(r.Kind)+"/"+r.Name] = r.Status
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name  string
+		apply applyFunc
+		// Sync applies send dependents anyway; Todoist fails them instead of htd skipping them.
+		failed, skipped int
+	}{
+		{name: "rest", apply: Apply, failed: 1, skipped: 2},
+		{name: "sync", apply: ApplySync, failed: 3, skipped: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := fake.New()
+			defer srv.Close()
+			srv.Reject("Personal")
+
+			clients := newClients(srv)
+			ctx := context.Background()
+			snap, err := FetchSnapshot(ctx, clients.V1, clients.Sync)
+			if err != nil {
+				t.Fatalf("FetchSnapshot: %v", err)
+			}
+			plan, err := BuildPlan(cfg, snap, Options{})
+			if err != nil {
+				t.Fatalf("BuildPlan: %v", err)
+			}
+
+			res, err := tc.apply(ctx, cfg, snap, plan, clients, Options{ContinueOnError: true})
+			if err == nil || res == nil {
+				t.Fatalf("expected partial result and error, got res=%v err=%v", res, err)
+			}
+			if res.Failed != tc.failed || res.Skipped != tc.skipped || len(res.Applied) != 4 {
+				t.Fatalf("unexpected counts failed=%d skipped=%d applied=%#v", res.Failed, res.Skipped, res.Applied)
+			}
+			got := statuses(res)
+			if got["label/next"] != "ok" || got["project/Personal"] == "ok" {
+				t.Fatalf("unexpected statuses: %#v", got)
+			}
+			if tc.skipped > 0 && got["task/Patch servers"] != `skipped: project create "Personal" failed` {
+				t.Fatalf("expected task to be skipped because of Personal, got %#v", got)
+			}
+			if len(srv.Labels()) != 1 {
+				t.Fatalf("expected label to be created despite the failure, got %#v", srv.Labels())
+			}
+		})
+	}
+
+	t.Run("stop on first error", func(t *testing.T) {
+		srv := fake.New()
+		defer srv.Close()
+		srv.Reject("Personal")
+		clients := newClients(srv)
+		ctx := context.Background()
+		snap, err := FetchSnapshot(ctx, clients.V1, clients.Sync)
+		if err != nil {
+			t.Fatalf("FetchSnapshot: %v", err)
+		}
+		plan, err := BuildPlan(cfg, snap, Options{})
+		if err != nil {
+			t.Fatalf("BuildPlan: %v", err)
+		}
+		res, err := Apply(ctx, cfg, snap, plan, clients, Options{})
+		if err == nil || res == nil || len(res.Applied) != 1 || res.Failed != 1 {
+			t.Fatalf("expected to stop after the first failure, got res=%#v err=%v", res, err)
+		}
+		if len(srv.Labels()) != 0 {
+			t.Fatalf("expected nothing applied after the failure, got %#v", srv.Labels())
+		}
+	})
+}
+
+func TestEndToEnd_UndoRestoresPreviousState(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	work := srv.AddProject(v1.Project{Name: "Work", Color: "blue"})
+	srv.AddProject(v1.Project{Name: "Homelab"})
+	srv.AddSection(v1.Section{ProjectID: work.ID, Name: "Doing", SectionOrder: 1})
+	srv.AddLabel(v1.Label{Name: "waiting"})
+	srv.AddLabel(v1.Label{Name: "stale", Color: "red"})
+	srv.AddFilter(sync.Filter{Name: "Important", Query: "p1", ItemOrder: 1})
+	srv.AddFilter(sync.Filter{Name: "Stale", Query: "p4", ItemOrder: 2})
+	srv.AddTask(v1.Task{Content: "Old review", Description: "HTD_KEY:review", ProjectID: work.ID, Priority: 1})
+
+	tmpl := "recurring_template"
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "e2e"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{
+				{Name: "Personal"},
+				{Name: "Homelab", Parent: strPtr("Personal")},
+				{Name: "Work", Color: strPtr("red"), Sections: []config.SectionSpec{{Name: "Backlog"}, {Name: "Doing"}}},
+			},
+			Labels:  []config.LabelSpec{{Name: "waiting", IsFavorite: boolPtr(true)}, {Name: "next"}},
+			Filters: []config.FilterSpec{{Name: "Waiting", Query: "@waiting"}, {Name: "Important", Query: "p1 & today"}},
+			Tasks: []config.TaskSpec{
+				{Key: "review", Type: &tmpl, Content: "Weekly review", Project: strPtr("Work"), Labels: []string{"next"}, Due: config.TaskDueSpec{String: strPtr("every friday")}},
+			},
+			Prune: config.PruneSpec{Labels: true, Filters: true},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	before := describeRemote(srv)
+
+	clients := newClients(srv)
+	ctx := context.Background()
+	snap, err := FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	plan, err := BuildPlan(cfg, snap, Options{Prune: true})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	res, err := Apply(ctx, cfg, snap, plan, clients, Options{Prune: true})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	dir := t.TempDir()
+	path, err := WriteJournal(dir, NewJournal(cfg.Metadata.Name, snap, plan, res))
+	if err != nil {
+		t.Fatalf("WriteJournal: %v", err)
+	}
+	if latest, err := LatestJournal(dir, cfg.Metadata.Name); err != nil || latest != path {
+		t.Fatalf("LatestJournal = %q, %v; want %q", latest, err, path)
+	}
+	j, err := ReadJournal(path)
+	if err != nil {
+		t.Fatalf("ReadJournal: %v", err)
+	}
+	if describeRemote(srv) == before {
+		t.Fatalf("expected apply to change remote state")
+	}
+
+	snap, err = FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot after apply: %v", err)
+	}
+	undo, err := BuildUndoPlan(j, snap)
+	if err != nil {
+		t.Fatalf("BuildUndoPlan: %v", err)
+	}
+	if _, err := Apply(ctx, &config.TodoistConfig{}, snap, undo, clients, Options{}); err != nil {
+		t.Fatalf("Apply undo: %v (plan %#v)", err, undo.Operations)
+	}
+	if got := describeRemote(srv); got != before {
+		t.Fatalf("undo did not restore the previous state\ngot:\n%s\nwant:\n%s", got, before)
+	}
+}
+
+func TestEndToEnd_RestoreFromBackup(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	personal := srv.AddProject(v1.Project{Name: "Personal", Color: "green"})
+	homelab := srv.AddProject(v1.Project{Name: "Homelab", ParentID: &personal.ID})
+	doing := srv.AddSection(v1.Section{ProjectID: homelab.ID, Name: "Doing", SectionOrder: 1})
+	waiting := srv.AddLabel(v1.Label{Name: "waiting", IsFavorite: true})
+	important := srv.AddFilter(sync.Filter{Name: "Important", Query: "p1", ItemOrder: 1})
+	patch := srv.AddTask(v1.Task{Content: "Patch servers", Description: "HTD_KEY:patch", ProjectID: homelab.ID, SectionID: &doing.ID, Labels: []string{"waiting"}, Priority: 2, Due: &v1.Due{String: "every month", IsRecurring: true}})
+	milk := srv.AddTask(v1.Task{Content: "Buy milk", ProjectID: personal.ID, Priority: 1})
+	srv.AddTask(v1.Task{Content: "Oat milk", ProjectID: personal.ID, ParentID: &milk.ID, ChildOrder: 1})
+	srv.AddTask(v1.Task{Content: "Check the date", ProjectID: personal.ID, ParentID: &milk.ID, ChildOrder: 2})
+
+	clients := newClients(srv)
+	ctx := context.Background()
+	snap, err := FetchSnapshot(ctx, clients.V1, clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshot: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "backup.json")
+	if err := WriteBackup(path, NewBackup(snap)); err != nil {
+		t.Fatalf("WriteBackup: %v", err)
+	}
+	before := describeRemote(srv)
+
+	// Drift: rename and re-parent a project, move a task out of its section, delete a label and an
+	// unmanaged task with its subtasks, edit a filter.
+	name := "Lab"
+	if _, err := clients.V1.UpdateProject(ctx, homelab.ID, v1.UpdateProjectRequest{Name: &name}); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	cmds := []sync.Command{
+		sync.NewCommand("project_move", map[string]any{"id": homelab.ID, "parent_id": nil}),
+		sync.NewCommand("filter_update", map[string]any{"id": important.ID, "query": "p2"}),
+		sync.NewCommand("item_move", map[string]any{"id": patch.ID, "project_id": homelab.ID}),
+	}
+	if _, err := clients.Sync.RunCommands(ctx, cmds); err != nil {
+		t.Fatalf("RunCommands: %v", err)
+	}
+	if err := clients.V1.DeleteLabel(ctx, waiting.ID); err != nil {
+		t.Fatalf("DeleteLabel: %v", err)
+	}
+	if err := clients.V1.DeleteTask(ctx, milk.ID); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+
+	b, err := ReadBackup(path)
+	if err != nil {
+		t.Fatalf("ReadBackup: %v", err)
+	}
+	restore := func() *Plan {
+		t.Helper()
+		snap, err := FetchSnapshot(ctx, clients.V1, clients.Sync)
+		if err != nil {
+			t.Fatalf("FetchSnapshot: %v", err)
+		}
+		cfg, _ := b.RestoreConfig(snap)
+		plan, err := BuildPlan(cfg, snap, Options{})
+		if err != nil {
+			t.Fatalf("BuildPlan: %v", err)
+		}
+		if _, err := Apply(ctx, cfg, snap, plan, clients, Options{}); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		return plan
+	}
+
+	if plan := restore(); plan.Summary.TotalChanges() == 0 {
+		t.Fatalf("expected restore to plan changes")
+	}
+	if got := describeRemote(srv); got != before {
+		t.Fatalf("restore did not match the backup\ngot:\n%s\nwant:\n%s", got, before)
+	}
+	// The recreated unmanaged task carries a restore key so the next restore finds it.
+	for _, task := range srv.Tasks() {
+		if task.Content == "Buy milk" && task.Description != "HTD_KEY:restore_"+milk.ID {
+			t.Fatalf("expected recreated task to be keyed by its old ID, got %q", task.Description)
+		}
+	}
+	if plan := restore(); plan.Summary.TotalChanges() != 0 {
+		t.Fatalf("expected a second restore to be a no-op, got %#v", plan.Operations)
+	}
+}
+
+// describeRemote captures remote state by name, since recreated objects get new IDs.
+func describeRemote(srv *fake.Server) string {
+	names := map[string]string{}
+	for _, p := range srv.Projects() {
+		names[p.ID] = p.Name
+	}
+	var out []string
+	for _, p := range srv.Projects() {
+		parent := ""
+		if p.ParentID != nil {
+			parent = names[*p.ParentID]
+		}
+		out = append(out, fmt.Sprintf("project %s color=%s parent=%s", p.Name, p.Color, parent))
+	}
+	sections := map[string]string{}
+	for _, sec := range srv.Sections() {
+		sections[sec.ID] = sec.Name
+		out = append(out, fmt.Sprintf("section %s/%s order=%d", names[sec.ProjectID], sec.Name, sec.SectionOrder))
+	}
+	for _, l := range srv.Labels() {
+		out = append(out, fmt.Sprintf("label %s color=%s fav=%t", l.Name, l.Color, l.IsFavorite))
+	}
+	for _, f := range srv.Filters() {
+		out = append(out, fmt.Sprintf("filter %s query=%s order=%d", f.Name, f.Query, f.ItemOrder))
+	}
+	contents := map[string]string{}
+	for _, task := range srv.Tasks() {
+		contents[task.ID] = task.Content
+	}
+	for _, task := range srv.Tasks() {
+		parent := ""
+		if task.ParentID != nil {
+			parent = contents[*task.ParentID]
+		}
+		section := ""
+		if task.SectionID != nil {
+			section = sections[*task.SectionID]
+		}
+		out = append(out, fmt.Sprintf("task %s project=%s section=%s parent=%s order=%d labels=%v due=%v", task.Content, names[task.ProjectID], section, parent, task.ChildOrder, task.Labels, task.Due))
+	}
+	sort.Strings(out)
+	return strings.Join(out, "\n")
+}
+
+func TestEndToEnd_Subtasks(t *testing.T) {
+	for name, apply := range map[string]applyFunc{"rest": Apply, "sync": ApplySync} {
+		t.Run(name, func(t *testing.T) {
+			srv := fake.New()
+			defer srv.Close()
+			work := srv.AddProject(v1.Project{Name: "Work"})
+			review := srv.AddTask(v1.Task{Content: "Weekly review", Description: "HTD_KEY:review", ProjectID: work.ID, Due: &v1.Due{String: "every friday", IsRecurring: true}})
+			srv.AddTask(v1.Task{Content: "Old step", Description: "HTD_KEY:review-old", ParentID: &review.ID, ChildOrder: 1})
+			srv.AddTask(v1.Task{Content: "Inbox zero", Description: "HTD_KEY:review-inbox", ParentID: &review.ID, ChildOrder: 2})
+			srv.AddTask(v1.Task{Content: "Hand-added", ParentID: &review.ID, ChildOrder: 3})
+			srv.AddTask(v1.Task{Content: "Stray", Description: "HTD_KEY:review-stray", ProjectID: work.ID})
+			gone := srv.AddTask(v1.Task{Content: "Gone", Description: "HTD_KEY:gone", ProjectID: work.ID})
+			srv.AddTask(v1.Task{Content: "Gone step", Description: "HTD_KEY:gone-step", ParentID: &gone.ID})
+
+			tmpl := "recurring_template"
+			cfg := &config.TodoistConfig{
+				Metadata: config.Metadata{Name: "e2e"},
+				Spec: config.Spec{
+					Projects: []config.ProjectSpec{{Name: "Work"}},
+					Tasks: []config.TaskSpec{
+						{Key: "review", Type: &tmpl, Content: "Weekly review", Project: strPtr("Work"), Due: config.TaskDueSpec{String: strPtr("every friday")}, Subtasks: []config.TaskSpec{
+							{Key: "review-inbox", Content: "Inbox zero"},
+							{Key: "review-stray", Content: "Stray"},
+							{Key: "review-plan", Content: "Plan week", Subtasks: []config.TaskSpec{{Key: "review-plan-cal", Content: "Check calendar"}}},
+						}},
+						{Key: "daily", Type: &tmpl, Content: "Daily review", Project: strPtr("Work"), Due: config.TaskDueSpec{String: strPtr("every day")}, Subtasks: []config.TaskSpec{
+							{Key: "daily-journal", Content: "Journal", Labels: []string{}},
+						}},
+					},
+					Prune: config.PruneSpec{Tasks: true},
+				},
+			}
+			cfg.Normalize()
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			clients := newClients(srv)
+			opts := Options{Prune: true}
+			// Old step and Gone are deleted (Gone step with its parent); Stray moves under the review.
+			snap, plan, res := planAndApply(t, cfg, clients, apply, opts, Summary{Create: 4, Move: 1, Reorder: 2, Delete: 2})
+
+			byContent := map[string]v1.Task{}
+			for _, task := range srv.Tasks() {
+				byContent[task.Content] = task
+			}
+			parentOf := func(content string) string {
+				task, ok := byContent[content]
+				if !ok {
+					return "<missing>"
+				}
+				if task.ParentID == nil {
+					return ""
+				}
+				for _, p := range srv.Tasks() {
+					if p.ID == *task.ParentID {
+						return p.Content
+					}
+				}
+				return "<unknown>"
+			}
+			for child, parent := range map[string]string{
+				"Inbox zero": "Weekly review", "Stray": "Weekly review", "Plan week": "Weekly review",
+				"Check calendar": "Plan week", "Journal": "Daily review", "Hand-added": "Weekly review",
+				"Old step": "<missing>", "Gone": "<missing>", "Gone step": "<missing>",
+			} {
+				if got := parentOf(child); got != parent {
+					t.Errorf("parent of %q = %q, want %q", child, got, parent)
+				}
+			}
+			if o := byContent["Plan week"].ChildOrder; o != 3 || byContent["Inbox zero"].ChildOrder != 1 || byContent["Stray"].ChildOrder != 2 {
+				t.Errorf("unexpected child orders: inbox=%d stray=%d plan=%d", byContent["Inbox zero"].ChildOrder, byContent["Stray"].ChildOrder, o)
+			}
+			if byContent["Journal"].ProjectID != work.ID {
+				t.Errorf("expected the subtask in its parent's project")
+			}
+
+			after := fetchSnapshot(t, cfg, clients)
+			assertCleanPlan(t, cfg, after, opts)
+
+			// Undo deletes the created tasks (subtasks with their parents) and moves Stray back out.
+			undo, err := BuildUndoPlan(NewJournal(cfg.Metadata.Name, snap, plan, res), after)
+			if err != nil {
+				t.Fatalf("BuildUndoPlan: %v", err)
+			}
+			if _, err := apply(context.Background(), cfg, after, undo, clients, Options{}); err != nil {
+				t.Fatalf("apply undo: %v", err)
+			}
+			byContent = map[string]v1.Task{}
+			for _, task := range srv.Tasks() {
+				byContent[task.Content] = task
+			}
+			for child, parent := range map[string]string{"Stray": "", "Plan week": "<missing>", "Check calendar": "<missing>", "Daily review": "<missing>"} {
+				if got := parentOf(child); got != parent {
+					t.Errorf("after undo: parent of %q = %q, want %q", child, got, parent)
+				}
+			}
+		})
+	}
+}
+
+func TestEndToEnd_TaskSections(t *testing.T) {
+	for name, apply := range map[string]applyFunc{"rest": Apply, "sync": ApplySync} {
+		t.Run(name, func(t *testing.T) {
+			srv := fake.New()
+			defer srv.Close()
+			work := srv.AddProject(v1.Project{Name: "Work"})
+			later := srv.AddSection(v1.Section{Name: "Later", ProjectID: work.ID, SectionOrder: 1})
+			triage := srv.AddTask(v1.Task{Content: "Triage", Description: "HTD_KEY:triage", ProjectID: work.ID, SectionID: &later.ID})
+			srv.AddTask(v1.Task{Content: "Read inbox", ParentID: &triage.ID})
+			srv.AddTask(v1.Task{Content: "Plan", Description: "HTD_KEY:plan", ProjectID: work.ID})
+			srv.AddTask(v1.Task{Content: "Loose", Description: "HTD_KEY:loose", ProjectID: work.ID, SectionID: &later.ID})
+
+			cfg := &config.TodoistConfig{
+				Metadata: config.Metadata{Name: "e2e"},
+				Spec: config.Spec{
+					Projects: []config.ProjectSpec{{Name: "Work", Sections: []config.SectionSpec{{Name: "Later"}, {Name: "Now"}}}},
+					Tasks: []config.TaskSpec{
+						{Key: "triage", Content: "Triage", Project: strPtr("Work"), Section: strPtr("Now")},
+						{Key: "plan", Content: "Plan", Project: strPtr("Work"), Section: strPtr("Later")},
+						{Key: "ship", Content: "Ship", Project: strPtr("Work"), Section: strPtr("Now")},
+						{Key: "loose", Content: "Loose", Project: strPtr("Work")},
+					},
+				},
+			}
+			cfg.Normalize()
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			clients := newClients(srv)
+			// The Now section and Ship are created; Triage moves to Now (a section created in this
+			// apply) and Plan into Later. Loose declares no section and stays put.
+			snap, plan, res := planAndApply(t, cfg, clients, apply, Options{}, Summary{Create: 2, Move: 2})
+
+			sectionOf := func(content string) string {
+				for _, task := range srv.Tasks() {
+					if task.Content != content {
+						continue
+					}
+					if task.SectionID == nil {
+						return ""
+					}
+					for _, sec := range srv.Sections() {
+						if sec.ID == *task.SectionID {
+							return sec.Name
+						}
+					}
+					return "<unknown>"
+				}
+				return "<missing>"
+			}
+			for content, section := range map[string]string{"Triage": "Now", "Read inbox": "Now", "Plan": "Later", "Ship": "Now", "Loose": "Later"} {
+				if got := sectionOf(content); got != section {
+					t.Errorf("section of %q = %q, want %q", content, got, section)
+				}
+			}
+
+			after := fetchSnapshot(t, cfg, clients)
+			assertCleanPlan(t, cfg, after, Options{})
+
+			// Undo moves the tasks back before deleting Ship and the Now section.
+			undo, err := BuildUndoPlan(NewJournal(cfg.Metadata.Name, snap, plan, res), after)
+			if err != nil {
+				t.Fatalf("BuildUndoPlan: %v", err)
+			}
+			if _, err := apply(context.Background(), cfg, after, undo, clients, Options{}); err != nil {
+				t.Fatalf("apply undo: %v", err)
+			}
+			for content, section := range map[string]string{"Triage": "Later", "Read inbox": "Later", "Plan": "", "Ship": "<missing>", "Loose": "Later"} {
+				if got := sectionOf(content); got != section {
+					t.Errorf("after undo: section of %q = %q, want %q", content, got, section)
+				}
+			}
+		})
+	}
+}
+
+func TestEndToEnd_OneShotTasks(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	srv.AddProject(v1.Project{Name: "Homelab"})
+
+	oneShot := "one_shot"
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "e2e"},
+		Spec: config.Spec{
+			Tasks: []config.TaskSpec{
+				{Key: "certs", Type: &oneShot, Content: "Rotate homelab certs", Project: strPtr("Homelab"), Subtasks: []config.TaskSpec{
+					{Key: "certs-wildcard", Content: "Renew wildcard cert"},
+				}},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	clients := newClients(srv)
+	st := state.New("e2e")
+	opts := Options{State: st}
+	// The task and its subtask are created.
+	planAndApply(t, cfg, clients, Apply, opts, Summary{Create: 2})
+	after := fetchSnapshot(t, cfg, clients)
+	if err := RecordState(st, cfg, after); err != nil {
+		t.Fatalf("RecordState: %v", err)
+	}
+	certs, ok := after.TaskByKey("certs")
+	if !ok {
+		t.Fatalf("expected the one-shot task to be created")
+	}
+
+	// Completing it (with its subtask) must not lead to a duplicate.
+	srv.CompleteTask(certs.ID)
+	after = fetchSnapshot(t, cfg, clients)
+	if plan := assertCleanPlan(t, cfg, after, opts); !strings.Contains(strings.Join(plan.Notes, "\n"), `one-shot task "Rotate homelab certs" was completed`) {
+		t.Fatalf("expected a note, got %v", plan.Notes)
+	}
+
+	// Once it falls out of the completed tasks Todoist lists, the state entry still stops it.
+	after.CompletedTasks = nil
+	if err := after.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	if plan := assertCleanPlan(t, cfg, after, opts); !strings.Contains(strings.Join(plan.Notes, "\n"), `one-shot task "Rotate homelab certs" was created before`) {
+		t.Fatalf("expected a note, got %v", plan.Notes)
+	}
+
+	// Forgetting the state entry as well creates it again.
+	st.Remove(string(KindTask), "certs")
+	plan, err := BuildPlan(cfg, after, opts)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.Create != 2 {
+		t.Fatalf("expected the task to be created again, got %+v", plan.Operations)
+	}
+}
+
+func TestEndToEnd_CompletedTasksOnlyForOneShot(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "e2e"},
+		Spec: config.Spec{
+			Tasks: []config.TaskSpec{{Key: "inbox", Content: "Inbox zero"}},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	clients := newClients(srv)
+	planAndApply(t, cfg, clients, Apply, Options{}, Summary{Create: 1})
+	assertCleanPlan(t, cfg, fetchSnapshot(t, cfg, clients), Options{})
+	if n := srv.CompletedReads(); n != 0 {
+		t.Fatalf("expected no completed task listings without one_shot tasks, got %d", n)
+	}
+
+	oneShot := "one_shot"
+	cfg.Spec.Tasks[0].Type = &oneShot
+	fetchSnapshot(t, cfg, clients)
+	if n := srv.CompletedReads(); n != 1 {
+		t.Fatalf("expected one completed task listing for a one_shot task, got %d", n)
+	}
+}
+
+func TestEndToEnd_CompletedRecurringTask(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+
+	tmpl := "recurring_template"
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "e2e"},
+		Spec: config.Spec{
+			Tasks: []config.TaskSpec{
+				{Key: "review", Type: &tmpl, Content: "Morning review", Due: config.TaskDueSpec{String: strPtr("every day at 8:00am")}},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	clients := newClients(srv)
+	planAndApply(t, cfg, clients, Apply, Options{}, Summary{Create: 1})
+
+	// Completing moves the date and rewrites the string; the rule is unchanged, so nothing to do.
+	created := srv.Tasks()[0]
+	srv.CompleteTask(created.ID)
+	completed := srv.Tasks()[0]
+	if completed.Due.String == created.Due.String || completed.Due.Date == created.Due.Date {
+		t.Fatalf("expected the fake to rewrite the due date, got %+v", completed.Due)
+	}
+	syncSnap, err := FetchSnapshotSync(context.Background(), clients.Sync)
+	if err != nil {
+		t.Fatalf("FetchSnapshotSync: %v", err)
+	}
+	for mode, after := range map[string]*Snapshot{"rest": fetchSnapshot(t, cfg, clients), "sync": syncSnap} {
+		t.Run(mode, func(t *testing.T) {
+			if task, _ := after.TaskByKey("review"); task.Due == nil || task.Due.Date != completed.Due.Date || task.Due.Lang != "en" {
+				t.Fatalf("expected the due date to be read, got %+v", task.Due)
+			}
+			assertCleanPlan(t, cfg, after, Options{})
+		})
+	}
+
+	// A different rule is still an update.
+	cfg.Spec.Tasks[0].Due.String = strPtr("every day at 9am")
+	plan, err := BuildPlan(cfg, fetchSnapshot(t, cfg, clients), Options{})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.Update != 1 || plan.Operations[0].Changes[0].Field != "due.string" {
+		t.Fatalf("expected a due.string update, got %+v", plan.Operations)
+	}
+}
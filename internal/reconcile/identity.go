@@ -0,0 +1,337 @@
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
+)
+
+// stateIDs maps config names to remote IDs that could not be matched by name but are known
+// from the local state file (either a recorded identity or an inferred rename).
+type stateIDs map[Kind]map[string]string
+
+func (ids stateIDs) lookup(kind Kind, name string) (string, bool) {
+	id, ok := ids[kind][name]
+	return id, ok
+}
+
+// namedKind abstracts the per-kind lookups needed to resolve identities from state.
+type namedKind struct {
+	kind       Kind
+	names      []string        // config names without an explicit id
+	claimedIDs map[string]bool // ids referenced explicitly via `id:`
+	byName     func(string) (string, bool, error)
+	byID       func(string) (string, bool) // returns remote name
+	// groupOf and groupOfID scope rename inference (sections pair within their project); nil
+	// means one group per kind.
+	groupOf   func(name string) string
+	groupOfID func(id string) string
+}
+
+// resolveStateIDs resolves config identities that no longer match remote names:
+//
+//  1. a config name recorded in state whose remote ID still exists (renamed in the app), and
+//  2. a rename in YAML: exactly one new config name that matches nothing, paired with exactly one
+//     state entry whose name disappeared from config but whose remote ID still exists (sections
+//     pair within the same project).
+//
+// Ambiguous cases (several new names or several orphans) are left alone and reported in notes.
+func resolveStateIDs(cfg *config.TodoistConfig, snap *Snapshot, st *state.State) (stateIDs, []string) {
+	ids := stateIDs{}
+	if st == nil {
+		return ids, nil
+	}
+
+	var kinds []namedKind
+	{
+		nk := namedKind{
+			kind:       KindProject,
+			claimedIDs: map[string]bool{},
+			byName: func(n string) (string, bool, error) {
+				p, ok, err := snap.ProjectByName(n)
+				return p.ID, ok, err
+			},
+			byID: func(id string) (string, bool) {
+				p, ok := snap.ProjectByID(id)
+				return p.Name, ok
+			},
+		}
+		for _, p := range cfg.Spec.Projects {
+			if p.ID != nil {
+				nk.claimedIDs[*p.ID] = true
+				continue
+			}
+			nk.names = append(nk.names, p.Name)
+		}
+		kinds = append(kinds, nk)
+	}
+	{
+		nk := namedKind{
+			kind:       KindLabel,
+			claimedIDs: map[string]bool{},
+			byName: func(n string) (string, bool, error) {
+				l, ok, err := snap.LabelByName(n)
+				return l.ID, ok, err
+			},
+			byID: func(id string) (string, bool) {
+				l, ok := snap.LabelByID(id)
+				return l.Name, ok
+			},
+		}
+		for _, l := range cfg.Spec.Labels {
+			if l.ID != nil {
+				nk.claimedIDs[*l.ID] = true
+				continue
+			}
+			nk.names = append(nk.names, l.Name)
+		}
+		kinds = append(kinds, nk)
+	}
+	{
+		nk := namedKind{
+			kind:       KindFilter,
+			claimedIDs: map[string]bool{},
+			byName: func(n string) (string, bool, error) {
+				f, ok, err := snap.FilterByName(n)
+				return f.ID, ok, err
+			},
+			byID: func(id string) (string, bool) {
+				f, ok := snap.FilterByID(id)
+				return f.Name, ok
+			},
+		}
+		for _, f := range cfg.Spec.Filters {
+			if f.ID != nil {
+				nk.claimedIDs[*f.ID] = true
+				continue
+			}
+			nk.names = append(nk.names, f.Name)
+		}
+		kinds = append(kinds, nk)
+	}
+
+	{
+		// Sections are recorded as "Project/Section"; the project may itself be resolved from state.
+		type declared struct{ project, section string }
+		byOpName := map[string]declared{}
+		projectID := func(project string) (string, bool, error) {
+			if id, ok := ids.lookup(KindProject, project); ok {
+				return id, true, nil
+			}
+			p, found, err := snap.ProjectByName(project)
+			return p.ID, found, err
+		}
+		nk := namedKind{
+			kind:       KindSection,
+			claimedIDs: map[string]bool{},
+			byName: func(n string) (string, bool, error) {
+				d := byOpName[n]
+				pid, found, err := projectID(d.project)
+				if err != nil || !found {
+					return "", false, err
+				}
+				sec, found, err := snap.SectionByName(pid, d.section)
+				return sec.ID, found, err
+			},
+			groupOf: func(n string) string {
+				pid, _, _ := projectID(byOpName[n].project)
+				return pid
+			},
+			groupOfID: func(id string) string {
+				sec, _ := snap.SectionByID(id)
+				return sec.ProjectID
+			},
+			byID: func(id string) (string, bool) {
+				sec, ok := snap.SectionByID(id)
+				if !ok {
+					return "", false
+				}
+				project, _ := snap.ProjectNameByID(sec.ProjectID)
+				return sectionOpName(project, sec.Name), true
+			},
+		}
+		for _, p := range cfg.Spec.Projects {
+			for _, sec := range p.Sections {
+				if sec.ID != nil {
+					nk.claimedIDs[*sec.ID] = true
+					continue
+				}
+				name := sectionOpName(p.Name, sec.Name)
+				byOpName[name] = declared{project: p.Name, section: sec.Name}
+				nk.names = append(nk.names, name)
+			}
+		}
+		kinds = append(kinds, nk)
+	}
+
+	var notes []string
+	for _, nk := range kinds {
+		resolved := map[string]string{}
+		configNames := map[string]bool{}
+		for _, n := range nk.names {
+			configNames[n] = true
+		}
+
+		// Claim everything that still matches by name first.
+		var candidates []string
+		for _, n := range nk.names {
+			id, ok, err := nk.byName(n)
+			if err != nil {
+				// Duplicate remote names are reported by the planner.
+				continue
+			}
+			if ok {
+				nk.claimedIDs[id] = true
+				continue
+			}
+			candidates = append(candidates, n)
+		}
+
+		var unmatched []string
+		for _, n := range candidates {
+			if r, ok := st.Lookup(string(nk.kind), n); ok {
+				if _, exists := nk.byID(r.ID); exists && !nk.claimedIDs[r.ID] {
+					resolved[n] = r.ID
+					nk.claimedIDs[r.ID] = true
+					continue
+				}
+			}
+			unmatched = append(unmatched, n)
+		}
+
+		var orphans []state.Resource
+		for _, r := range st.ByKind(string(nk.kind)) {
+			if configNames[r.Name] || nk.claimedIDs[r.ID] {
+				continue
+			}
+			remoteName, exists := nk.byID(r.ID)
+			if !exists || configNames[remoteName] {
+				continue
+			}
+			orphans = append(orphans, r)
+		}
+
+		unmatchedByGroup := map[string][]string{}
+		var groups []string
+		for _, n := range unmatched {
+			g := ""
+			if nk.groupOf != nil {
+				if g = nk.groupOf(n); g == "" {
+					continue // e.g. a section of a project that does not exist yet
+				}
+			}
+			if _, ok := unmatchedByGroup[g]; !ok {
+				groups = append(groups, g)
+			}
+			unmatchedByGroup[g] = append(unmatchedByGroup[g], n)
+		}
+		orphansByGroup := map[string][]state.Resource{}
+		for _, r := range orphans {
+			g := ""
+			if nk.groupOfID != nil {
+				g = nk.groupOfID(r.ID)
+			}
+			orphansByGroup[g] = append(orphansByGroup[g], r)
+		}
+		for _, g := range groups {
+			names, removed := unmatchedByGroup[g], orphansByGroup[g]
+			switch {
+			case len(names) == 1 && len(removed) == 1:
+				resolved[names[0]] = removed[0].ID
+				notes = append(notes, fmt.Sprintf("treating %s %q -> %q as a rename (from state)", nk.kind, removed[0].Name, names[0]))
+			case len(removed) > 0:
+				var old []string
+				for _, r := range removed {
+					old = append(old, fmt.Sprintf("%q (id %s)", r.Name, r.ID))
+				}
+				notes = append(notes, fmt.Sprintf("cannot infer %s renames: new %s and %s removed from config are planned as create + delete; set id: on the new entry to rename instead",
+					nk.kind, quoteNames(names), strings.Join(old, ", ")))
+			}
+		}
+		if len(resolved) > 0 {
+			ids[nk.kind] = resolved
+		}
+	}
+	return ids, notes
+}
+
+// RecordState updates st with the remote IDs of every config resource found in snap.
+// It is meant to be called with a snapshot taken after a successful apply, when remote names
+// match the config. Task entries (keyed by task key) are kept when the task is no longer active,
+// since completed tasks disappear from the snapshot.
+func RecordState(st *state.State, cfg *config.TodoistConfig, snap *Snapshot) error {
+	if st == nil || cfg == nil || snap == nil {
+		return fmt.Errorf("state/config/snapshot must be non-nil")
+	}
+	st.Config = cfg.Metadata.Name
+
+	st.RemoveKind(string(KindProject))
+	st.RemoveKind(string(KindSection))
+	for _, p := range cfg.Spec.Projects {
+		id := ""
+		if p.ID != nil {
+			if _, ok := snap.ProjectByID(*p.ID); ok {
+				id = *p.ID
+			}
+		} else if rp, ok, _ := snap.ProjectByName(p.Name); ok {
+			id = rp.ID
+		}
+		if id == "" {
+			continue
+		}
+		st.Set(string(KindProject), p.Name, id)
+		for _, sec := range p.Sections {
+			if sec.ID != nil {
+				st.Set(string(KindSection), sectionOpName(p.Name, sec.Name), *sec.ID)
+			} else if rs, ok, _ := snap.SectionByName(id, sec.Name); ok {
+				st.Set(string(KindSection), sectionOpName(p.Name, sec.Name), rs.ID)
+			}
+		}
+	}
+
+	st.RemoveKind(string(KindLabel))
+	for _, l := range cfg.Spec.Labels {
+		if l.ID != nil {
+			st.Set(string(KindLabel), l.Name, *l.ID)
+		} else if rl, ok, _ := snap.LabelByName(l.Name); ok {
+			st.Set(string(KindLabel), l.Name, rl.ID)
+		}
+	}
+
+	st.RemoveKind(string(KindFilter))
+	for _, f := range cfg.Spec.Filters {
+		if f.ID != nil {
+			st.Set(string(KindFilter), f.Name, *f.ID)
+		} else if rf, ok, _ := snap.FilterByName(f.Name); ok {
+			st.Set(string(KindFilter), f.Name, rf.ID)
+		}
+	}
+
+	keys := map[string]bool{}
+	for _, t := range cfg.Spec.AllTasks() {
+		if t.Key == "" {
+			continue
+		}
+		keys[t.Key] = true
+		if rt, ok := snap.TaskByKey(t.Key); ok {
+			st.Set(string(KindTask), t.Key, rt.ID)
+		}
+	}
+	// Forget tasks that were removed from config.
+	for _, r := range st.ByKind(string(KindTask)) {
+		if !keys[r.Name] {
+			st.Remove(string(KindTask), r.Name)
+		}
+	}
+	return nil
+}
+
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = fmt.Sprintf("%q", n)
+	}
+	return strings.Join(quoted, ", ")
+}
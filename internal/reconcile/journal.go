@@ -0,0 +1,380 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// JournalVersion is the on-disk journal format version.
+const JournalVersion = 1
+
+// Journal records every mutation of one apply with the remote objects as they were before, so the
+// apply can be reverted with `htd undo`.
+type Journal struct {
+	Version   int       `json:"version"`
+	Config    string    `json:"config"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Undoes is the journal this apply reverted, if it was an undo.
+	Undoes string `json:"undoes,omitempty"`
+	// UndoneAt is set once this journal has been reverted.
+	UndoneAt *time.Time `json:"undone_at,omitempty"`
+
+	Entries []JournalEntry `json:"entries"`
+}
+
+// JournalEntry is one successful operation. ID is the remote ID, for creates the new object's.
+// The object fields hold the remote state before the operation (unset for creates).
+type JournalEntry struct {
+	Kind    Kind     `json:"kind"`
+	Action  Action   `json:"action"`
+	Name    string   `json:"name"`
+	ID      string   `json:"id,omitempty"`
+	Changes []Change `json:"changes,omitempty"`
+
+	Project  *v1.Project    `json:"project,omitempty"`
+	Section  *v1.Section    `json:"section,omitempty"`
+	Label    *v1.Label      `json:"label,omitempty"`
+	Filter   *sync.Filter   `json:"filter,omitempty"`
+	Task     *v1.Task       `json:"task,omitempty"`
+	Reminder *sync.Reminder `json:"reminder,omitempty"`
+
+	// Filters holds every filter's order before a filter reorder.
+	Filters []sync.Filter `json:"filters,omitempty"`
+}
+
+// NewJournal records the successful operations of res; snap is the snapshot plan was built from.
+func NewJournal(configName string, snap *Snapshot, plan *Plan, res *ApplyResult) *Journal {
+	j := &Journal{Version: JournalVersion, Config: configName, CreatedAt: time.Now().UTC()}
+	if res == nil {
+		return j
+	}
+	used := make([]bool, len(plan.Operations))
+	for _, r := range res.Applied {
+		if r.Status != "ok" {
+			continue
+		}
+		e := JournalEntry{Kind: r.Kind, Action: r.Action, Name: r.Name, ID: r.ID}
+		for i, op := range plan.Operations {
+			if used[i] || op.Kind != r.Kind || op.Action != r.Action || op.Name != r.Name {
+				continue
+			}
+			if op.ID != "" && r.ID != "" && op.ID != r.ID {
+				continue
+			}
+			used[i] = true
+			e.Changes = op.Changes
+			break
+		}
+		if r.Action != ActionCreate {
+			switch r.Kind {
+			case KindProject:
+				if p, ok := snap.ProjectByID(r.ID); ok {
+					e.Project = &p
+				}
+			case KindSection:
+				if sec, ok := snap.SectionByID(r.ID); ok {
+					e.Section = &sec
+				}
+			case KindLabel:
+				if l, ok := snap.LabelByID(r.ID); ok {
+					e.Label = &l
+				}
+			case KindFilter:
+				if f, ok := snap.FilterByID(r.ID); ok {
+					e.Filter = &f
+				} else if r.Action == ActionReorder {
+					e.Filters = append([]sync.Filter(nil), snap.Filters...)
+				}
+			case KindTask:
+				if t, ok := snap.TaskByID(r.ID); ok {
+					e.Task = &t
+				}
+			case KindReminder:
+				if rem, ok := snap.ReminderByID(r.ID); ok {
+					e.Reminder = &rem
+				}
+			}
+		}
+		j.Entries = append(j.Entries, e)
+	}
+	return j
+}
+
+// BuildUndoPlan computes the operations that revert j against the current remote state: updates,
+// moves and reorders restore the recorded values, creates become deletes, and deleted labels,
+// filters, managed tasks and reminders are recreated. Objects that no longer exist are skipped with a note.
+func BuildUndoPlan(j *Journal, snap *Snapshot) (*Plan, error) {
+	if j == nil || snap == nil {
+		return nil, fmt.Errorf("journal/snapshot must be non-nil")
+	}
+	plan := &Plan{}
+	note := func(format string, args ...any) {
+		plan.Notes = append(plan.Notes, fmt.Sprintf(format, args...))
+	}
+	add := func(op Operation) {
+		plan.Operations = append(plan.Operations, op)
+	}
+
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		e := j.Entries[i]
+		changes := invertChanges(e.Changes)
+		switch e.Action {
+		case ActionCreate:
+			if !remoteExists(snap, e.Kind, e.ID) {
+				note("%s %q (id %s) no longer exists; nothing to delete", e.Kind, e.Name, e.ID)
+				continue
+			}
+			op := Operation{Kind: e.Kind, Action: ActionDelete, Name: e.Name, ID: e.ID}
+			if e.Kind == KindFilter {
+				op.FilterPayload = &FilterPayload{RemoteID: e.ID, DesiredName: e.Name}
+			}
+			add(op)
+
+		case ActionUpdate, ActionMove, ActionReorder:
+			if e.Kind == KindFilter && e.Action == ActionReorder {
+				for _, before := range e.Filters {
+					cur, ok := snap.FilterByID(before.ID)
+					if !ok || cur.ItemOrder == before.ItemOrder {
+						continue
+					}
+					add(Operation{
+						Kind: KindFilter, Action: ActionUpdate, Name: cur.Name, ID: cur.ID,
+						Changes:       []Change{{Field: "order", From: fmt.Sprintf("%d", cur.ItemOrder), To: fmt.Sprintf("%d", before.ItemOrder)}},
+						FilterPayload: &FilterPayload{DesiredName: cur.Name, Query: cur.Query, Order: before.ItemOrder, RemoteID: cur.ID},
+					})
+				}
+				continue
+			}
+			if !remoteExists(snap, e.Kind, e.ID) {
+				note("%s %q (id %s) no longer exists; cannot restore it", e.Kind, e.Name, e.ID)
+				continue
+			}
+			op := Operation{Kind: e.Kind, Action: e.Action, Name: e.Name, ID: e.ID, Changes: changes}
+			switch {
+			case e.Project != nil:
+				p := e.Project
+				op.ProjectPayload = &ProjectPayload{DesiredName: p.Name, Color: &p.Color, IsFavorite: &p.IsFavorite, ViewStyle: &p.ViewStyle}
+				if e.Action == ActionMove && p.ParentID != nil {
+					parent, ok := snap.ProjectNameByID(*p.ParentID)
+					if !ok {
+						note("project %q: former parent (id %s) no longer exists; cannot move it back", e.Name, *p.ParentID)
+						continue
+					}
+					op.ProjectPayload.ParentName = &parent
+				}
+			case e.Section != nil:
+				projectName, _ := snap.ProjectNameByID(e.Section.ProjectID)
+				op.SectionPayload = &SectionPayload{ProjectName: projectName, DesiredName: e.Section.Name, Order: e.Section.SectionOrder}
+			case e.Label != nil:
+				l := e.Label
+				op.LabelPayload = &LabelPayload{DesiredName: l.Name, Color: &l.Color, IsFavorite: &l.IsFavorite}
+			case e.Filter != nil:
+				f := e.Filter
+				op.FilterPayload = &FilterPayload{DesiredName: f.Name, Query: f.Query, Color: &f.Color, IsFavorite: &f.IsFavorite, Order: f.ItemOrder, RemoteID: f.ID}
+			case e.Task != nil:
+				op.TaskPayload = taskRestorePayload(e.Task)
+				restoreTaskSection(snap, e.Name, op.TaskPayload, note)
+			case e.Reminder != nil:
+				op.ReminderPayload = reminderRestorePayload(snap, e.Reminder)
+			default:
+				note("%s %s %q has no recorded previous state; cannot restore it", e.Kind, e.Action, e.Name)
+				continue
+			}
+			add(op)
+
+		case ActionDelete:
+			switch {
+			case e.Label != nil:
+				l := e.Label
+				add(Operation{Kind: KindLabel, Action: ActionCreate, Name: l.Name, LabelPayload: &LabelPayload{DesiredName: l.Name, Color: &l.Color, IsFavorite: &l.IsFavorite}})
+			case e.Filter != nil:
+				f := e.Filter
+				add(Operation{Kind: KindFilter, Action: ActionCreate, Name: f.Name, FilterPayload: &FilterPayload{DesiredName: f.Name, Query: f.Query, Color: &f.Color, IsFavorite: &f.IsFavorite, Order: f.ItemOrder}})
+			case e.Task != nil:
+				if _, ok := snap.ProjectByID(e.Task.ProjectID); !ok {
+					note("task %q: its project (id %s) no longer exists; cannot recreate it", e.Name, e.Task.ProjectID)
+					continue
+				}
+				payload := taskRestorePayload(e.Task)
+				if payload.ParentID != nil {
+					if _, ok := snap.TaskByID(*payload.ParentID); !ok {
+						note("task %q: its parent (id %s) no longer exists; it is recreated as a top-level task", e.Name, *payload.ParentID)
+						payload.ParentID, payload.Order = nil, 0
+					}
+				}
+				restoreTaskSection(snap, e.Name, payload, note)
+				add(Operation{Kind: KindTask, Action: ActionCreate, Name: e.Task.Content, TaskPayload: payload})
+				note("task %q is recreated as a new task (comments and history are not restored)", e.Name)
+			case e.Reminder != nil:
+				if _, ok := snap.TaskByID(e.Reminder.ItemID); !ok {
+					note("reminder %q: its task (id %s) no longer exists; cannot recreate it", e.Name, e.Reminder.ItemID)
+					continue
+				}
+				add(Operation{Kind: KindReminder, Action: ActionCreate, Name: e.Name, ReminderPayload: reminderRestorePayload(snap, e.Reminder)})
+			default:
+				note("cannot restore deleted %s %q (Todoist deletes its contents with it)", e.Kind, e.Name)
+			}
+		}
+	}
+
+	dropSubtaskDeletes(plan, snap)
+	sortOperations(plan.Operations)
+	plan.Summary = summarize(plan.Operations)
+	return plan, nil
+}
+
+// taskRestorePayload describes t as it was, for updating a task back or recreating it.
+func taskRestorePayload(t *v1.Task) *TaskPayload {
+	key, _ := ManagedTaskKey(t.Description)
+	desc, projectID, priority := t.Description, t.ProjectID, t.Priority
+	due := "no date"
+	if t.Due != nil {
+		due = t.Due.String
+	}
+	p := &TaskPayload{
+		Key:         key,
+		DesiredName: t.Content,
+		Description: &desc,
+		ProjectID:   &projectID,
+		Labels:      append([]string{}, t.Labels...),
+		Priority:    &priority,
+		DueString:   &due,
+		Order:       t.ChildOrder,
+	}
+	if t.ParentID != nil {
+		parentID := *t.ParentID
+		p.ParentID = &parentID
+	}
+	if t.SectionID != nil {
+		sectionID := *t.SectionID
+		p.SectionID = &sectionID
+	}
+	return p
+}
+
+// restoreTaskSection leaves the section out of a restored task when the section no longer exists,
+// so the task goes back to its project root instead.
+func restoreTaskSection(snap *Snapshot, name string, payload *TaskPayload, note func(string, ...any)) {
+	if payload.SectionID == nil {
+		return
+	}
+	if _, ok := snap.SectionByID(*payload.SectionID); !ok {
+		note("task %q: its section (id %s) no longer exists; it is restored to the project root", name, *payload.SectionID)
+		payload.SectionID = nil
+	}
+}
+
+func invertChanges(changes []Change) []Change {
+	out := make([]Change, 0, len(changes))
+	for _, ch := range changes {
+		out = append(out, Change{Field: ch.Field, From: ch.To, To: ch.From})
+	}
+	return out
+}
+
+func remoteExists(snap *Snapshot, kind Kind, id string) bool {
+	var ok bool
+	switch kind {
+	case KindProject:
+		_, ok = snap.ProjectByID(id)
+	case KindSection:
+		_, ok = snap.SectionByID(id)
+	case KindLabel:
+		_, ok = snap.LabelByID(id)
+	case KindFilter:
+		_, ok = snap.FilterByID(id)
+	case KindTask:
+		_, ok = snap.TaskByID(id)
+	case KindReminder:
+		_, ok = snap.ReminderByID(id)
+	}
+	return ok
+}
+
+// DefaultJournalDir is where apply journals are kept: ~/.config/todoist/journal.
+func DefaultJournalDir() (string, error) {
+	dir, err := config.ConfigDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "journal"), nil
+}
+
+// WriteJournal saves j in dir as <config>-<timestamp>.json and returns the path.
+func WriteJournal(dir string, j *Journal) (string, error) {
+	path := filepath.Join(dir, j.Config+"-"+j.CreatedAt.Format("20060102T150405.000000000Z")+".json")
+	if err := SaveJournal(path, j); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SaveJournal writes j to path, creating parent directories.
+func SaveJournal(path string, j *Journal) error {
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal journal: %w", err)
+	}
+	b = append(b, '\n')
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create journal dir: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("write journal %q: %w", path, err)
+	}
+	return nil
+}
+
+// ReadJournal loads a journal written by WriteJournal.
+func ReadJournal(path string) (*Journal, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read journal %q: %w", path, err)
+	}
+	var j Journal
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, fmt.Errorf("parse journal %q: %w", path, err)
+	}
+	if j.Version != JournalVersion {
+		return nil, fmt.Errorf("journal %q has unsupported version %d (expected %d)", path, j.Version, JournalVersion)
+	}
+	return &j, nil
+}
+
+// LatestJournal returns the path of the newest journal in dir for configName that has not been
+// undone and is not itself an undo, so repeated `htd undo` walks back through earlier applies.
+func LatestJournal(dir, configName string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("read journal dir %q: %w", dir, err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), configName+"-") && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		j, err := ReadJournal(path)
+		if err != nil {
+			return "", err
+		}
+		if j.Config == configName && j.Undoes == "" && j.UndoneAt == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no journal to undo for config %q in %s", configName, dir)
+}
@@ -0,0 +1,86 @@
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/filterquery"
+)
+
+// LintIssue is a filter query reference that resolves to nothing.
+type LintIssue struct {
+	Filter  string `json:"filter"`
+	Ref     string `json:"ref"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("filter %q: %s", i.Filter, i.Message)
+}
+
+// LintFilterQueries reports #project, /section and @label references in filter queries that match
+// neither the config nor, when snap is non-nil, the remote snapshot. Matching is case-insensitive
+// and honours "*" wildcards, like Todoist. With a nil snap only config names are known (offline).
+func LintFilterQueries(cfg *config.TodoistConfig, snap *Snapshot) ([]LintIssue, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is nil")
+	}
+
+	known := map[filterquery.RefKind][]string{
+		// The inbox always exists, even offline.
+		filterquery.RefProject: {"Inbox"},
+	}
+	for _, p := range cfg.Spec.Projects {
+		known[filterquery.RefProject] = append(known[filterquery.RefProject], p.Name)
+		for _, sec := range p.Sections {
+			known[filterquery.RefSection] = append(known[filterquery.RefSection], sec.Name)
+		}
+	}
+	for _, l := range cfg.Spec.Labels {
+		known[filterquery.RefLabel] = append(known[filterquery.RefLabel], l.Name)
+	}
+	if snap != nil {
+		for _, p := range snap.Projects {
+			known[filterquery.RefProject] = append(known[filterquery.RefProject], p.Name)
+		}
+		for _, sec := range snap.Sections {
+			known[filterquery.RefSection] = append(known[filterquery.RefSection], sec.Name)
+		}
+		for _, l := range snap.Labels {
+			known[filterquery.RefLabel] = append(known[filterquery.RefLabel], l.Name)
+		}
+	}
+
+	where := "config"
+	if snap != nil {
+		where = "config or Todoist"
+	}
+	var issues []LintIssue
+	for _, f := range cfg.Spec.Filters {
+		q, err := filterquery.Parse(f.Query)
+		if err != nil {
+			issues = append(issues, LintIssue{Filter: f.Name, Message: fmt.Sprintf("invalid query: %v", err)})
+			continue
+		}
+		for _, ref := range q.Refs() {
+			if anyMatch(ref.Name, known[ref.Kind]) {
+				continue
+			}
+			issues = append(issues, LintIssue{
+				Filter:  f.Name,
+				Ref:     ref.String(),
+				Message: fmt.Sprintf("%s %q not found in %s", ref.Kind, ref.Name, where),
+			})
+		}
+	}
+	return issues, nil
+}
+
+func anyMatch(pattern string, names []string) bool {
+	for _, n := range names {
+		if filterquery.Match(pattern, n) {
+			return true
+		}
+	}
+	return false
+}
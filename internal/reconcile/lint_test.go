@@ -0,0 +1,50 @@
+package reconcile
+
+import (
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+func TestLintFilterQueries(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{{Name: "Work", Sections: []config.SectionSpec{{Name: "Doing"}}}},
+			Labels:   []config.LabelSpec{{Name: "waiting"}},
+			Filters: []config.FilterSpec{
+				{Name: "OK", Query: "(#work | ##Inbox) & /Doing & @Waiting"},
+				{Name: "Typos", Query: "#Wrok & @wating"},
+				{Name: "Remote", Query: "#Errands & @home*"},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	issues, err := LintFilterQueries(cfg, nil)
+	if err != nil {
+		t.Fatalf("LintFilterQueries: %v", err)
+	}
+	if len(issues) != 4 {
+		t.Fatalf("expected 4 offline issues, got %v", issues)
+	}
+
+	snap := &Snapshot{
+		Projects: []v1.Project{{ID: "P1", Name: "Errands"}},
+		Labels:   []v1.Label{{ID: "L1", Name: "home-office"}},
+	}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	issues, err = LintFilterQueries(cfg, snap)
+	if err != nil {
+		t.Fatalf("LintFilterQueries: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Ref != "#Wrok" || issues[1].Ref != "@wating" {
+		t.Fatalf("expected only the typos, got %v", issues)
+	}
+}
@@ -5,12 +5,28 @@ import (
 	"sort"
 
 	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
 )
 
 type Options struct {
 	Prune bool
+
+	// State, when set, is used to match config entries to remote IDs across renames and to tell
+	// completed one-shot tasks from tasks not created yet.
+	State *state.State
+
+	// Targets, when set, restrict the plan to matching resources and their dependencies.
+	Targets []Target
+
+	// ContinueOnError makes Apply record a failed operation and carry on, skipping the operations
+	// that depend on it, instead of stopping at the first failure.
+	ContinueOnError bool
+
+	// AdoptTasks plans a "key" change for tasks matched by id whose description lacks their
+	// HTD_KEY: marker, so applying writes it and the tasks stay managed without the id.
+	AdoptTasks bool
 }
 
 func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan, error) {
@@ -23,7 +39,11 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 
 	plan := &Plan{}
 
+	ids, idNotes := resolveStateIDs(cfg, snap, opts.State)
+	plan.Notes = append(plan.Notes, idNotes...)
+
 	pruneProjects := opts.Prune && cfg.Spec.Prune.Projects
+	pruneSections := opts.Prune && cfg.Spec.Prune.Sections
 	pruneLabels := opts.Prune && cfg.Spec.Prune.Labels
 	pruneFilters := opts.Prune && cfg.Spec.Prune.Filters
 	pruneTasks := opts.Prune && cfg.Spec.Prune.Tasks
@@ -31,6 +51,8 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 	// Projects
 	desiredProjectNames := map[string]struct{}{}
 	desiredProjectIDs := map[string]struct{}{}
+	remoteProjectIDByName := map[string]string{}
+	remoteProjectByName := map[string]v1.Project{}
 	for _, p := range cfg.Spec.Projects {
 		desiredProjectNames[p.Name] = struct{}{}
 		if p.ID != nil {
@@ -39,6 +61,7 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 
 		var remote v1.Project
 		var exists bool
+		var byStateID bool
 		var err error
 		if p.ID != nil {
 			remote, exists = snap.ProjectByID(*p.ID)
@@ -50,6 +73,15 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			if err != nil {
 				return nil, err
 			}
+			if !exists {
+				if id, ok := ids.lookup(KindProject, p.Name); ok {
+					remote, exists = snap.ProjectByID(id)
+					byStateID = exists
+				}
+			}
+		}
+		if byStateID {
+			desiredProjectIDs[remote.ID] = struct{}{}
 		}
 		if !exists {
 			plan.Operations = append(plan.Operations, Operation{
@@ -67,10 +99,12 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			plan.Summary.Create++
 			continue
 		}
+		remoteProjectIDByName[p.Name] = remote.ID
+		remoteProjectByName[p.Name] = remote
 
 		// Update managed fields via Unified API v1.
 		var changes []Change
-		if p.ID != nil && remote.Name != p.Name {
+		if (p.ID != nil || byStateID) && remote.Name != p.Name {
 			changes = append(changes, Change{Field: "name", From: remote.Name, To: p.Name})
 		}
 		if p.Color != nil && remote.Color != *p.Color {
@@ -98,8 +132,15 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			})
 			plan.Summary.Update++
 		}
+	}
 
-		// Parent (project_move) via /sync.
+	// Parent (project_move) via /sync. Checked once every project is resolved so that a renamed
+	// parent is compared by ID rather than by its old name.
+	for _, p := range cfg.Spec.Projects {
+		remote, ok := remoteProjectByName[p.Name]
+		if !ok {
+			continue
+		}
 		desiredParent := ""
 		if p.Parent != nil {
 			desiredParent = *p.Parent
@@ -112,7 +153,11 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 				remoteParent = *remote.ParentID
 			}
 		}
-		if desiredParent != remoteParent {
+		parentChanged := desiredParent != remoteParent
+		if pid, ok := remoteProjectIDByName[desiredParent]; ok && remote.ParentID != nil {
+			parentChanged = pid != *remote.ParentID
+		}
+		if parentChanged {
 			plan.Operations = append(plan.Operations, Operation{
 				Kind:    KindProject,
 				Action:  ActionMove,
@@ -171,6 +216,128 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 		}
 	}
 
+	// Sections (identity: name within the owning project; only for projects that declare sections)
+	if opts.Prune && !cfg.Spec.Prune.Sections {
+		plan.Notes = append(plan.Notes, "--prune set but spec.prune.sections=false; section deletions are disabled")
+	}
+	var sectionExtras int
+	for _, p := range cfg.Spec.Projects {
+		if p.Sections == nil {
+			continue
+		}
+		projectID, projectExists := remoteProjectIDByName[p.Name]
+		desiredSectionNames := map[string]struct{}{}
+		desiredSectionIDs := map[string]struct{}{}
+		for _, sec := range p.Sections {
+			desiredSectionNames[sec.Name] = struct{}{}
+			if sec.ID != nil {
+				desiredSectionIDs[*sec.ID] = struct{}{}
+			}
+			ord := 0
+			if sec.Order != nil {
+				ord = *sec.Order
+			}
+			opName := sectionOpName(p.Name, sec.Name)
+
+			var remote v1.Section
+			var exists bool
+			var err error
+			if sec.ID != nil {
+				remote, exists = snap.SectionByID(*sec.ID)
+				if !exists {
+					return nil, fmt.Errorf("section %q references id %q which was not found", opName, *sec.ID)
+				}
+				if !projectExists || remote.ProjectID != projectID {
+					owner, _ := snap.ProjectNameByID(remote.ProjectID)
+					return nil, fmt.Errorf("section %q references id %q which belongs to project %q", opName, *sec.ID, owner)
+				}
+			} else if projectExists {
+				remote, exists, err = snap.SectionByName(projectID, sec.Name)
+				if err != nil {
+					return nil, err
+				}
+				if !exists {
+					// Renamed in the app: the recorded ID is renamed back (only within this project).
+					if id, ok := ids.lookup(KindSection, opName); ok {
+						if rs, found := snap.SectionByID(id); found && rs.ProjectID == projectID {
+							remote, exists = rs, true
+							desiredSectionIDs[id] = struct{}{}
+						}
+					}
+				}
+			}
+			if !exists {
+				plan.Operations = append(plan.Operations, Operation{
+					Kind:   KindSection,
+					Action: ActionCreate,
+					Name:   opName,
+					SectionPayload: &SectionPayload{
+						ProjectName: p.Name,
+						DesiredName: sec.Name,
+						Order:       ord,
+					},
+				})
+				plan.Summary.Create++
+				continue
+			}
+			if remote.Name != sec.Name {
+				plan.Operations = append(plan.Operations, Operation{
+					Kind:    KindSection,
+					Action:  ActionUpdate,
+					Name:    opName,
+					ID:      remote.ID,
+					Changes: []Change{{Field: "name", From: remote.Name, To: sec.Name}},
+					SectionPayload: &SectionPayload{
+						ProjectName: p.Name,
+						DesiredName: sec.Name,
+						Order:       ord,
+					},
+				})
+				plan.Summary.Update++
+			}
+			if ord != 0 && remote.SectionOrder != ord {
+				plan.Operations = append(plan.Operations, Operation{
+					Kind:    KindSection,
+					Action:  ActionReorder,
+					Name:    opName,
+					ID:      remote.ID,
+					Changes: []Change{{Field: "order", From: fmt.Sprintf("%d", remote.SectionOrder), To: fmt.Sprintf("%d", ord)}},
+					SectionPayload: &SectionPayload{
+						ProjectName: p.Name,
+						DesiredName: sec.Name,
+						Order:       ord,
+					},
+				})
+				plan.Summary.Reorder++
+			}
+		}
+		if !projectExists {
+			continue
+		}
+		for _, rs := range snap.SectionsInProject(projectID) {
+			if _, ok := desiredSectionIDs[rs.ID]; ok {
+				continue
+			}
+			if _, ok := desiredSectionNames[rs.Name]; ok {
+				continue
+			}
+			if !pruneSections {
+				sectionExtras++
+				continue
+			}
+			plan.Operations = append(plan.Operations, Operation{
+				Kind:   KindSection,
+				Action: ActionDelete,
+				Name:   sectionOpName(p.Name, rs.Name),
+				ID:     rs.ID,
+			})
+			plan.Summary.Delete++
+		}
+	}
+	if sectionExtras > 0 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("%d remote sections in managed projects are not in config (prune disabled)", sectionExtras))
+	}
+
 	// Labels
 	desiredLabelNames := map[string]struct{}{}
 	desiredLabelIDs := map[string]struct{}{}
@@ -182,6 +349,7 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 
 		var remote v1.Label
 		var exists bool
+		var byStateID bool
 		var err error
 		if l.ID != nil {
 			remote, exists = snap.LabelByID(*l.ID)
@@ -193,6 +361,15 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			if err != nil {
 				return nil, err
 			}
+			if !exists {
+				if id, ok := ids.lookup(KindLabel, l.Name); ok {
+					remote, exists = snap.LabelByID(id)
+					byStateID = exists
+				}
+			}
+		}
+		if byStateID {
+			desiredLabelIDs[remote.ID] = struct{}{}
 		}
 		if !exists {
 			plan.Operations = append(plan.Operations, Operation{
@@ -209,7 +386,7 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			continue
 		}
 		var changes []Change
-		if l.ID != nil && remote.Name != l.Name {
+		if (l.ID != nil || byStateID) && remote.Name != l.Name {
 			changes = append(changes, Change{Field: "name", From: remote.Name, To: l.Name})
 		}
 		if l.Color != nil && remote.Color != *l.Color {
@@ -282,6 +459,7 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 
 		var remote sync.Filter
 		var exists bool
+		var byStateID bool
 		var err error
 		if f.ID != nil {
 			remote, exists = snap.FilterByID(*f.ID)
@@ -293,6 +471,15 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			if err != nil {
 				return nil, err
 			}
+			if !exists {
+				if id, ok := ids.lookup(KindFilter, f.Name); ok {
+					remote, exists = snap.FilterByID(id)
+					byStateID = exists
+				}
+			}
+		}
+		if byStateID {
+			desiredFilterIDs[remote.ID] = struct{}{}
 		}
 		ord := 0
 		if f.Order != nil {
@@ -315,7 +502,7 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			continue
 		}
 		var changes []Change
-		if f.ID != nil && remote.Name != f.Name {
+		if (f.ID != nil || byStateID) && remote.Name != f.Name {
 			changes = append(changes, Change{Field: "name", From: remote.Name, To: f.Name})
 		}
 		if remote.Query != f.Query {
@@ -387,10 +574,18 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 		}
 	}
 
-	// Tasks (managed templates only; identity by id or key)
+	// Tasks (managed templates only; identity by id or key). Subtasks follow their parent.
 	desiredTaskIDs := map[string]struct{}{}
 	desiredTaskKeys := map[string]struct{}{}
-	for _, t := range cfg.Spec.Tasks {
+	declaredSections := map[string]config.SectionSpec{}
+	for _, p := range cfg.Spec.Projects {
+		for _, sec := range p.Sections {
+			declaredSections[sectionOpName(p.Name, sec.Name)] = sec
+		}
+	}
+	unmarked := 0
+	done := map[string]bool{} // taskIdentity of completed one-shot tasks, and of their subtasks
+	for _, t := range cfg.Spec.AllTasks() {
 		if t.ID != nil {
 			desiredTaskIDs[*t.ID] = struct{}{}
 		}
@@ -405,44 +600,85 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 		} else {
 			remote, exists = snap.TaskByKey(t.Key)
 		}
+		// Completed tasks leave the snapshot, and Todoist completes subtasks with their parent.
+		if t.Parent != nil && done[taskIdentity(*t.Parent)] {
+			done[taskIdentity(t.TaskSpec)] = true
+			continue
+		}
+		if !exists {
+			if note := oneShotDone(t.TaskSpec, snap, opts.State); note != "" {
+				done[taskIdentity(t.TaskSpec)] = true
+				plan.Notes = append(plan.Notes, fmt.Sprintf("one-shot task %q %s; not recreating it", t.Content, note))
+				continue
+			}
+		}
 
 		var desiredProjectID *string
 		var desiredProjectName *string
 		if t.Project != nil {
 			desiredProjectName = t.Project
-			p, ok, err := snap.ProjectByName(*t.Project)
-			if err != nil {
-				return nil, err
-			}
-			if !ok {
-				// Allow referencing projects declared in config that will be created in this apply.
-				if _, declared := desiredProjectNames[*t.Project]; !declared {
+			if pid, ok := remoteProjectIDByName[*t.Project]; ok {
+				// Declared in config and resolved (possibly by id or state).
+				desiredProjectID = &pid
+			} else if _, declared := desiredProjectNames[*t.Project]; !declared {
+				p, ok, err := snap.ProjectByName(*t.Project)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
 					return nil, fmt.Errorf("task %q references unknown project %q", t.Content, *t.Project)
 				}
-			} else {
 				pid := p.ID
 				desiredProjectID = &pid
 			}
+			// Otherwise the project is declared in config and will be created in this apply.
 		}
 
 		desiredDesc := buildManagedTaskDescription(t.Description, t.Key)
+		payload := &TaskPayload{
+			Key:         t.Key,
+			DesiredName: t.Content,
+			Description: desiredDesc,
+			ProjectName: desiredProjectName,
+			ProjectID:   desiredProjectID,
+			Labels:      t.Labels,
+			Priority:    t.Priority,
+			DueString:   t.Due.String,
+		}
+		if t.Section != nil {
+			sectionID, err := resolveTaskSection(t.TaskSpec, desiredProjectID, snap, declaredSections)
+			if err != nil {
+				return nil, err
+			}
+			payload.SectionName, payload.SectionID = t.Section, sectionID
+		}
+		if t.Parent != nil {
+			// The parent is planned first; when it does not exist yet it is created in this apply.
+			parentName := t.Parent.Content
+			payload.ParentName, payload.ParentKey, payload.Order = &parentName, t.Parent.Key, t.Order
+			var parent v1.Task
+			var ok bool
+			if t.Parent.ID != nil {
+				parent, ok = snap.TaskByID(*t.Parent.ID)
+			} else {
+				parent, ok = snap.TaskByKey(t.Parent.Key)
+			}
+			if ok {
+				parentID := parent.ID
+				payload.ParentID = &parentID
+			}
+		}
 		if !exists {
 			plan.Operations = append(plan.Operations, Operation{
-				Kind:   KindTask,
-				Action: ActionCreate,
-				Name:   t.Content,
-				TaskPayload: &TaskPayload{
-					Key:         t.Key,
-					DesiredName: t.Content,
-					Description: desiredDesc,
-					ProjectName: desiredProjectName,
-					ProjectID:   desiredProjectID,
-					Labels:      t.Labels,
-					Priority:    t.Priority,
-					DueString:   t.Due.String,
-				},
+				Kind:        KindTask,
+				Action:      ActionCreate,
+				Name:        t.Content,
+				TaskPayload: payload,
 			})
 			plan.Summary.Create++
+			if err := planTaskReminders(plan, t.TaskSpec, nil, snap); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
@@ -450,7 +686,7 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 		if remote.Content != t.Content {
 			changes = append(changes, Change{Field: "content", From: remote.Content, To: t.Content})
 		}
-		remoteDesc := taskDescriptionSansManagedKey(remote.Description)
+		remoteDesc := TaskDescriptionSansManagedKey(remote.Description)
 		wantDesc := ""
 		if t.Description != nil {
 			wantDesc = *t.Description
@@ -459,14 +695,12 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			changes = append(changes, Change{Field: "description", From: remoteDesc, To: wantDesc})
 		}
 		remoteProjectID := remote.ProjectID
-		if desiredProjectName != nil {
+		if desiredProjectName != nil && (desiredProjectID == nil || *desiredProjectID != remoteProjectID) {
 			remoteProjectName := ""
 			if n, ok := snap.ProjectNameByID(remoteProjectID); ok {
 				remoteProjectName = n
 			}
-			if remoteProjectName != *desiredProjectName {
-				changes = append(changes, Change{Field: "project", From: remoteProjectName, To: *desiredProjectName})
-			}
+			changes = append(changes, Change{Field: "project", From: remoteProjectName, To: *desiredProjectName})
 		}
 		if !equalStringSet(remote.Labels, t.Labels) {
 			changes = append(changes, Change{Field: "labels", From: fmt.Sprintf("%v", remote.Labels), To: fmt.Sprintf("%v", t.Labels)})
@@ -486,30 +720,87 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 		if t.Due.String != nil {
 			wantDueString = *t.Due.String
 		}
-		if remoteDueString != wantDueString {
+		if !dueMatches(remote.Due, wantDueString) {
 			changes = append(changes, Change{Field: "due.string", From: remoteDueString, To: wantDueString})
 		}
+		if remoteKey, _ := ManagedTaskKey(remote.Description); t.Key != "" && remoteKey != t.Key {
+			if opts.AdoptTasks {
+				changes = append(changes, Change{Field: "key", From: remoteKey, To: t.Key})
+			} else {
+				unmarked++
+			}
+		}
 
 		if len(changes) > 0 {
 			plan.Operations = append(plan.Operations, Operation{
-				Kind:    KindTask,
-				Action:  ActionUpdate,
-				Name:    t.Content,
-				ID:      remote.ID,
-				Changes: changes,
-				TaskPayload: &TaskPayload{
-					Key:         t.Key,
-					DesiredName: t.Content,
-					Description: desiredDesc,
-					ProjectName: desiredProjectName,
-					ProjectID:   desiredProjectID,
-					Labels:      t.Labels,
-					Priority:    t.Priority,
-					DueString:   t.Due.String,
-				},
+				Kind:        KindTask,
+				Action:      ActionUpdate,
+				Name:        t.Content,
+				ID:          remote.ID,
+				Changes:     changes,
+				TaskPayload: payload,
 			})
 			plan.Summary.Update++
 		}
+		if t.Section != nil && (payload.SectionID == nil || remote.SectionID == nil || *remote.SectionID != *payload.SectionID) {
+			// Sections (item_move via /sync); tasks without a declared section stay where they are.
+			from := ""
+			if remote.SectionID != nil {
+				from = *remote.SectionID
+				if sec, ok := snap.SectionByID(*remote.SectionID); ok {
+					from = sec.Name
+				}
+			}
+			plan.Operations = append(plan.Operations, Operation{
+				Kind:        KindTask,
+				Action:      ActionMove,
+				Name:        t.Content,
+				ID:          remote.ID,
+				Changes:     []Change{{Field: "section", From: from, To: *t.Section}},
+				TaskPayload: payload,
+			})
+			plan.Summary.Move++
+		}
+		if t.Parent != nil {
+			// Parent (item_move) and position among the parent's subtasks (item_reorder) via /sync.
+			// Top-level tasks keep whatever parent they have remotely.
+			moved := payload.ParentID == nil || remote.ParentID == nil || *remote.ParentID != *payload.ParentID
+			if moved {
+				from := ""
+				if remote.ParentID != nil {
+					from = *remote.ParentID
+					if p, ok := snap.TaskByID(*remote.ParentID); ok {
+						from = p.Content
+					}
+				}
+				plan.Operations = append(plan.Operations, Operation{
+					Kind:        KindTask,
+					Action:      ActionMove,
+					Name:        t.Content,
+					ID:          remote.ID,
+					Changes:     []Change{{Field: "parent", From: from, To: *payload.ParentName}},
+					TaskPayload: payload,
+				})
+				plan.Summary.Move++
+			}
+			if moved || remote.ChildOrder != t.Order {
+				plan.Operations = append(plan.Operations, Operation{
+					Kind:        KindTask,
+					Action:      ActionReorder,
+					Name:        t.Content,
+					ID:          remote.ID,
+					Changes:     []Change{{Field: "order", From: fmt.Sprintf("%d", remote.ChildOrder), To: fmt.Sprintf("%d", t.Order)}},
+					TaskPayload: payload,
+				})
+				plan.Summary.Reorder++
+			}
+		}
+		if err := planTaskReminders(plan, t.TaskSpec, &remote, snap); err != nil {
+			return nil, err
+		}
+	}
+	if unmarked > 0 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("%d task(s) matched by id have no HTD_KEY marker for their key; use --adopt-tasks to write it", unmarked))
 	}
 	if opts.Prune && !cfg.Spec.Prune.Tasks {
 		plan.Notes = append(plan.Notes, "--prune set but spec.prune.tasks=false; task deletions are disabled")
@@ -519,7 +810,7 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			if _, ok := desiredTaskIDs[rt.ID]; ok {
 				continue
 			}
-			key, managed := managedTaskKey(rt.Description)
+			key, managed := ManagedTaskKey(rt.Description)
 			if !managed {
 				continue
 			}
@@ -540,7 +831,7 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 			if _, ok := desiredTaskIDs[rt.ID]; ok {
 				continue
 			}
-			key, managed := managedTaskKey(rt.Description)
+			key, managed := ManagedTaskKey(rt.Description)
 			if !managed {
 				continue
 			}
@@ -554,9 +845,18 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 		}
 	}
 
-	// Deterministic ordering: sort by kind then name, then action.
-	sort.Slice(plan.Operations, func(i, j int) bool {
-		a, b := plan.Operations[i], plan.Operations[j]
+	dropSubtaskDeletes(plan, snap)
+
+	sortOperations(plan.Operations)
+	restrictToTargets(plan, opts.Targets)
+
+	return plan, nil
+}
+
+// sortOperations orders ops deterministically: by kind, then name, then action.
+func sortOperations(ops []Operation) {
+	sort.Slice(ops, func(i, j int) bool {
+		a, b := ops[i], ops[j]
 		if a.Kind != b.Kind {
 			return kindOrder(a.Kind) < kindOrder(b.Kind)
 		}
@@ -565,21 +865,62 @@ func BuildPlan(cfg *config.TodoistConfig, snap *Snapshot, opts Options) (*Plan,
 		}
 		return a.Action < b.Action
 	})
-
-	return plan, nil
 }
 
 func kindOrder(k Kind) int {
 	switch k {
 	case KindProject:
 		return 0
-	case KindLabel:
+	case KindSection:
 		return 1
-	case KindFilter:
+	case KindLabel:
 		return 2
-	case KindTask:
+	case KindFilter:
 		return 3
+	case KindTask:
+		return 4
+	case KindReminder:
+		return 5
 	default:
 		return 99
 	}
 }
+
+// sectionOpName is the display/identity name for a section operation ("Project/Section").
+func sectionOpName(projectName, sectionName string) string {
+	return projectName + "/" + sectionName
+}
+
+// oneShotDone explains why a one_shot task missing from the snapshot must not be recreated, or
+// returns "" when it should be created: it was completed (found by its HTD_KEY marker among the
+// completed tasks), it is pinned by id, or, as a fallback for completions older than the completed
+// tasks snap lists, its key is recorded in state (RecordState keeps task entries after the task
+// leaves the snapshot).
+func oneShotDone(t config.TaskSpec, snap *Snapshot, st *state.State) string {
+	if t.Type == nil || *t.Type != "one_shot" {
+		return ""
+	}
+	if t.Key != "" {
+		if _, ok := snap.CompletedTaskByKey(t.Key); ok {
+			return "was completed"
+		}
+	}
+	if t.ID != nil {
+		return "was created before and is no longer active (completed or deleted)"
+	}
+	if st == nil || t.Key == "" {
+		return ""
+	}
+	if _, ok := st.Lookup(string(KindTask), t.Key); ok {
+		return "was created before and is no longer active (completed or deleted)"
+	}
+	return ""
+}
+
+// taskIdentity identifies a declared task by its id, or by its key when it has none.
+func taskIdentity(t config.TaskSpec) string {
+	if t.ID != nil {
+		return "id:" + *t.ID
+	}
+	return "key:" + t.Key
+}
@@ -1,9 +1,12 @@
 package reconcile
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
 )
@@ -202,6 +205,405 @@ func TestBuildPlan_TaskByKey_Update(t *testing.T) {
 	}
 }
 
+func TestBuildPlan_Sections(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{
+				{Name: "Homelab", Sections: []config.SectionSpec{{Name: "Backlog"}, {Name: "Doing"}}},
+				{Name: "New", Sections: []config.SectionSpec{{Name: "Inbox"}}},
+				{Name: "Unmanaged"},
+			},
+			Prune: config.PruneSpec{Sections: true},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	snap := &Snapshot{
+		Projects: []v1.Project{
+			{ID: "P1", Name: "Homelab"},
+			{ID: "P2", Name: "Unmanaged"},
+		},
+		Sections: []v1.Section{
+			{ID: "S1", ProjectID: "P1", Name: "Doing", SectionOrder: 1},
+			{ID: "S2", ProjectID: "P1", Name: "Old", SectionOrder: 2},
+			{ID: "S3", ProjectID: "P2", Name: "Whatever", SectionOrder: 1},
+		},
+	}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	plan, err := BuildPlan(cfg, snap, Options{Prune: true})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+
+	want := map[string]Action{
+		"Homelab/Backlog": ActionCreate,
+		"Homelab/Doing":   ActionReorder,
+		"Homelab/Old":     ActionDelete,
+		"New/Inbox":       ActionCreate,
+	}
+	got := map[string]Action{}
+	for _, op := range plan.Operations {
+		if op.Kind == KindSection {
+			got[op.Name] = op.Action
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected section ops %v, got %v", want, got)
+	}
+	for name, action := range want {
+		if got[name] != action {
+			t.Fatalf("expected %s %q, got %q", action, name, got[name])
+		}
+	}
+	if plan.Summary.Reorder != 1 {
+		t.Fatalf("expected 1 reorder, got %d", plan.Summary.Reorder)
+	}
+
+	// A section pinned by id must belong to the project it is declared under.
+	cfg.Spec.Projects[0].Sections[1].ID = strPtr("S3")
+	if _, err := BuildPlan(cfg, snap, Options{Prune: true}); err == nil || !strings.Contains(err.Error(), `belongs to project "Unmanaged"`) {
+		t.Fatalf("expected a wrong-project error, got %v", err)
+	}
+}
+
+func TestBuildPlan_RenameFromState(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{
+				{Name: "Homelab", Sections: []config.SectionSpec{{Name: "Doing"}}},
+				{Name: "Lab", Parent: strPtr("Homelab"), Sections: []config.SectionSpec{{Name: "Next"}}},
+			},
+			Labels: []config.LabelSpec{
+				{Name: "waiting-for"},
+			},
+			Tasks: []config.TaskSpec{
+				{Key: "patch", Content: "Patch servers", Project: strPtr("Homelab")},
+			},
+			Prune: config.PruneSpec{Projects: true, Sections: true, Labels: true},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	// Homelab and its Doing section were renamed in the app; Lab's Todo section and the waiting
+	// label were renamed in YAML.
+	parent := "P1"
+	snap := &Snapshot{
+		Projects: []v1.Project{
+			{ID: "P1", Name: "Home Lab"},
+			{ID: "P2", Name: "Lab", ParentID: &parent},
+		},
+		Sections: []v1.Section{
+			{ID: "S1", ProjectID: "P1", Name: "In progress", SectionOrder: 1},
+			{ID: "S2", ProjectID: "P2", Name: "Todo", SectionOrder: 1},
+		},
+		Labels: []v1.Label{{ID: "L1", Name: "waiting"}},
+		Tasks:  []v1.Task{{ID: "T1", Content: "Patch servers", Description: "HTD_KEY:patch", ProjectID: "P1"}},
+	}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	st := state.New("test")
+	st.Set("project", "Homelab", "P1")
+	st.Set("project", "Lab", "P2")
+	st.Set("section", "Homelab/Doing", "S1")
+	st.Set("section", "Lab/Todo", "S2")
+	st.Set("label", "waiting", "L1")
+
+	opsOf := func(plan *Plan) string {
+		var got []string
+		for _, op := range plan.Operations {
+			got = append(got, fmt.Sprintf("%s %s %s %v", op.Action, op.Kind, op.Name, op.Changes))
+		}
+		return strings.Join(got, "\n")
+	}
+	plan, err := BuildPlan(cfg, snap, Options{Prune: true, State: st})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := []string{
+		"update project Homelab [{name Home Lab Homelab}]",
+		"update section Homelab/Doing [{name In progress Doing}]",
+		"update section Lab/Next [{name Todo Next}]",
+		"update label waiting-for [{name waiting waiting-for}]",
+	}
+	if got := opsOf(plan); got != strings.Join(want, "\n") {
+		t.Fatalf("unexpected ops:\n%s", got)
+	}
+	if !strings.Contains(strings.Join(plan.Notes, "\n"), `treating label "waiting" -> "waiting-for" as a rename (from state)`) {
+		t.Fatalf("expected a rename note, got %v", plan.Notes)
+	}
+
+	// Two new labels for one removed label are ambiguous: create + delete, with a note.
+	cfg.Spec.Labels = append(cfg.Spec.Labels, config.LabelSpec{Name: "someday"})
+	// A new section does not pair with a removed section of another project.
+	cfg.Spec.Projects[0].Sections = append(cfg.Spec.Projects[0].Sections, config.SectionSpec{Name: "Later"})
+	cfg.Spec.Projects[1].Sections = []config.SectionSpec{}
+	plan, err = BuildPlan(cfg, snap, Options{Prune: true, State: st})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want = []string{
+		"update project Homelab [{name Home Lab Homelab}]",
+		"update section Homelab/Doing [{name In progress Doing}]",
+		"create section Homelab/Later []",
+		"delete section Lab/Todo []",
+		"create label someday []",
+		"delete label waiting []",
+		"create label waiting-for []",
+	}
+	if got := opsOf(plan); got != strings.Join(want, "\n") {
+		t.Fatalf("unexpected ops:\n%s", got)
+	}
+	if !strings.Contains(strings.Join(plan.Notes, "\n"), `cannot infer label renames: new "waiting-for", "someday" and "waiting" (id L1) removed from config are planned as create + delete`) {
+		t.Fatalf("expected a note about the ambiguous rename, got %v", plan.Notes)
+	}
+
+	// Without state, app renames are a create (+ delete when pruning) too.
+	plan, err = BuildPlan(cfg, snap, Options{Prune: true})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Summary.Create != 5 || plan.Summary.Delete != 3 {
+		t.Fatalf("expected 5 creates + 3 deletes without state, got %#v: %s", plan.Summary, opsOf(plan))
+	}
+}
+
+func TestBuildPlan_TaskReminders(t *testing.T) {
+	tp := "recurring_template"
+	due := "every day at 8:00am"
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Tasks: []config.TaskSpec{
+				{Key: "rent", Type: &tp, Content: "Pay rent", Due: config.TaskDueSpec{String: &due}, Reminders: []config.TaskReminderSpec{
+					{MinutesBefore: intPtr(15)},
+					{MinutesBefore: intPtr(60)},
+					{At: strPtr("2026-11-01T09:00:00+01:00")},
+				}},
+				{Key: "review", Type: &tp, Content: "Daily Review", Due: config.TaskDueSpec{String: &due}, Reminders: []config.TaskReminderSpec{
+					{MinutesBefore: intPtr(0)},
+				}},
+				// No reminders key: remote reminders are left alone.
+				{Key: "water", Type: &tp, Content: "Water plants", Due: config.TaskDueSpec{String: &due}},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	remoteDue := &v1.Due{String: due, IsRecurring: true}
+	snap := &Snapshot{
+		Tasks: []v1.Task{
+			{ID: "T1", Content: "Pay rent", Description: "HTD_KEY:rent", Priority: 1, Labels: []string{}, Due: remoteDue},
+			{ID: "T2", Content: "Water plants", Description: "HTD_KEY:water", Priority: 1, Labels: []string{}, Due: remoteDue},
+		},
+		Reminders: []sync.Reminder{
+			{ID: "R1", ItemID: "T1", Type: "relative", MinuteOffset: 60},
+			{ID: "R2", ItemID: "T1", Type: "relative", MinuteOffset: 30},
+			{ID: "R3", ItemID: "T1", Type: "absolute", Due: &sync.ReminderDue{Date: "2026-11-01T10:00:00+02:00"}},
+			{ID: "R4", ItemID: "T1", Type: "absolute", Due: &sync.ReminderDue{Date: "2026-12-24T18:00:00"}},
+			{ID: "R5", ItemID: "T1", Type: "location"},
+			{ID: "R6", ItemID: "T2", Type: "relative", MinuteOffset: 5},
+		},
+	}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	plan, err := BuildPlan(cfg, snap, Options{})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	var got []string
+	for _, op := range plan.Operations {
+		if op.Kind == KindReminder {
+			got = append(got, string(op.Action)+" "+op.Name+" "+op.ID)
+		}
+	}
+	// 60 and the absolute one (same instant in UTC) match; 30 becomes 15; the other absolute is
+	// deleted; the location reminder and Water plants' reminder are untouched.
+	want := []string{
+		"create Daily Review/0 min before ",
+		"update Pay rent/15 min before R2",
+		"delete Pay rent/at 2026-12-24T18:00:00 R4",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected reminder ops:\n%s", strings.Join(got, "\n"))
+	}
+
+	restricted, err := BuildPlan(cfg, snap, Options{Targets: []Target{{Kind: KindTask, Pattern: "review"}}})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if restricted.Summary.Create != 2 || restricted.Summary.TotalChanges() != 2 {
+		t.Fatalf("expected the task create and its reminder, got %+v", restricted.Operations)
+	}
+}
+
+func TestBuildPlan_TaskSections(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{{Name: "Work", Sections: []config.SectionSpec{{Name: "Now"}}}},
+			Tasks: []config.TaskSpec{
+				{Key: "triage", Content: "Triage", Project: strPtr("Work"), Section: strPtr("Now")},
+				{Key: "plan", Content: "Plan", Project: strPtr("Work"), Section: strPtr("Later")},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	snap := &Snapshot{
+		Projects: []v1.Project{{ID: "P1", Name: "Work"}},
+		Sections: []v1.Section{{ID: "S1", ProjectID: "P1", Name: "Later", SectionOrder: 2}},
+		Tasks: []v1.Task{
+			{ID: "T1", Content: "Triage", Description: "HTD_KEY:triage", ProjectID: "P1", SectionID: strPtr("S1"), Priority: 1, Labels: []string{}},
+			{ID: "T2", Content: "Plan", Description: "HTD_KEY:plan", ProjectID: "P1", Priority: 1, Labels: []string{}},
+		},
+	}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	// Triage moves to Now, which is created in the same apply; only a target on Triage keeps it.
+	plan, err := BuildPlan(cfg, snap, Options{Targets: []Target{{Kind: KindTask, Pattern: "triage"}}})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	var got []string
+	for _, op := range plan.Operations {
+		got = append(got, fmt.Sprintf("%s %s %s %v", op.Action, op.Kind, op.Name, op.Changes))
+	}
+	want := []string{
+		"create section Work/Now []",
+		"move task Triage [{section Later Now}]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected ops:\n%s", strings.Join(got, "\n"))
+	}
+
+	cfg.Spec.Tasks[1].Section = strPtr("Someday")
+	if _, err := BuildPlan(cfg, snap, Options{}); err == nil || !strings.Contains(err.Error(), `unknown section "Someday" in project "Work"`) {
+		t.Fatalf("expected unknown section error, got %v", err)
+	}
+}
+
+func TestBuildPlan_OneShotSubtasks(t *testing.T) {
+	oneShot := "one_shot"
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Tasks: []config.TaskSpec{
+				{ID: strPtr("T9"), Type: &oneShot, Content: "Old one-off", Subtasks: []config.TaskSpec{
+					{Key: "old-step", Content: "Old step"},
+				}},
+				{ID: strPtr("T1"), Content: "Weekly review", Subtasks: []config.TaskSpec{
+					{Key: "review-inbox", Content: "Inbox zero"},
+				}},
+				{Key: "certs", Type: &oneShot, Content: "Rotate certs"},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	snap := &Snapshot{
+		Projects:       []v1.Project{{ID: "P1", Name: "Inbox", InboxProject: true}},
+		Tasks:          []v1.Task{{ID: "T1", Content: "Weekly review", ProjectID: "P1", Priority: 1, Labels: []string{}}},
+		CompletedTasks: []v1.Task{{ID: "T7", Content: "Rotate certs", Description: "HTD_KEY:certs", ProjectID: "P1"}},
+	}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	// The id-pinned one-shot task and its subtask are gone; the other id-pinned task's missing
+	// subtask is still created, and the completed keyed task is not recreated.
+	plan, err := BuildPlan(cfg, snap, Options{})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	var got []string
+	for _, op := range plan.Operations {
+		got = append(got, fmt.Sprintf("%s %s %s", op.Action, op.Kind, op.Name))
+	}
+	if strings.Join(got, "\n") != "create task Inbox zero" {
+		t.Fatalf("unexpected ops:\n%s", strings.Join(got, "\n"))
+	}
+	notes := strings.Join(plan.Notes, "\n")
+	if !strings.Contains(notes, `one-shot task "Old one-off" was created before`) || !strings.Contains(notes, `one-shot task "Rotate certs" was completed`) {
+		t.Fatalf("unexpected notes: %v", plan.Notes)
+	}
+}
+
 func strPtr(s string) *string { return &s }
 func boolPtr(b bool) *bool    { return &b }
 func intPtr(i int) *int       { return &i }
+
+func TestUnmanaged(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{{Name: "Homelab"}, {Name: "Pinned", ID: strPtr("P3")}},
+			Labels:   []config.LabelSpec{{Name: "waiting"}},
+			Tasks: []config.TaskSpec{
+				{Key: "patch", Content: "Patch servers"},
+				{Key: "backup", Content: "Check backups", ID: strPtr("T2")},
+			},
+		},
+	}
+	snap := &Snapshot{
+		Projects: []v1.Project{
+			{ID: "P0", Name: "Inbox", InboxProject: true},
+			{ID: "P1", Name: "Home Lab"},
+			{ID: "P2", Name: "Garden"},
+			{ID: "P3", Name: "Renamed in app"},
+		},
+		Labels:  []v1.Label{{ID: "L1", Name: "waiting"}, {ID: "L2", Name: "someday"}},
+		Filters: []sync.Filter{{ID: "F1", Name: "Today"}},
+		Tasks: []v1.Task{
+			{ID: "T1", Content: "Patch servers", Description: "HTD_KEY:patch"},
+			{ID: "T2", Content: "Check backups"},
+			{ID: "T3", Content: "Buy milk"},
+			{ID: "T4", Content: "Old", Description: "HTD_KEY:old"},
+		},
+	}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	st := state.New("test")
+	st.Set("project", "Homelab", "P1")
+
+	got := Unmanaged(cfg, snap, st)
+	want := map[Kind][]string{
+		KindProject: {"P2"},
+		KindLabel:   {"L2"},
+		KindFilter:  {"F1"},
+		KindTask:    {"T3", "T4"},
+	}
+	for kind, ids := range want {
+		if len(got[kind]) != len(ids) {
+			t.Fatalf("%s: expected %v, got %v", kind, ids, got[kind])
+		}
+		for _, id := range ids {
+			if !got[kind][id] {
+				t.Fatalf("%s: expected %v, got %v", kind, ids, got[kind])
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package reconcile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+)
+
+// PlanFileVersion is the on-disk format version written by `htd plan --out`.
+const PlanFileVersion = 1
+
+// PlanFile is a plan saved to disk so it can be reviewed and applied later exactly as computed.
+// It records fingerprints of the config and of the remote snapshot the plan was computed against;
+// applying refuses to proceed if either has changed.
+type PlanFile struct {
+	Version             int       `json:"version"`
+	CreatedAt           time.Time `json:"created_at"`
+	Config              string    `json:"config"`
+	ConfigFingerprint   string    `json:"config_fingerprint"`
+	SnapshotFingerprint string    `json:"snapshot_fingerprint"`
+	Prune               bool      `json:"prune"`
+	Targets             []Target  `json:"targets,omitempty"`
+	Plan                savedPlan `json:"plan"`
+}
+
+type savedPlan struct {
+	Operations []savedOperation `json:"operations"`
+	Summary    Summary          `json:"summary"`
+	Notes      []string         `json:"notes,omitempty"`
+}
+
+// savedOperation exposes the internal payloads that Operation hides from JSON output.
+type savedOperation struct {
+	Operation
+	ProjectPayload  *ProjectPayload  `json:"project_payload,omitempty"`
+	SectionPayload  *SectionPayload  `json:"section_payload,omitempty"`
+	LabelPayload    *LabelPayload    `json:"label_payload,omitempty"`
+	FilterPayload   *FilterPayload   `json:"filter_payload,omitempty"`
+	TaskPayload     *TaskPayload     `json:"task_payload,omitempty"`
+	ReminderPayload *ReminderPayload `json:"reminder_payload,omitempty"`
+}
+
+// NewPlanFile captures plan together with the fingerprints of cfg and snap.
+func NewPlanFile(cfg *config.TodoistConfig, snap *Snapshot, plan *Plan, opts Options) (*PlanFile, error) {
+	if cfg == nil || snap == nil || plan == nil {
+		return nil, fmt.Errorf("cfg/snapshot/plan must be non-nil")
+	}
+	cfp, err := configFingerprint(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sfp, err := snap.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	pf := &PlanFile{
+		Version:             PlanFileVersion,
+		CreatedAt:           time.Now().UTC(),
+		Config:              cfg.Metadata.Name,
+		ConfigFingerprint:   cfp,
+		SnapshotFingerprint: sfp,
+		Prune:               opts.Prune,
+		Targets:             opts.Targets,
+		Plan: savedPlan{
+			Summary: plan.Summary,
+			Notes:   plan.Notes,
+		},
+	}
+	for _, op := range plan.Operations {
+		pf.Plan.Operations = append(pf.Plan.Operations, savedOperation{
+			Operation:       op,
+			ProjectPayload:  op.ProjectPayload,
+			SectionPayload:  op.SectionPayload,
+			LabelPayload:    op.LabelPayload,
+			FilterPayload:   op.FilterPayload,
+			TaskPayload:     op.TaskPayload,
+			ReminderPayload: op.ReminderPayload,
+		})
+	}
+	return pf, nil
+}
+
+// ToPlan returns the saved plan with payloads restored.
+func (pf *PlanFile) ToPlan() *Plan {
+	plan := &Plan{Summary: pf.Plan.Summary, Notes: pf.Plan.Notes}
+	for _, so := range pf.Plan.Operations {
+		op := so.Operation
+		op.ProjectPayload = so.ProjectPayload
+		op.SectionPayload = so.SectionPayload
+		op.LabelPayload = so.LabelPayload
+		op.FilterPayload = so.FilterPayload
+		op.TaskPayload = so.TaskPayload
+		op.ReminderPayload = so.ReminderPayload
+		plan.Operations = append(plan.Operations, op)
+	}
+	return plan
+}
+
+// Verify checks that cfg and snap are the ones the plan was computed against.
+func (pf *PlanFile) Verify(cfg *config.TodoistConfig, snap *Snapshot) error {
+	if cfg.Metadata.Name != pf.Config {
+		return fmt.Errorf("plan was made for config %q, not %q", pf.Config, cfg.Metadata.Name)
+	}
+	cfp, err := configFingerprint(cfg)
+	if err != nil {
+		return err
+	}
+	if cfp != pf.ConfigFingerprint {
+		return fmt.Errorf("config has changed since the plan was made (%s); re-run htd plan", pf.CreatedAt.Format(time.RFC3339))
+	}
+	sfp, err := snap.Fingerprint()
+	if err != nil {
+		return err
+	}
+	if sfp != pf.SnapshotFingerprint {
+		return fmt.Errorf("remote state has drifted since the plan was made (%s); re-run htd plan", pf.CreatedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// WritePlanFile writes pf as indented JSON.
+func WritePlanFile(path string, pf *PlanFile) error {
+	b, err := json.MarshalIndent(pf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal plan: %w", err)
+	}
+	b = append(b, '\n')
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("write plan %q: %w", path, err)
+	}
+	return nil
+}
+
+// ReadPlanFile loads a plan written by WritePlanFile.
+func ReadPlanFile(path string) (*PlanFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read plan %q: %w", path, err)
+	}
+	var pf PlanFile
+	if err := json.Unmarshal(b, &pf); err != nil {
+		return nil, fmt.Errorf("parse plan %q: %w", path, err)
+	}
+	if pf.Version != PlanFileVersion {
+		return nil, fmt.Errorf("plan %q has unsupported version %d (expected %d)", path, pf.Version, PlanFileVersion)
+	}
+	return &pf, nil
+}
+
+func configFingerprint(cfg *config.TodoistConfig) (string, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("fingerprint config: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
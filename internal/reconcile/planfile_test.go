@@ -0,0 +1,88 @@
+package reconcile
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+func TestPlanFile_RoundTripAndDrift(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{{Name: "Work", Color: strPtr("red")}},
+			Labels:   []config.LabelSpec{{Name: "waiting"}},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	snap := &Snapshot{
+		Projects: []v1.Project{{ID: "P1", Name: "Work", Color: "blue"}},
+		Tasks:    []v1.Task{{ID: "T9", Content: "unmanaged"}},
+	}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	plan, err := BuildPlan(cfg, snap, Options{})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+
+	pf, err := NewPlanFile(cfg, snap, plan, Options{Prune: true})
+	if err != nil {
+		t.Fatalf("NewPlanFile: %v", err)
+	}
+	p := filepath.Join(t.TempDir(), "plan.json")
+	if err := WritePlanFile(p, pf); err != nil {
+		t.Fatalf("WritePlanFile: %v", err)
+	}
+	got, err := ReadPlanFile(p)
+	if err != nil {
+		t.Fatalf("ReadPlanFile: %v", err)
+	}
+	if !got.Prune {
+		t.Fatalf("expected prune to round-trip")
+	}
+	restored := got.ToPlan()
+	if restored.Summary != plan.Summary || len(restored.Operations) != len(plan.Operations) {
+		t.Fatalf("expected %#v, got %#v", plan, restored)
+	}
+	for _, op := range restored.Operations {
+		switch op.Kind {
+		case KindProject:
+			if op.ProjectPayload == nil || op.ProjectPayload.Color == nil || *op.ProjectPayload.Color != "red" {
+				t.Fatalf("expected project payload to round-trip, got %#v", op.ProjectPayload)
+			}
+		case KindLabel:
+			if op.LabelPayload == nil || op.LabelPayload.DesiredName != "waiting" {
+				t.Fatalf("expected label payload to round-trip, got %#v", op.LabelPayload)
+			}
+		}
+	}
+
+	if err := got.Verify(cfg, snap); err != nil {
+		t.Fatalf("Verify on unchanged snapshot: %v", err)
+	}
+
+	// Unmanaged task activity is not drift.
+	snap.Tasks = append(snap.Tasks, v1.Task{ID: "T10", Content: "another"})
+	if err := got.Verify(cfg, snap); err != nil {
+		t.Fatalf("Verify after unmanaged task change: %v", err)
+	}
+
+	snap.Projects[0].Color = "green"
+	if err := got.Verify(cfg, snap); err == nil {
+		t.Fatalf("expected drift error after remote project change")
+	}
+	snap.Projects[0].Color = "blue"
+
+	cfg.Spec.Labels[0].Name = "blocked"
+	if err := got.Verify(cfg, snap); err == nil {
+		t.Fatalf("expected error after config change")
+	}
+}
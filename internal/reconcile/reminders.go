@@ -0,0 +1,188 @@
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// reminder is a relative or absolute reminder in the form Todoist stores it.
+type reminder struct {
+	Type         string
+	MinuteOffset int
+	Date         string
+}
+
+func (r reminder) String() string {
+	if r.Type == "relative" {
+		return fmt.Sprintf("%d min before", r.MinuteOffset)
+	}
+	return "at " + r.Date
+}
+
+func (r reminder) less(o reminder) bool {
+	if r.Type != o.Type {
+		return r.Type < o.Type
+	}
+	if r.MinuteOffset != o.MinuteOffset {
+		return r.MinuteOffset < o.MinuteOffset
+	}
+	return r.Date < o.Date
+}
+
+func desiredReminder(r config.TaskReminderSpec) (reminder, error) {
+	if r.MinutesBefore != nil {
+		return reminder{Type: "relative", MinuteOffset: *r.MinutesBefore}, nil
+	}
+	date, err := r.AtDate()
+	if err != nil {
+		return reminder{}, err
+	}
+	return reminder{Type: "absolute", Date: date}, nil
+}
+
+func remoteReminder(r sync.Reminder) reminder {
+	out := reminder{Type: r.Type, MinuteOffset: r.MinuteOffset}
+	if r.Due != nil {
+		out.Date = r.Due.Date
+		if t, err := time.Parse(time.RFC3339, r.Due.Date); err == nil {
+			out.Date = t.UTC().Format("2006-01-02T15:04:05Z")
+		}
+	}
+	return out
+}
+
+// ReminderName is the operation name of a reminder declared on a task.
+func ReminderName(taskName string, r config.TaskReminderSpec) string {
+	d, err := desiredReminder(r)
+	if err != nil {
+		return taskName + "/?"
+	}
+	return reminderOpName(taskName, d)
+}
+
+func reminderOpName(taskName string, r reminder) string {
+	return taskName + "/" + r.String()
+}
+
+func (r reminder) payload(taskName, taskKey, taskID string) *ReminderPayload {
+	return &ReminderPayload{TaskName: taskName, TaskKey: taskKey, TaskID: taskID, Type: r.Type, MinuteOffset: r.MinuteOffset, Date: r.Date}
+}
+
+// planTaskReminders diffs the reminders of a task that declares them against remote (nil when the
+// task is created in this plan). Reminders have no identity: equal ones are left alone, the rest are
+// paired by type into updates, and what remains is added or deleted. Location reminders, which
+// cannot be declared, are never touched.
+func planTaskReminders(plan *Plan, t config.TaskSpec, remote *v1.Task, snap *Snapshot) error {
+	if t.Reminders == nil {
+		return nil
+	}
+	var desired []reminder
+	for _, r := range t.Reminders {
+		d, err := desiredReminder(r)
+		if err != nil {
+			return fmt.Errorf("task %q: %w", t.Content, err)
+		}
+		desired = append(desired, d)
+	}
+	taskID := ""
+	var current []sync.Reminder
+	if remote != nil {
+		taskID = remote.ID
+		for _, r := range snap.RemindersForTask(remote.ID) {
+			if r.Type == "relative" || r.Type == "absolute" {
+				current = append(current, r)
+			}
+		}
+	}
+
+	matched := make([]bool, len(current))
+	var missing []reminder
+	for _, d := range desired {
+		found := false
+		for i, r := range current {
+			if !matched[i] && remoteReminder(r) == d {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, d)
+		}
+	}
+	var extra []sync.Reminder
+	for i, r := range current {
+		if !matched[i] {
+			extra = append(extra, r)
+		}
+	}
+	sort.SliceStable(missing, func(i, j int) bool { return missing[i].less(missing[j]) })
+	sort.SliceStable(extra, func(i, j int) bool { return remoteReminder(extra[i]).less(remoteReminder(extra[j])) })
+
+	for _, d := range missing {
+		i := -1
+		for j, r := range extra {
+			if r.Type == d.Type {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			plan.Operations = append(plan.Operations, Operation{
+				Kind:            KindReminder,
+				Action:          ActionCreate,
+				Name:            reminderOpName(t.Content, d),
+				ReminderPayload: d.payload(t.Content, t.Key, taskID),
+			})
+			plan.Summary.Create++
+			continue
+		}
+		from := remoteReminder(extra[i])
+		plan.Operations = append(plan.Operations, Operation{
+			Kind:            KindReminder,
+			Action:          ActionUpdate,
+			Name:            reminderOpName(t.Content, d),
+			ID:              extra[i].ID,
+			Changes:         []Change{{Field: "reminder", From: from.String(), To: d.String()}},
+			ReminderPayload: d.payload(t.Content, t.Key, taskID),
+		})
+		plan.Summary.Update++
+		extra = append(extra[:i], extra[i+1:]...)
+	}
+	for _, r := range extra {
+		from := remoteReminder(r)
+		plan.Operations = append(plan.Operations, Operation{
+			Kind:            KindReminder,
+			Action:          ActionDelete,
+			Name:            reminderOpName(t.Content, from),
+			ID:              r.ID,
+			ReminderPayload: from.payload(t.Content, t.Key, taskID),
+		})
+		plan.Summary.Delete++
+	}
+	return nil
+}
+
+// reminderArgs are the reminder_add/reminder_update arguments for p (without item_id or id).
+func reminderArgs(p *ReminderPayload) map[string]any {
+	args := map[string]any{"type": p.Type}
+	if p.Type == "relative" {
+		args["minute_offset"] = p.MinuteOffset
+	} else {
+		args["due"] = map[string]any{"date": p.Date}
+	}
+	return args
+}
+
+// reminderRestorePayload describes r as it was, for updating it back or recreating it.
+func reminderRestorePayload(snap *Snapshot, r *sync.Reminder) *ReminderPayload {
+	taskName := r.ItemID
+	if t, ok := snap.TaskByID(r.ItemID); ok {
+		taskName = t.Content
+	}
+	return remoteReminder(*r).payload(taskName, "", r.ItemID)
+}
@@ -2,29 +2,43 @@ package reconcile
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
 	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
 )
 
 type Snapshot struct {
-	Projects []v1.Project
-	Labels   []v1.Label
-	Filters  []sync.Filter
-	Tasks    []v1.Task
-
-	projectByName   map[string][]v1.Project
-	projectByID     map[string]v1.Project
-	labelByName     map[string][]v1.Label
-	labelByID       map[string]v1.Label
-	filterByName    map[string][]sync.Filter
-	filterByID      map[string]sync.Filter
-	taskByID        map[string]v1.Task
-	taskByKey       map[string]v1.Task
-	projectNameByID map[string]string
+	Projects  []v1.Project
+	Sections  []v1.Section
+	Labels    []v1.Label
+	Filters   []sync.Filter
+	Tasks     []v1.Task
+	Reminders []sync.Reminder
+	// CompletedTasks are the recently completed tasks that carry an HTD_KEY marker.
+	CompletedTasks []v1.Task
+
+	projectByName     map[string][]v1.Project
+	projectByID       map[string]v1.Project
+	sectionsByProject map[string][]v1.Section
+	sectionByID       map[string]v1.Section
+	labelByName       map[string][]v1.Label
+	labelByID         map[string]v1.Label
+	filterByName      map[string][]sync.Filter
+	filterByID        map[string]sync.Filter
+	taskByID          map[string]v1.Task
+	taskByKey         map[string]v1.Task
+	completedByKey    map[string]v1.Task
+	projectNameByID   map[string]string
+	reminderByID      map[string]sync.Reminder
+	remindersByTask   map[string][]sync.Reminder
 }
 
 func FetchSnapshot(ctx context.Context, v1c *v1.Client, syncc *sync.Client) (*Snapshot, error) {
@@ -32,6 +46,10 @@ func FetchSnapshot(ctx context.Context, v1c *v1.Client, syncc *sync.Client) (*Sn
 	if err != nil {
 		return nil, fmt.Errorf("list projects: %w", err)
 	}
+	sections, err := v1c.ListSections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list sections: %w", err)
+	}
 	labels, err := v1c.ListLabels(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("list labels: %w", err)
@@ -40,76 +58,178 @@ func FetchSnapshot(ctx context.Context, v1c *v1.Client, syncc *sync.Client) (*Sn
 	if err != nil {
 		return nil, fmt.Errorf("list tasks: %w", err)
 	}
-	filtersResp, err := syncc.Read(ctx, []string{"filters"})
+	// Filters and reminders have no v1 list endpoint.
+	syncResp, err := syncc.Read(ctx, []string{"filters", "reminders"})
 	if err != nil {
-		return nil, fmt.Errorf("sync read filters: %w", err)
+		return nil, fmt.Errorf("sync read filters and reminders: %w", err)
 	}
 	var filters []sync.Filter
-	for _, f := range filtersResp.Filters {
+	for _, f := range syncResp.Filters {
 		if f.IsDeleted {
 			continue
 		}
 		filters = append(filters, f)
 	}
+	var reminders []sync.Reminder
+	for _, r := range syncResp.Reminders {
+		if r.IsDeleted {
+			continue
+		}
+		reminders = append(reminders, r)
+	}
 
 	s := &Snapshot{
-		Projects:        projects,
-		Labels:          labels,
-		Filters:         filters,
-		Tasks:           tasks,
-		projectByName:   map[string][]v1.Project{},
-		projectByID:     map[string]v1.Project{},
-		labelByName:     map[string][]v1.Label{},
-		labelByID:       map[string]v1.Label{},
-		filterByName:    map[string][]sync.Filter{},
-		filterByID:      map[string]sync.Filter{},
-		taskByID:        map[string]v1.Task{},
-		taskByKey:       map[string]v1.Task{},
-		projectNameByID: map[string]string{},
-	}
-
-	for _, p := range projects {
+		Projects:  projects,
+		Sections:  sections,
+		Labels:    labels,
+		Filters:   filters,
+		Tasks:     tasks,
+		Reminders: reminders,
+	}
+	if err := s.index(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// completedLookback is how far back FetchCompletedTasks looks; Todoist lists at most three months
+// of completed tasks per request.
+const completedLookback = 12 * 7 * 24 * time.Hour
+
+// FetchCompletedTasks adds the managed tasks completed in the last twelve weeks to s, which the
+// planner needs to tell a completed one_shot task from one never created. It makes no request
+// unless cfg declares a one_shot task, so snapshots stay as cheap as before for other configs.
+func FetchCompletedTasks(ctx context.Context, v1c *v1.Client, cfg *config.TodoistConfig, s *Snapshot) error {
+	if !hasOneShotTasks(cfg) {
+		return nil
+	}
+	completed, err := listCompletedManagedTasks(ctx, v1c)
+	if err != nil {
+		return err
+	}
+	s.CompletedTasks = completed
+	return s.index()
+}
+
+func hasOneShotTasks(cfg *config.TodoistConfig) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, t := range cfg.Spec.AllTasks() {
+		if t.Type != nil && *t.Type == "one_shot" {
+			return true
+		}
+	}
+	return false
+}
+
+func listCompletedManagedTasks(ctx context.Context, v1c *v1.Client) ([]v1.Task, error) {
+	now := time.Now()
+	all, err := v1c.ListCompletedTasks(ctx, now.Add(-completedLookback), now)
+	if err != nil {
+		return nil, fmt.Errorf("list completed tasks: %w", err)
+	}
+	var managed []v1.Task
+	for _, t := range all {
+		if _, ok := ManagedTaskKey(t.Description); ok {
+			managed = append(managed, t)
+		}
+	}
+	return managed, nil
+}
+
+// index (re)builds the lookup maps from the exported slices and sorts them for stable output.
+func (s *Snapshot) index() error {
+	s.projectByName = map[string][]v1.Project{}
+	s.projectByID = map[string]v1.Project{}
+	s.sectionsByProject = map[string][]v1.Section{}
+	s.sectionByID = map[string]v1.Section{}
+	s.labelByName = map[string][]v1.Label{}
+	s.labelByID = map[string]v1.Label{}
+	s.filterByName = map[string][]sync.Filter{}
+	s.filterByID = map[string]sync.Filter{}
+	s.taskByID = map[string]v1.Task{}
+	s.taskByKey = map[string]v1.Task{}
+	s.completedByKey = map[string]v1.Task{}
+	s.projectNameByID = map[string]string{}
+	s.reminderByID = map[string]sync.Reminder{}
+	s.remindersByTask = map[string][]sync.Reminder{}
+
+	for _, p := range s.Projects {
 		if _, ok := s.projectByID[p.ID]; ok {
-			return nil, fmt.Errorf("remote has duplicate project id %q", p.ID)
+			return fmt.Errorf("remote has duplicate project id %q", p.ID)
 		}
 		s.projectByID[p.ID] = p
 		s.projectNameByID[p.ID] = p.Name
 		s.projectByName[p.Name] = append(s.projectByName[p.Name], p)
 	}
-	for _, l := range labels {
+	for _, sec := range s.Sections {
+		if _, ok := s.sectionByID[sec.ID]; ok {
+			return fmt.Errorf("remote has duplicate section id %q", sec.ID)
+		}
+		s.sectionByID[sec.ID] = sec
+		s.sectionsByProject[sec.ProjectID] = append(s.sectionsByProject[sec.ProjectID], sec)
+	}
+	for _, l := range s.Labels {
 		if _, ok := s.labelByID[l.ID]; ok {
-			return nil, fmt.Errorf("remote has duplicate label id %q", l.ID)
+			return fmt.Errorf("remote has duplicate label id %q", l.ID)
 		}
 		s.labelByID[l.ID] = l
 		s.labelByName[l.Name] = append(s.labelByName[l.Name], l)
 	}
-	for _, f := range filters {
+	for _, f := range s.Filters {
 		if _, ok := s.filterByID[f.ID]; ok {
-			return nil, fmt.Errorf("remote has duplicate filter id %q", f.ID)
+			return fmt.Errorf("remote has duplicate filter id %q", f.ID)
 		}
 		s.filterByID[f.ID] = f
 		s.filterByName[f.Name] = append(s.filterByName[f.Name], f)
 	}
-	for _, t := range tasks {
+	for _, t := range s.Tasks {
 		if _, ok := s.taskByID[t.ID]; ok {
-			return nil, fmt.Errorf("remote has duplicate task id %q", t.ID)
+			return fmt.Errorf("remote has duplicate task id %q", t.ID)
 		}
 		s.taskByID[t.ID] = t
-		if key, ok := managedTaskKey(t.Description); ok {
+		if key, ok := ManagedTaskKey(t.Description); ok {
 			if _, exists := s.taskByKey[key]; exists {
-				return nil, fmt.Errorf("remote has duplicate managed task key %q", key)
+				return fmt.Errorf("remote has duplicate managed task key %q", key)
 			}
 			s.taskByKey[key] = t
 		}
 	}
+	for _, t := range s.CompletedTasks {
+		if key, ok := ManagedTaskKey(t.Description); ok {
+			s.completedByKey[key] = t
+		}
+	}
+	// Reminders of tasks outside the snapshot (e.g. completed ones) are dropped.
+	reminders := s.Reminders[:0]
+	for _, r := range s.Reminders {
+		if _, ok := s.taskByID[r.ItemID]; !ok {
+			continue
+		}
+		if _, ok := s.reminderByID[r.ID]; ok {
+			return fmt.Errorf("remote has duplicate reminder id %q", r.ID)
+		}
+		s.reminderByID[r.ID] = r
+		s.remindersByTask[r.ItemID] = append(s.remindersByTask[r.ItemID], r)
+		reminders = append(reminders, r)
+	}
+	s.Reminders = reminders
 
 	// Ensure stable snapshot ordering for debugging/JSON output.
 	sort.Slice(s.Projects, func(i, j int) bool { return s.Projects[i].Name < s.Projects[j].Name })
+	sort.Slice(s.Sections, func(i, j int) bool {
+		if s.Sections[i].ProjectID != s.Sections[j].ProjectID {
+			return s.Sections[i].ProjectID < s.Sections[j].ProjectID
+		}
+		return s.Sections[i].SectionOrder < s.Sections[j].SectionOrder
+	})
 	sort.Slice(s.Labels, func(i, j int) bool { return s.Labels[i].Name < s.Labels[j].Name })
 	sort.Slice(s.Filters, func(i, j int) bool { return s.Filters[i].Name < s.Filters[j].Name })
 	sort.Slice(s.Tasks, func(i, j int) bool { return s.Tasks[i].Content < s.Tasks[j].Content })
+	sort.Slice(s.Reminders, func(i, j int) bool { return s.Reminders[i].ID < s.Reminders[j].ID })
 
-	return s, nil
+	return nil
 }
 
 func (s *Snapshot) ProjectByName(name string) (v1.Project, bool, error) {
@@ -134,6 +254,80 @@ func (s *Snapshot) ProjectByID(id string) (v1.Project, bool) {
 	return p, ok
 }
 
+// Fingerprint returns a stable hash of the remote state htd reconciles: projects, sections,
+// labels, filters and HTD-managed tasks with their reminders. Unmanaged tasks are excluded so that
+// day-to-day task activity does not invalidate a saved plan.
+func (s *Snapshot) Fingerprint() (string, error) {
+	projects := append([]v1.Project(nil), s.Projects...)
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	sections := append([]v1.Section(nil), s.Sections...)
+	sort.Slice(sections, func(i, j int) bool { return sections[i].ID < sections[j].ID })
+	labels := append([]v1.Label(nil), s.Labels...)
+	sort.Slice(labels, func(i, j int) bool { return labels[i].ID < labels[j].ID })
+	filters := append([]sync.Filter(nil), s.Filters...)
+	sort.Slice(filters, func(i, j int) bool { return filters[i].ID < filters[j].ID })
+	var tasks []v1.Task
+	var reminders []sync.Reminder
+	for _, t := range s.Tasks {
+		if _, ok := ManagedTaskKey(t.Description); ok {
+			tasks = append(tasks, t)
+			reminders = append(reminders, s.remindersByTask[t.ID]...)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	sort.Slice(reminders, func(i, j int) bool { return reminders[i].ID < reminders[j].ID })
+
+	b, err := json.Marshal(struct {
+		Projects []v1.Project  `json:"projects"`
+		Sections []v1.Section  `json:"sections"`
+		Labels   []v1.Label    `json:"labels"`
+		Filters  []sync.Filter `json:"filters"`
+		Tasks    []v1.Task     `json:"tasks"`
+		// Omitted when empty so fingerprints of plans saved before reminders stay valid.
+		Reminders []sync.Reminder `json:"reminders,omitempty"`
+	}{projects, sections, labels, filters, tasks, reminders})
+	if err != nil {
+		return "", fmt.Errorf("fingerprint snapshot: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SectionByName looks up a section by name within a project.
+func (s *Snapshot) SectionByName(projectID, name string) (v1.Section, bool, error) {
+	var matches []v1.Section
+	for _, sec := range s.sectionsByProject[projectID] {
+		if sec.Name == name {
+			matches = append(matches, sec)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return v1.Section{}, false, nil
+	case 1:
+		return matches[0], true, nil
+	default:
+		ids := make([]string, 0, len(matches))
+		for _, sec := range matches {
+			ids = append(ids, sec.ID)
+		}
+		sort.Strings(ids)
+		return v1.Section{}, false, fmt.Errorf("remote has %d sections named %q in project %q (ids: %s); cannot reconcile by name", len(matches), name, s.projectNameByID[projectID], strings.Join(ids, ", "))
+	}
+}
+
+func (s *Snapshot) SectionByID(id string) (v1.Section, bool) {
+	sec, ok := s.sectionByID[id]
+	return sec, ok
+}
+
+// SectionsInProject returns the remote sections of a project in section order.
+func (s *Snapshot) SectionsInProject(projectID string) []v1.Section {
+	out := append([]v1.Section(nil), s.sectionsByProject[projectID]...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].SectionOrder < out[j].SectionOrder })
+	return out
+}
+
 func (s *Snapshot) LabelByName(name string) (v1.Label, bool, error) {
 	ls := s.labelByName[name]
 	switch len(ls) {
@@ -188,12 +382,29 @@ func (s *Snapshot) TaskByKey(key string) (v1.Task, bool) {
 	return t, ok
 }
 
+// CompletedTaskByKey returns a recently completed task with the HTD_KEY marker key.
+func (s *Snapshot) CompletedTaskByKey(key string) (v1.Task, bool) {
+	t, ok := s.completedByKey[key]
+	return t, ok
+}
+
+func (s *Snapshot) ReminderByID(id string) (sync.Reminder, bool) {
+	r, ok := s.reminderByID[id]
+	return r, ok
+}
+
+// RemindersForTask returns the reminders of a task, in snapshot (index) order.
+func (s *Snapshot) RemindersForTask(taskID string) []sync.Reminder {
+	return s.remindersByTask[taskID]
+}
+
 func (s *Snapshot) ProjectNameByID(id string) (string, bool) {
 	name, ok := s.projectNameByID[id]
 	return name, ok
 }
 
-func managedTaskKey(description string) (string, bool) {
+// ManagedTaskKey returns the key from a task description's HTD_KEY: line, if any.
+func ManagedTaskKey(description string) (string, bool) {
 	for _, ln := range strings.Split(description, "\n") {
 		ln = strings.TrimSpace(ln)
 		if strings.HasPrefix(ln, managedTaskKeyPrefix) {
@@ -0,0 +1,88 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// FetchSnapshotSync reads projects, sections, labels, items, filters and reminders in a single
+// full /sync read instead of the paginated v1 list calls FetchSnapshot makes.
+func FetchSnapshotSync(ctx context.Context, syncc *sync.Client) (*Snapshot, error) {
+	resp, err := syncc.Read(ctx, sync.SnapshotResourceTypes)
+	if err != nil {
+		return nil, fmt.Errorf("sync read: %w", err)
+	}
+	cache := sync.NewCache("")
+	cache.Merge(resp)
+	return SnapshotFromCache(cache)
+}
+
+// FetchSnapshotCached brings cache up to date with a single (incremental when the cache has a
+// sync_token) /sync read and builds the snapshot from it. Callers persist the cache afterwards.
+func FetchSnapshotCached(ctx context.Context, syncc *sync.Client, cache *sync.Cache) (*Snapshot, error) {
+	if err := syncc.Refresh(ctx, cache); err != nil {
+		return nil, fmt.Errorf("sync read: %w", err)
+	}
+	return SnapshotFromCache(cache)
+}
+
+// SnapshotFromCache maps /sync resources onto the REST shapes the planner works with.
+func SnapshotFromCache(cache *sync.Cache) (*Snapshot, error) {
+	s := &Snapshot{}
+	for _, p := range cache.SortedProjects() {
+		s.Projects = append(s.Projects, v1.Project{
+			ID:           p.ID,
+			Name:         p.Name,
+			Color:        p.Color,
+			IsFavorite:   p.IsFavorite,
+			ViewStyle:    p.ViewStyle,
+			ParentID:     p.ParentID,
+			InboxProject: p.InboxProject,
+		})
+	}
+	for _, sec := range cache.SortedSections() {
+		s.Sections = append(s.Sections, v1.Section{
+			ID:           sec.ID,
+			ProjectID:    sec.ProjectID,
+			Name:         sec.Name,
+			SectionOrder: sec.SectionOrder,
+		})
+	}
+	for _, l := range cache.SortedLabels() {
+		s.Labels = append(s.Labels, v1.Label{
+			ID:         l.ID,
+			Name:       l.Name,
+			Color:      l.Color,
+			IsFavorite: l.IsFavorite,
+		})
+	}
+	for _, it := range cache.SortedItems() {
+		t := v1.Task{
+			ID:          it.ID,
+			Content:     it.Content,
+			Description: it.Description,
+			ProjectID:   it.ProjectID,
+			SectionID:   it.SectionID,
+			ParentID:    it.ParentID,
+			ChildOrder:  it.ChildOrder,
+			Labels:      it.Labels,
+			Priority:    it.Priority,
+		}
+		if t.Labels == nil {
+			t.Labels = []string{}
+		}
+		if it.Due != nil {
+			t.Due = &v1.Due{String: it.Due.String, IsRecurring: it.Due.IsRecurring, Date: it.Due.Date, Timezone: it.Due.Timezone, Lang: it.Due.Lang}
+		}
+		s.Tasks = append(s.Tasks, t)
+	}
+	s.Filters = cache.SortedFilters()
+	s.Reminders = cache.SortedReminders()
+	if err := s.index(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,115 @@
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+)
+
+// tasksParentFirst orders task creates so that every subtask follows the parent it is created
+// under, keeping the order of ops otherwise.
+func tasksParentFirst(ops []Operation) []Operation {
+	byKey := map[string]Operation{}
+	for _, op := range ops {
+		if op.TaskPayload != nil && op.TaskPayload.Key != "" {
+			byKey[op.TaskPayload.Key] = op
+		}
+	}
+	depth := func(op Operation) int {
+		d := 0
+		for op.TaskPayload != nil && op.TaskPayload.ParentID == nil && op.TaskPayload.ParentKey != "" {
+			parent, ok := byKey[op.TaskPayload.ParentKey]
+			if !ok || d > len(ops) {
+				break
+			}
+			op = parent
+			d++
+		}
+		return d
+	}
+	out := append([]Operation(nil), ops...)
+	sort.SliceStable(out, func(i, j int) bool { return depth(out[i]) < depth(out[j]) })
+	return out
+}
+
+// taskParentID returns the (real or temp) ID of the parent a subtask is created or moved under, or
+// "" for a top-level task. createdIDs holds the IDs of tasks created in this apply by key.
+func taskParentID(op Operation, createdIDs map[string]string) (string, error) {
+	payload := op.TaskPayload
+	switch {
+	case payload.ParentID != nil:
+		return *payload.ParentID, nil
+	case payload.ParentKey != "":
+		id, ok := createdIDs[payload.ParentKey]
+		if !ok {
+			return "", fmt.Errorf("task %q: parent task %q not found (create ordering bug)", op.Name, payload.ParentKey)
+		}
+		return id, nil
+	}
+	return "", nil
+}
+
+// taskMoveArgs are the item_move arguments for a task move: under its parent, into its section, or
+// to the root of its project when it has neither (e.g. when undoing a move). createdTasks and
+// createdSections hold the IDs of tasks (by key) and sections (by op name) created in this apply.
+func taskMoveArgs(op Operation, createdTasks, createdSections map[string]string) (map[string]any, error) {
+	parentID, err := taskParentID(op, createdTasks)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != "" {
+		return map[string]any{"id": op.ID, "parent_id": parentID}, nil
+	}
+	sectionID, err := taskSectionID(op, createdSections)
+	if err != nil {
+		return nil, err
+	}
+	if sectionID != "" {
+		return map[string]any{"id": op.ID, "section_id": sectionID}, nil
+	}
+	if op.TaskPayload.ProjectID == nil {
+		return nil, fmt.Errorf("move task %q: no parent or project to move it to", op.Name)
+	}
+	return map[string]any{"id": op.ID, "project_id": *op.TaskPayload.ProjectID}, nil
+}
+
+// taskReorderArgs are the item_reorder arguments for one task; one command per task keeps the
+// result per operation.
+func taskReorderArgs(op Operation) map[string]any {
+	return map[string]any{"items": []map[string]any{{"id": op.ID, "child_order": op.TaskPayload.Order}}}
+}
+
+// dropSubtaskDeletes leaves out deletes of tasks whose parent (or an ancestor) is deleted too:
+// Todoist deletes subtasks with their parent, so a separate delete would fail.
+func dropSubtaskDeletes(plan *Plan, snap *Snapshot) {
+	deleted := map[string]bool{}
+	for _, op := range plan.Operations {
+		if op.Kind == KindTask && op.Action == ActionDelete {
+			deleted[op.ID] = true
+		}
+	}
+	ops := plan.Operations[:0]
+	for _, op := range plan.Operations {
+		if op.Kind == KindTask && op.Action == ActionDelete && hasDeletedAncestor(snap, op.ID, deleted) {
+			plan.Summary.Delete--
+			plan.Notes = append(plan.Notes, fmt.Sprintf("task %q is deleted with its parent", op.Name))
+			continue
+		}
+		ops = append(ops, op)
+	}
+	plan.Operations = ops
+}
+
+func hasDeletedAncestor(snap *Snapshot, id string, deleted map[string]bool) bool {
+	seen := map[string]bool{}
+	for {
+		t, ok := snap.TaskByID(id)
+		if !ok || t.ParentID == nil || seen[id] {
+			return false
+		}
+		seen[id] = true
+		id = *t.ParentID
+		if deleted[id] {
+			return true
+		}
+	}
+}
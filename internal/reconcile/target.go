@@ -0,0 +1,208 @@
+package reconcile
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Target selects resources by kind and a path.Match glob on the resource name, e.g.
+// "filter/Work*", "project/Homelab" or "section/Homelab/*". A section pattern without a "/"
+// ("section/Homelab*") selects every section of the matching projects. Tasks match by key or
+// content, and a task target also selects the task's reminders.
+type Target struct {
+	Kind    Kind   `json:"kind"`
+	Pattern string `json:"pattern"`
+}
+
+func (t Target) String() string { return string(t.Kind) + "/" + t.Pattern }
+
+// ParseTarget parses a "kind/name" selector.
+func ParseTarget(s string) (Target, error) {
+	kind, pattern, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok || pattern == "" {
+		return Target{}, fmt.Errorf("invalid target %q (expected kind/name)", s)
+	}
+	t := Target{Kind: Kind(strings.ToLower(kind)), Pattern: pattern}
+	switch t.Kind {
+	case KindProject, KindSection, KindLabel, KindFilter, KindTask, KindReminder:
+	default:
+		return Target{}, fmt.Errorf("invalid target %q: unknown kind %q", s, kind)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return Target{}, fmt.Errorf("invalid target %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// ParseTargets parses each selector, failing on the first invalid one.
+func ParseTargets(ss []string) ([]Target, error) {
+	var out []Target
+	for _, s := range ss {
+		t, err := ParseTarget(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (t Target) matches(op Operation) bool {
+	if t.Kind == KindTask && op.Kind == KindReminder && op.ReminderPayload != nil {
+		return t.matchesTask(op.ReminderPayload.TaskName, op.ReminderPayload.TaskKey)
+	}
+	if op.Kind != t.Kind {
+		return false
+	}
+	if ok, _ := path.Match(t.Pattern, op.Name); ok {
+		return true
+	}
+	if op.Kind == KindSection && !strings.Contains(t.Pattern, "/") {
+		// "*" does not match "/", so a pattern without one selects the sections of matching projects.
+		project, _, _ := strings.Cut(op.Name, "/")
+		if op.SectionPayload != nil {
+			project = op.SectionPayload.ProjectName
+		}
+		if ok, _ := path.Match(t.Pattern, project); ok {
+			return true
+		}
+	}
+	if op.TaskPayload != nil && op.TaskPayload.Key != "" {
+		if ok, _ := path.Match(t.Pattern, op.TaskPayload.Key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Target) matchesTask(content, key string) bool {
+	if ok, _ := path.Match(t.Pattern, content); ok {
+		return true
+	}
+	ok, _ := path.Match(t.Pattern, key)
+	return key != "" && ok
+}
+
+// restrictToTargets keeps only operations selected by targets, plus the operations they depend on:
+// creates and renames of parent projects, of the project a section or task lives in, of the section
+// a task is placed in and of labels a task uses, and creates of the task a subtask or reminder is
+// added to.
+func restrictToTargets(plan *Plan, targets []Target) {
+	if len(targets) == 0 {
+		return
+	}
+
+	// Operations that make a name exist: creates, and renames (apply resolves names declared in
+	// config through them).
+	provides := map[Kind]map[string]int{KindProject: {}, KindSection: {}, KindLabel: {}, KindTask: {}}
+	for i, op := range plan.Operations {
+		if op.Action == ActionCreate || (op.Action == ActionUpdate && op.Kind != KindTask && hasChange(op, "name")) {
+			if byName, ok := provides[op.Kind]; ok {
+				byName[op.Name] = i
+			}
+		}
+	}
+
+	keep := map[int]bool{}
+	var include func(i int)
+	includeDep := func(kind Kind, name string) {
+		if i, ok := provides[kind][name]; ok {
+			include(i)
+		}
+	}
+	includeProject := func(name *string) {
+		if name != nil {
+			includeDep(KindProject, *name)
+		}
+	}
+	include = func(i int) {
+		if keep[i] {
+			return
+		}
+		keep[i] = true
+		op := plan.Operations[i]
+		switch {
+		case op.ProjectPayload != nil:
+			includeProject(op.ProjectPayload.ParentName)
+		case op.SectionPayload != nil:
+			includeProject(&op.SectionPayload.ProjectName)
+		case op.TaskPayload != nil:
+			includeProject(op.TaskPayload.ProjectName)
+			for _, l := range op.TaskPayload.Labels {
+				includeDep(KindLabel, l)
+			}
+			if p := op.TaskPayload; p.SectionID == nil && p.SectionName != nil && p.ProjectName != nil {
+				includeDep(KindSection, sectionOpName(*p.ProjectName, *p.SectionName))
+			}
+			if p := op.TaskPayload; p.ParentID == nil && p.ParentName != nil {
+				includeDep(KindTask, *p.ParentName)
+			}
+		case op.ReminderPayload != nil && op.ReminderPayload.TaskID == "":
+			includeDep(KindTask, op.ReminderPayload.TaskName)
+		}
+	}
+
+	matched := make([]bool, len(targets))
+	for i, op := range plan.Operations {
+		for ti, t := range targets {
+			if t.matches(op) {
+				matched[ti] = true
+				include(i)
+			}
+		}
+	}
+
+	var ops []Operation
+	for i, op := range plan.Operations {
+		if keep[i] {
+			ops = append(ops, op)
+		}
+	}
+	plan.Operations = ops
+	plan.Summary = summarize(ops)
+
+	var names []string
+	for ti, t := range targets {
+		names = append(names, t.String())
+		if !matched[ti] {
+			plan.Notes = append(plan.Notes, fmt.Sprintf("target %q matched no changes", t.String()))
+		}
+	}
+	plan.Notes = append(plan.Notes, fmt.Sprintf("plan restricted to targets %s (plus dependencies); other changes are not shown", strings.Join(names, ", ")))
+}
+
+// WithoutDeletes returns a copy of plan with every delete left out, for applying only the
+// non-destructive part of a plan unattended.
+func WithoutDeletes(plan *Plan) *Plan {
+	out := &Plan{Notes: append([]string(nil), plan.Notes...)}
+	for _, op := range plan.Operations {
+		if op.Action != ActionDelete {
+			out.Operations = append(out.Operations, op)
+		}
+	}
+	out.Summary = summarize(out.Operations)
+	if plan.Summary.Delete > 0 {
+		out.Notes = append(out.Notes, fmt.Sprintf("%d delete(s) left out", plan.Summary.Delete))
+	}
+	return out
+}
+
+func summarize(ops []Operation) Summary {
+	var s Summary
+	for _, op := range ops {
+		switch op.Action {
+		case ActionCreate:
+			s.Create++
+		case ActionUpdate:
+			s.Update++
+		case ActionMove:
+			s.Move++
+		case ActionDelete:
+			s.Delete++
+		case ActionReorder:
+			s.Reorder++
+		}
+	}
+	return s
+}
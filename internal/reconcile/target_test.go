@@ -0,0 +1,138 @@
+package reconcile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+func TestBuildPlan_TargetsIncludeDependencies(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{
+				{Name: "Personal"},
+				{Name: "Homelab", Parent: strPtr("Personal")},
+				{Name: "Work"},
+			},
+			Labels: []config.LabelSpec{{Name: "next"}, {Name: "waiting"}},
+			Filters: []config.FilterSpec{
+				{Name: "Work Focus", Query: "#Work"},
+				{Name: "Waiting", Query: "@waiting"},
+			},
+			Tasks: []config.TaskSpec{
+				{Key: "patch", Content: "Patch servers", Project: strPtr("Homelab"), Labels: []string{"next"}},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	snap := &Snapshot{}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	targets, err := ParseTargets([]string{"task/patch"})
+	if err != nil {
+		t.Fatalf("ParseTargets: %v", err)
+	}
+	plan, err := BuildPlan(cfg, snap, Options{Targets: targets})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	var got []string
+	for _, op := range plan.Operations {
+		got = append(got, op.SortKey())
+	}
+	want := []string{
+		"project/Homelab/create",
+		"project/Personal/create",
+		"label/next/create",
+		"task/Patch servers/create",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if plan.Summary.Create != 4 {
+		t.Fatalf("expected summary recomputed to 4 creates, got %#v", plan.Summary)
+	}
+
+	targets, err = ParseTargets([]string{"filter/Work*"})
+	if err != nil {
+		t.Fatalf("ParseTargets: %v", err)
+	}
+	plan, err = BuildPlan(cfg, snap, Options{Targets: targets})
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if len(plan.Operations) != 1 || plan.Operations[0].Name != "Work Focus" {
+		t.Fatalf("expected only filter Work Focus, got %#v", plan.Operations)
+	}
+}
+
+func TestParseTarget_Invalid(t *testing.T) {
+	for _, s := range []string{"project", "widget/x", "filter/[", "label/"} {
+		if _, err := ParseTarget(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+func TestBuildPlan_TargetsSectionsAndRenamedParents(t *testing.T) {
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "test"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{
+				{Name: "Homelab", ID: strPtr("P1"), Sections: []config.SectionSpec{{Name: "Inbox"}, {Name: "Doing"}}},
+				{Name: "Lab", Parent: strPtr("Homelab")},
+				{Name: "Work", Sections: []config.SectionSpec{{Name: "Inbox"}}},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	snap := &Snapshot{Projects: []v1.Project{{ID: "P1", Name: "Home Lab"}}}
+	if err := snap.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	keys := func(targetArgs ...string) []string {
+		t.Helper()
+		targets, err := ParseTargets(targetArgs)
+		if err != nil {
+			t.Fatalf("ParseTargets: %v", err)
+		}
+		plan, err := BuildPlan(cfg, snap, Options{Targets: targets})
+		if err != nil {
+			t.Fatalf("BuildPlan: %v", err)
+		}
+		var got []string
+		for _, op := range plan.Operations {
+			got = append(got, op.SortKey())
+		}
+		return got
+	}
+
+	got := keys("section/Home*")
+	want := []string{"project/Homelab/update", "section/Homelab/Doing/create", "section/Homelab/Inbox/create"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	got = keys("project/Lab")
+	want = []string{"project/Homelab/update", "project/Lab/create"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -27,7 +27,8 @@ func buildManagedTaskDescription(userDescription *string, key string) *string {
 	return &v
 }
 
-func taskDescriptionSansManagedKey(description string) string {
+// TaskDescriptionSansManagedKey returns a task description without its HTD_KEY: line.
+func TaskDescriptionSansManagedKey(description string) string {
 	var out []string
 	for _, ln := range strings.Split(description, "\n") {
 		t := strings.TrimSpace(ln)
@@ -0,0 +1,53 @@
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+)
+
+// resolveTaskSection returns the remote ID of the section a task is placed in (t.Section within
+// t.Project), or nil when the section is declared in config and created in this apply. projectID
+// is nil when the project itself is created in this apply.
+func resolveTaskSection(t config.TaskSpec, projectID *string, snap *Snapshot, declared map[string]config.SectionSpec) (*string, error) {
+	sec, isDeclared := declared[sectionOpName(*t.Project, *t.Section)]
+	if isDeclared && sec.ID != nil {
+		// Pinned by id: the section may be renamed to its declared name in this apply.
+		if remote, ok := snap.SectionByID(*sec.ID); ok {
+			id := remote.ID
+			return &id, nil
+		}
+	}
+	if projectID != nil {
+		remote, ok, err := snap.SectionByName(*projectID, *t.Section)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			id := remote.ID
+			return &id, nil
+		}
+	}
+	if isDeclared {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("task %q references unknown section %q in project %q", t.Content, *t.Section, *t.Project)
+}
+
+// taskSectionID returns the (real or temp) ID of the section a task is created in or moved to, or
+// "" when it has none. createdIDs holds the IDs of sections created in this apply by op name.
+func taskSectionID(op Operation, createdIDs map[string]string) (string, error) {
+	payload := op.TaskPayload
+	switch {
+	case payload.SectionID != nil:
+		return *payload.SectionID, nil
+	case payload.SectionName != nil && payload.ProjectName != nil:
+		name := sectionOpName(*payload.ProjectName, *payload.SectionName)
+		id, ok := createdIDs[name]
+		if !ok {
+			return "", fmt.Errorf("task %q: section %q not found (create ordering bug)", op.Name, name)
+		}
+		return id, nil
+	}
+	return "", nil
+}
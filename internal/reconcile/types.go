@@ -5,10 +5,12 @@ import "fmt"
 type Kind string
 
 const (
-	KindProject Kind = "project"
-	KindLabel   Kind = "label"
-	KindFilter  Kind = "filter"
-	KindTask    Kind = "task"
+	KindProject  Kind = "project"
+	KindSection  Kind = "section"
+	KindLabel    Kind = "label"
+	KindFilter   Kind = "filter"
+	KindTask     Kind = "task"
+	KindReminder Kind = "reminder"
 )
 
 type Action string
@@ -35,10 +37,12 @@ type Operation struct {
 	Changes []Change `json:"changes,omitempty"`
 
 	// Internal-only payloads for apply.
-	ProjectPayload *ProjectPayload `json:"-"`
-	LabelPayload   *LabelPayload   `json:"-"`
-	FilterPayload  *FilterPayload  `json:"-"`
-	TaskPayload    *TaskPayload    `json:"-"`
+	ProjectPayload  *ProjectPayload  `json:"-"`
+	SectionPayload  *SectionPayload  `json:"-"`
+	LabelPayload    *LabelPayload    `json:"-"`
+	FilterPayload   *FilterPayload   `json:"-"`
+	TaskPayload     *TaskPayload     `json:"-"`
+	ReminderPayload *ReminderPayload `json:"-"`
 }
 
 func (op Operation) SortKey() string {
@@ -63,10 +67,12 @@ type Plan struct {
 	Notes      []string    `json:"notes,omitempty"`
 }
 
-// ApplyResult captures outcomes per operation (best-effort; MVP only).
+// ApplyResult captures outcomes per operation, in the order they were attempted.
 type ApplyResult struct {
 	Applied []OperationResult `json:"applied"`
 	Summary Summary           `json:"summary"`
+	Failed  int               `json:"failed,omitempty"`
+	Skipped int               `json:"skipped,omitempty"`
 }
 
 type OperationResult struct {
@@ -74,39 +80,66 @@ type OperationResult struct {
 	Action Action `json:"action"`
 	Name   string `json:"name"`
 	ID     string `json:"id,omitempty"`
-	Status string `json:"status"` // "ok" or error string
+	Status string `json:"status"` // "ok", an error string, or "skipped: <reason>"
 }
 
+// Payloads are hidden from plan JSON output but serialized in saved plan files (see PlanFile).
+
 type ProjectPayload struct {
-	DesiredName string
-	ParentName  *string
-	Color       *string
-	IsFavorite  *bool
-	ViewStyle   *string
+	DesiredName string  `json:"desired_name"`
+	ParentName  *string `json:"parent_name,omitempty"`
+	Color       *string `json:"color,omitempty"`
+	IsFavorite  *bool   `json:"is_favorite,omitempty"`
+	ViewStyle   *string `json:"view_style,omitempty"`
+}
+
+type SectionPayload struct {
+	ProjectName string `json:"project_name"`
+	DesiredName string `json:"desired_name"`
+	Order       int    `json:"order"`
 }
 
 type LabelPayload struct {
-	DesiredName string
-	Color       *string
-	IsFavorite  *bool
+	DesiredName string  `json:"desired_name"`
+	Color       *string `json:"color,omitempty"`
+	IsFavorite  *bool   `json:"is_favorite,omitempty"`
 }
 
 type FilterPayload struct {
-	DesiredName string
-	Query       string
-	Color       *string
-	IsFavorite  *bool
-	Order       int
-	RemoteID    string // for updates/deletes
+	DesiredName string  `json:"desired_name"`
+	Query       string  `json:"query"`
+	Color       *string `json:"color,omitempty"`
+	IsFavorite  *bool   `json:"is_favorite,omitempty"`
+	Order       int     `json:"order"`
+	RemoteID    string  `json:"remote_id,omitempty"` // for updates/deletes
 }
 
 type TaskPayload struct {
-	Key         string
-	DesiredName string
-	Description *string
-	ProjectName *string
-	ProjectID   *string
-	Labels      []string
-	Priority    *int
-	DueString   *string
+	Key         string   `json:"key,omitempty"`
+	DesiredName string   `json:"desired_name"`
+	Description *string  `json:"description,omitempty"`
+	ProjectName *string  `json:"project_name,omitempty"`
+	ProjectID   *string  `json:"project_id,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Priority    *int     `json:"priority,omitempty"`
+	DueString   *string  `json:"due_string,omitempty"`
+
+	// SectionName is a section of ProjectName; SectionID is nil when the section is created in the same apply.
+	SectionName *string `json:"section_name,omitempty"`
+	SectionID   *string `json:"section_id,omitempty"`
+
+	// Subtasks only. ParentKey resolves a parent created in the same apply (ParentID is nil then).
+	ParentName *string `json:"parent_name,omitempty"`
+	ParentKey  string  `json:"parent_key,omitempty"`
+	ParentID   *string `json:"parent_id,omitempty"`
+	Order      int     `json:"order,omitempty"`
+}
+
+type ReminderPayload struct {
+	TaskName     string `json:"task_name"`
+	TaskKey      string `json:"task_key,omitempty"` // resolves a task created in the same apply
+	TaskID       string `json:"task_id,omitempty"`  // empty when the task is created in the same apply
+	Type         string `json:"type"`               // relative or absolute
+	MinuteOffset int    `json:"minute_offset,omitempty"`
+	Date         string `json:"date,omitempty"`
 }
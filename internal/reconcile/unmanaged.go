@@ -0,0 +1,85 @@
+package reconcile
+
+import (
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/state"
+)
+
+// Unmanaged returns the IDs of the remote projects, labels, filters and tasks that cfg does not
+// declare, i.e. what a plan reports as "not in config" and deletes with --prune. Resources are
+// matched like BuildPlan does: by id, by name (tasks: by HTD_KEY: marker) and through st for
+// renames. The inbox project is never included, and neither are sections.
+func Unmanaged(cfg *config.TodoistConfig, snap *Snapshot, st *state.State) map[Kind]map[string]bool {
+	ids, _ := resolveStateIDs(cfg, snap, st)
+	out := map[Kind]map[string]bool{
+		KindProject: {},
+		KindLabel:   {},
+		KindFilter:  {},
+		KindTask:    {},
+	}
+
+	projectNames, projectIDs := map[string]bool{}, map[string]bool{}
+	for _, p := range cfg.Spec.Projects {
+		projectNames[p.Name] = true
+		if p.ID != nil {
+			projectIDs[*p.ID] = true
+		} else if id, ok := ids.lookup(KindProject, p.Name); ok {
+			projectIDs[id] = true
+		}
+	}
+	for _, p := range snap.Projects {
+		if !p.InboxProject && !projectIDs[p.ID] && !projectNames[p.Name] {
+			out[KindProject][p.ID] = true
+		}
+	}
+
+	labelNames, labelIDs := map[string]bool{}, map[string]bool{}
+	for _, l := range cfg.Spec.Labels {
+		labelNames[l.Name] = true
+		if l.ID != nil {
+			labelIDs[*l.ID] = true
+		} else if id, ok := ids.lookup(KindLabel, l.Name); ok {
+			labelIDs[id] = true
+		}
+	}
+	for _, l := range snap.Labels {
+		if !labelIDs[l.ID] && !labelNames[l.Name] {
+			out[KindLabel][l.ID] = true
+		}
+	}
+
+	filterNames, filterIDs := map[string]bool{}, map[string]bool{}
+	for _, f := range cfg.Spec.Filters {
+		filterNames[f.Name] = true
+		if f.ID != nil {
+			filterIDs[*f.ID] = true
+		} else if id, ok := ids.lookup(KindFilter, f.Name); ok {
+			filterIDs[id] = true
+		}
+	}
+	for _, f := range snap.Filters {
+		if !filterIDs[f.ID] && !filterNames[f.Name] {
+			out[KindFilter][f.ID] = true
+		}
+	}
+
+	taskKeys, taskIDs := map[string]bool{}, map[string]bool{}
+	for _, t := range cfg.Spec.AllTasks() {
+		if t.ID != nil {
+			taskIDs[*t.ID] = true
+		}
+		if t.Key != "" {
+			taskKeys[t.Key] = true
+		}
+	}
+	for _, t := range snap.Tasks {
+		if taskIDs[t.ID] {
+			continue
+		}
+		if key, ok := ManagedTaskKey(t.Description); ok && taskKeys[key] {
+			continue
+		}
+		out[KindTask][t.ID] = true
+	}
+	return out
+}
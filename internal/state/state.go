@@ -0,0 +1,172 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+)
+
+// Version is the on-disk state format version.
+const Version = 1
+
+// Resource maps a config identity (kind + name, or kind + key for tasks) to a remote ID.
+type Resource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// State is the local record of which remote objects htd manages for a config.
+// It lets the planner recognise renames (same remote ID, new name in YAML or in the app) instead of
+// treating them as create + delete.
+type State struct {
+	Version   int        `json:"version"`
+	Config    string     `json:"config"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Resources []Resource `json:"resources"`
+}
+
+// New returns an empty state for the named config.
+func New(configName string) *State {
+	return &State{Version: Version, Config: configName}
+}
+
+// DefaultPath returns the state file used for a config name:
+//
+//	~/.config/todoist/state/<name>.json
+func DefaultPath(configName string) (string, error) {
+	dir, err := config.ConfigDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "state", configName+".json"), nil
+}
+
+// Load reads a state file. A missing file yields an empty state (first run).
+func Load(path, configName string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return New(configName), nil
+		}
+		return nil, fmt.Errorf("read state %q: %w", path, err)
+	}
+	var st State
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("parse state %q: %w", path, err)
+	}
+	if st.Version != Version {
+		return nil, fmt.Errorf("state %q has unsupported version %d (expected %d)", path, st.Version, Version)
+	}
+	if configName != "" && st.Config != "" && st.Config != configName {
+		return nil, fmt.Errorf("state %q belongs to config %q, not %q", path, st.Config, configName)
+	}
+	return &st, nil
+}
+
+// Save writes the state atomically (temp file + rename), creating parent directories.
+func (s *State) Save(path string) error {
+	s.Version = Version
+	s.UpdatedAt = time.Now().UTC()
+	s.sort()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+	b = append(b, '\n')
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("write state %q: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state %q: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write state %q: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write state %q: %w", path, err)
+	}
+	return nil
+}
+
+// Lookup returns the resource recorded for kind/name.
+func (s *State) Lookup(kind, name string) (Resource, bool) {
+	if s == nil {
+		return Resource{}, false
+	}
+	for _, r := range s.Resources {
+		if r.Kind == kind && r.Name == name {
+			return r, true
+		}
+	}
+	return Resource{}, false
+}
+
+// ByKind returns all resources of a kind, sorted by name.
+func (s *State) ByKind(kind string) []Resource {
+	if s == nil {
+		return nil
+	}
+	var out []Resource
+	for _, r := range s.Resources {
+		if r.Kind == kind {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Set records (or replaces) the remote ID for kind/name.
+func (s *State) Set(kind, name, id string) {
+	for i, r := range s.Resources {
+		if r.Kind == kind && r.Name == name {
+			s.Resources[i].ID = id
+			return
+		}
+	}
+	s.Resources = append(s.Resources, Resource{Kind: kind, Name: name, ID: id})
+}
+
+// Remove deletes the entry for kind/name, reporting whether it existed.
+func (s *State) Remove(kind, name string) bool {
+	for i, r := range s.Resources {
+		if r.Kind == kind && r.Name == name {
+			s.Resources = append(s.Resources[:i], s.Resources[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveKind deletes every entry of a kind.
+func (s *State) RemoveKind(kind string) {
+	out := s.Resources[:0]
+	for _, r := range s.Resources {
+		if r.Kind != kind {
+			out = append(out, r)
+		}
+	}
+	s.Resources = out
+}
+
+func (s *State) sort() {
+	sort.Slice(s.Resources, func(i, j int) bool {
+		if s.Resources[i].Kind != s.Resources[j].Kind {
+			return s.Resources[i].Kind < s.Resources[j].Kind
+		}
+		return s.Resources[i].Name < s.Resources[j].Name
+	})
+}
@@ -0,0 +1,49 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_MissingFileIsEmpty(t *testing.T) {
+	st, err := Load(filepath.Join(t.TempDir(), "nope.json"), "personal")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if st.Config != "personal" || len(st.Resources) != 0 {
+		t.Fatalf("expected empty state for personal, got %#v", st)
+	}
+}
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "nested", "personal.json")
+	st := New("personal")
+	st.Set("project", "Work", "P1")
+	st.Set("label", "waiting", "L1")
+	st.Set("project", "Work", "P2")
+	if err := st.Save(p); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := Load(p, "personal")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got.Resources) != 2 {
+		t.Fatalf("expected 2 resources, got %#v", got.Resources)
+	}
+	r, ok := got.Lookup("project", "Work")
+	if !ok || r.ID != "P2" {
+		t.Fatalf("expected project Work -> P2, got %#v (ok=%t)", r, ok)
+	}
+	if !got.Remove("label", "waiting") {
+		t.Fatalf("expected Remove to report existing entry")
+	}
+	if got.Remove("label", "waiting") {
+		t.Fatalf("expected second Remove to report missing entry")
+	}
+
+	if _, err := Load(p, "other"); err == nil {
+		t.Fatalf("expected error loading state for a different config")
+	}
+}
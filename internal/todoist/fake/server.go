@@ -0,0 +1,960 @@
+// Package fake implements an in-memory Todoist API for end-to-end tests.
+//
+// It covers the subset of /api/v1 that htd uses: REST CRUD for projects, sections, labels and
+// tasks (with cursor pagination), the completed tasks list, and /api/v1/sync reads and commands
+// (with temp_id mapping).
+// Point a client at it with todoisthttp.WithBaseURL(server.URL()).
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"time"
+
+	todoistsync "github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// DefaultPageSize matches the Todoist default page size for list endpoints.
+const DefaultPageSize = 50
+
+// Server is an in-memory Todoist backed by an httptest.Server.
+type Server struct {
+	srv *httptest.Server
+
+	mu       gosync.Mutex
+	pageSize int
+	nextID   int
+	requests int
+
+	// syncReads counts /sync reads; history maps each sync_token issued to the state it saw.
+	syncReads int
+	history   map[string]syncState
+
+	projects []v1.Project
+	sections []v1.Section
+	labels   []v1.Label
+	tasks    []v1.Task
+	filters  []todoistsync.Filter
+
+	// reminders of deleted tasks are kept but hidden, like tombstones.
+	reminders []todoistsync.Reminder
+
+	// completed tasks are listed by /tasks/completed/by_completion_date; see CompleteTask.
+	completed      []completedTask
+	completedReads int
+
+	// rejected names (or task contents) fail on create and update; see Reject.
+	rejected map[string]bool
+}
+
+type Option func(*Server)
+
+// WithPageSize sets the number of results per page on list endpoints.
+func WithPageSize(n int) Option {
+	return func(s *Server) {
+		if n <= 0 {
+			return
+		}
+		s.pageSize = n
+	}
+}
+
+// New starts a fake server with an empty account (apart from the Inbox project).
+// Callers must Close it.
+func New(opts ...Option) *Server {
+	s := &Server{pageSize: DefaultPageSize, history: map[string]syncState{}, rejected: map[string]bool{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.AddProject(v1.Project{Name: "Inbox", InboxProject: true})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/projects", s.handleListProjects)
+	mux.HandleFunc("POST /api/v1/projects", s.handleCreateProject)
+	mux.HandleFunc("POST /api/v1/projects/{id}", s.handleUpdateProject)
+	mux.HandleFunc("DELETE /api/v1/projects/{id}", s.handleDeleteProject)
+	mux.HandleFunc("GET /api/v1/sections", s.handleListSections)
+	mux.HandleFunc("POST /api/v1/sections", s.handleCreateSection)
+	mux.HandleFunc("POST /api/v1/sections/{id}", s.handleUpdateSection)
+	mux.HandleFunc("DELETE /api/v1/sections/{id}", s.handleDeleteSection)
+	mux.HandleFunc("GET /api/v1/labels", s.handleListLabels)
+	mux.HandleFunc("POST /api/v1/labels", s.handleCreateLabel)
+	mux.HandleFunc("POST /api/v1/labels/{id}", s.handleUpdateLabel)
+	mux.HandleFunc("DELETE /api/v1/labels/{id}", s.handleDeleteLabel)
+	mux.HandleFunc("GET /api/v1/tasks", s.handleListTasks)
+	mux.HandleFunc("POST /api/v1/tasks", s.handleCreateTask)
+	mux.HandleFunc("POST /api/v1/tasks/{id}", s.handleUpdateTask)
+	mux.HandleFunc("DELETE /api/v1/tasks/{id}", s.handleDeleteTask)
+	mux.HandleFunc("GET /api/v1/tasks/completed/by_completion_date", s.handleListCompletedTasks)
+	mux.HandleFunc("POST /api/v1/sync", s.handleSync)
+
+	s.srv = httptest.NewServer(s.authenticate(s.rejectNames(mux)))
+	return s
+}
+
+// URL is the base URL to pass to todoisthttp.WithBaseURL.
+func (s *Server) URL() string { return s.srv.URL }
+
+// Close shuts the server down.
+func (s *Server) Close() { s.srv.Close() }
+
+// Requests returns the number of API requests served so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// AddProject seeds a project, assigning an ID when empty.
+func (s *Server) AddProject(p v1.Project) v1.Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.ID == "" {
+		p.ID = s.newID()
+	}
+	if p.Color == "" {
+		p.Color = "charcoal"
+	}
+	if p.ViewStyle == "" {
+		p.ViewStyle = "list"
+	}
+	s.projects = append(s.projects, p)
+	return p
+}
+
+// AddSection seeds a section, assigning an ID when empty.
+func (s *Server) AddSection(sec v1.Section) v1.Section {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sec.ID == "" {
+		sec.ID = s.newID()
+	}
+	s.sections = append(s.sections, sec)
+	return sec
+}
+
+// AddLabel seeds a label, assigning an ID when empty.
+func (s *Server) AddLabel(l v1.Label) v1.Label {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l.ID == "" {
+		l.ID = s.newID()
+	}
+	if l.Color == "" {
+		l.Color = "charcoal"
+	}
+	s.labels = append(s.labels, l)
+	return l
+}
+
+// AddTask seeds an active task, assigning an ID when empty and defaulting to the Inbox (or, for
+// subtasks, the parent's project and section).
+func (s *Server) AddTask(t v1.Task) v1.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.ID == "" {
+		t.ID = s.newID()
+	}
+	if t.ParentID != nil {
+		if i := s.taskIndex(*t.ParentID); i >= 0 && t.ProjectID == "" {
+			t.ProjectID, t.SectionID = s.tasks[i].ProjectID, copyString(s.tasks[i].SectionID)
+		}
+	}
+	if t.ProjectID == "" {
+		t.ProjectID = s.inboxID()
+	}
+	if t.ChildOrder == 0 {
+		t.ChildOrder = s.nextChildOrder(t.ParentID)
+	}
+	if t.Priority == 0 {
+		t.Priority = 1
+	}
+	if t.Labels == nil {
+		t.Labels = []string{}
+	}
+	s.tasks = append(s.tasks, t)
+	return t
+}
+
+// AddFilter seeds a filter, assigning an ID when empty.
+func (s *Server) AddFilter(f todoistsync.Filter) todoistsync.Filter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f.ID == "" {
+		f.ID = s.newID()
+	}
+	if f.Color == "" {
+		f.Color = "charcoal"
+	}
+	s.filters = append(s.filters, f)
+	return f
+}
+
+// AddReminder seeds a reminder, assigning an ID when empty.
+func (s *Server) AddReminder(r todoistsync.Reminder) todoistsync.Reminder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.ID == "" {
+		r.ID = s.newID()
+	}
+	s.reminders = append(s.reminders, r)
+	return r
+}
+
+// Projects returns a copy of the current projects.
+func (s *Server) Projects() []v1.Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]v1.Project(nil), s.projects...)
+}
+
+// Sections returns a copy of the current sections.
+func (s *Server) Sections() []v1.Section {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]v1.Section(nil), s.sections...)
+}
+
+// Labels returns a copy of the current labels.
+func (s *Server) Labels() []v1.Label {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]v1.Label(nil), s.labels...)
+}
+
+// Tasks returns a copy of the current active tasks.
+func (s *Server) Tasks() []v1.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]v1.Task(nil), s.tasks...)
+}
+
+// Filters returns a copy of the current filters.
+func (s *Server) Filters() []todoistsync.Filter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]todoistsync.Filter(nil), s.filters...)
+}
+
+// Reminders returns a copy of the reminders of active tasks.
+func (s *Server) Reminders() []todoistsync.Reminder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activeReminders()
+}
+
+func (s *Server) activeReminders() []todoistsync.Reminder {
+	var out []todoistsync.Reminder
+	for _, r := range s.reminders {
+		if s.taskIndex(r.ItemID) >= 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		s.mu.Lock()
+		s.requests++
+		s.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Reject makes every REST call or /sync command that sets a name (or task content) to name fail,
+// for testing partial applies.
+func (s *Server) Reject(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected[name] = true
+}
+
+// isRejected reports whether args set a rejected name; callers must hold s.mu.
+func (s *Server) isRejected(args map[string]any) bool {
+	for _, field := range []string{"name", "content"} {
+		if v, ok := args[field].(string); ok && s.rejected[v] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) rejectNames(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path == "/api/v1/sync" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("read body: %v", err))
+			return
+		}
+		var args map[string]any
+		_ = json.Unmarshal(body, &args)
+		s.mu.Lock()
+		rejected := s.isRejected(args)
+		s.mu.Unlock()
+		if rejected {
+			writeError(w, http.StatusBadRequest, "rejected by test")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newID returns a fresh numeric-looking ID; callers must hold s.mu.
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.Itoa(1000 + s.nextID)
+}
+
+func (s *Server) inboxID() string {
+	for _, p := range s.projects {
+		if p.InboxProject {
+			return p.ID
+		}
+	}
+	return ""
+}
+
+// --- REST
+
+type listResponse[T any] struct {
+	Results    []T     `json:"results"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// page slices items according to the opaque cursor (an offset) in the request.
+func page[T any](r *http.Request, items []T, size int) (listResponse[T], error) {
+	start := 0
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		n, err := strconv.Atoi(c)
+		if err != nil || n < 0 || n > len(items) {
+			return listResponse[T]{}, fmt.Errorf("invalid cursor %q", c)
+		}
+		start = n
+	}
+	end := start + size
+	if end > len(items) {
+		end = len(items)
+	}
+	resp := listResponse[T]{Results: append([]T{}, items[start:end]...)}
+	if end < len(items) {
+		next := strconv.Itoa(end)
+		resp.NextCursor = &next
+	}
+	return resp, nil
+}
+
+func list[T any](s *Server, w http.ResponseWriter, r *http.Request, items *[]T) {
+	s.mu.Lock()
+	resp, err := page(r, *items, s.pageSize)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
+	list(s, w, r, &s.projects)
+}
+
+func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	var req v1.CreateProjectRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.ParentID != nil && s.projectIndex(*req.ParentID) < 0 {
+		writeError(w, http.StatusBadRequest, "parent project not found")
+		return
+	}
+	p := v1.Project{ID: s.newID(), Name: req.Name, Color: "charcoal", ViewStyle: "list", ParentID: req.ParentID}
+	if req.Color != nil {
+		p.Color = *req.Color
+	}
+	if req.IsFavorite != nil {
+		p.IsFavorite = *req.IsFavorite
+	}
+	if req.ViewStyle != nil {
+		p.ViewStyle = *req.ViewStyle
+	}
+	s.projects = append(s.projects, p)
+	writeJSON(w, p)
+}
+
+func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	var req v1.UpdateProjectRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.projectIndex(r.PathValue("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	p := &s.projects[i]
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Color != nil {
+		p.Color = *req.Color
+	}
+	if req.IsFavorite != nil {
+		p.IsFavorite = *req.IsFavorite
+	}
+	if req.ViewStyle != nil {
+		p.ViewStyle = *req.ViewStyle
+	}
+	writeJSON(w, *p)
+}
+
+func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.PathValue("id")
+	i := s.projectIndex(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	if s.projects[i].InboxProject {
+		writeError(w, http.StatusBadRequest, "cannot delete the inbox project")
+		return
+	}
+	s.deleteProject(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteProject removes a project with its sub-projects, sections and tasks, like Todoist does.
+func (s *Server) deleteProject(id string) {
+	var children []string
+	for _, p := range s.projects {
+		if p.ParentID != nil && *p.ParentID == id {
+			children = append(children, p.ID)
+		}
+	}
+	for _, c := range children {
+		s.deleteProject(c)
+	}
+	s.projects = remove(s.projects, func(p v1.Project) bool { return p.ID == id })
+	s.sections = remove(s.sections, func(sec v1.Section) bool { return sec.ProjectID == id })
+	s.tasks = remove(s.tasks, func(t v1.Task) bool { return t.ProjectID == id })
+}
+
+func (s *Server) handleListSections(w http.ResponseWriter, r *http.Request) {
+	list(s, w, r, &s.sections)
+}
+
+func (s *Server) handleCreateSection(w http.ResponseWriter, r *http.Request) {
+	var req v1.CreateSectionRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.projectIndex(req.ProjectID) < 0 {
+		writeError(w, http.StatusBadRequest, "project not found")
+		return
+	}
+	sec := v1.Section{ID: s.newID(), ProjectID: req.ProjectID, Name: req.Name}
+	if req.Order != nil {
+		sec.SectionOrder = *req.Order
+	} else {
+		for _, other := range s.sections {
+			if other.ProjectID == req.ProjectID && other.SectionOrder >= sec.SectionOrder {
+				sec.SectionOrder = other.SectionOrder + 1
+			}
+		}
+		if sec.SectionOrder == 0 {
+			sec.SectionOrder = 1
+		}
+	}
+	s.sections = append(s.sections, sec)
+	writeJSON(w, sec)
+}
+
+func (s *Server) handleUpdateSection(w http.ResponseWriter, r *http.Request) {
+	var req v1.UpdateSectionRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.sectionIndex(r.PathValue("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "section not found")
+		return
+	}
+	if req.Name != nil {
+		s.sections[i].Name = *req.Name
+	}
+	writeJSON(w, s.sections[i])
+}
+
+func (s *Server) handleDeleteSection(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.PathValue("id")
+	if s.sectionIndex(id) < 0 {
+		writeError(w, http.StatusNotFound, "section not found")
+		return
+	}
+	s.deleteSection(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListLabels(w http.ResponseWriter, r *http.Request) {
+	list(s, w, r, &s.labels)
+}
+
+func (s *Server) handleCreateLabel(w http.ResponseWriter, r *http.Request) {
+	var req v1.CreateLabelRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.labels {
+		if strings.EqualFold(l.Name, req.Name) {
+			writeError(w, http.StatusBadRequest, "label already exists")
+			return
+		}
+	}
+	l := v1.Label{ID: s.newID(), Name: req.Name, Color: "charcoal"}
+	if req.Color != nil {
+		l.Color = *req.Color
+	}
+	if req.IsFavorite != nil {
+		l.IsFavorite = *req.IsFavorite
+	}
+	s.labels = append(s.labels, l)
+	writeJSON(w, l)
+}
+
+func (s *Server) handleUpdateLabel(w http.ResponseWriter, r *http.Request) {
+	var req v1.UpdateLabelRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.labelIndex(r.PathValue("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "label not found")
+		return
+	}
+	l := &s.labels[i]
+	if req.Name != nil {
+		s.renameLabel(i, *req.Name)
+	}
+	if req.Color != nil {
+		l.Color = *req.Color
+	}
+	if req.IsFavorite != nil {
+		l.IsFavorite = *req.IsFavorite
+	}
+	writeJSON(w, *l)
+}
+
+func (s *Server) handleDeleteLabel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.labelIndex(r.PathValue("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "label not found")
+		return
+	}
+	s.deleteLabel(i)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// renameLabel renames the label at index i; renaming a personal label renames it on every task.
+func (s *Server) renameLabel(i int, name string) {
+	old := s.labels[i].Name
+	if name == old {
+		return
+	}
+	for ti := range s.tasks {
+		for li, l := range s.tasks[ti].Labels {
+			if l == old {
+				s.tasks[ti].Labels[li] = name
+			}
+		}
+	}
+	s.labels[i].Name = name
+}
+
+// deleteLabel removes the label at index i and strips it from every task.
+func (s *Server) deleteLabel(i int) {
+	name := s.labels[i].Name
+	s.labels = append(s.labels[:i], s.labels[i+1:]...)
+	for ti := range s.tasks {
+		s.tasks[ti].Labels = remove(s.tasks[ti].Labels, func(l string) bool { return l == name })
+	}
+}
+
+func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	list(s, w, r, &s.tasks)
+}
+
+func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
+	var req v1.CreateTaskRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := v1.Task{ID: s.newID(), Content: req.Content, ProjectID: s.inboxID(), Labels: []string{}, Priority: 1}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	if req.ProjectID != nil {
+		if s.projectIndex(*req.ProjectID) < 0 {
+			writeError(w, http.StatusBadRequest, "project not found")
+			return
+		}
+		t.ProjectID = *req.ProjectID
+	}
+	if req.SectionID != nil {
+		si := s.sectionIndex(*req.SectionID)
+		if si < 0 {
+			writeError(w, http.StatusBadRequest, "section not found")
+			return
+		}
+		t.ProjectID, t.SectionID = s.sections[si].ProjectID, copyString(req.SectionID)
+	}
+	if req.ParentID != nil {
+		pi := s.taskIndex(*req.ParentID)
+		if pi < 0 {
+			writeError(w, http.StatusBadRequest, "parent task not found")
+			return
+		}
+		parent := *req.ParentID
+		t.ParentID = &parent
+		t.ProjectID, t.SectionID = s.tasks[pi].ProjectID, copyString(s.tasks[pi].SectionID)
+	}
+	t.ChildOrder = s.nextChildOrder(t.ParentID)
+	if req.Order != nil {
+		t.ChildOrder = *req.Order
+	}
+	if req.Labels != nil {
+		t.Labels = append([]string{}, req.Labels...)
+	}
+	if req.Priority != nil {
+		t.Priority = *req.Priority
+	}
+	if req.DueString != nil {
+		t.Due = parseDue(*req.DueString)
+	}
+	s.tasks = append(s.tasks, t)
+	writeJSON(w, t)
+}
+
+func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	var req v1.UpdateTaskRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.taskIndex(r.PathValue("id"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "task not found")
+		return
+	}
+	t := &s.tasks[i]
+	if req.Content != nil {
+		t.Content = *req.Content
+	}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	if req.ProjectID != nil {
+		if s.projectIndex(*req.ProjectID) < 0 {
+			writeError(w, http.StatusBadRequest, "project not found")
+			return
+		}
+		s.moveTask(i, *req.ProjectID, nil, nil)
+	}
+	if req.Labels != nil {
+		t.Labels = append([]string{}, (*req.Labels)...)
+	}
+	if req.Priority != nil {
+		t.Priority = *req.Priority
+	}
+	if req.DueString != nil {
+		t.Due = parseDue(*req.DueString)
+	}
+	writeJSON(w, *t)
+}
+
+func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.PathValue("id")
+	if s.taskIndex(id) < 0 {
+		writeError(w, http.StatusNotFound, "task not found")
+		return
+	}
+	s.deleteTask(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteSection removes a section with its tasks, like Todoist does.
+func (s *Server) deleteSection(id string) {
+	s.sections = remove(s.sections, func(sec v1.Section) bool { return sec.ID == id })
+	s.tasks = remove(s.tasks, func(t v1.Task) bool { return t.SectionID != nil && *t.SectionID == id })
+}
+
+type completedTask struct {
+	task v1.Task
+	at   time.Time
+}
+
+type completedResponse struct {
+	Items      []v1.Task `json:"items"`
+	NextCursor *string   `json:"next_cursor"`
+}
+
+func (s *Server) handleListCompletedTasks(w http.ResponseWriter, r *http.Request) {
+	since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid since")
+		return
+	}
+	until, err := time.Parse(time.RFC3339, r.URL.Query().Get("until"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid until")
+		return
+	}
+	s.mu.Lock()
+	s.completedReads++
+	var tasks []v1.Task
+	for _, c := range s.completed {
+		if !c.at.Before(since) && !c.at.After(until) {
+			tasks = append(tasks, c.task)
+		}
+	}
+	resp, err := page(r, tasks, s.pageSize)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, completedResponse{Items: resp.Results, NextCursor: resp.NextCursor})
+}
+
+// CompletedReads returns the number of completed task listings served so far.
+func (s *Server) CompletedReads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.completedReads
+}
+
+// CompleteTask completes a task. Like in Todoist, a recurring task stays active with its next date
+// and a rewritten due string ("every day at 8:00am" becomes "Every day at 8am"); any other task is
+// no longer listed, with its subtasks, and moves to the completed tasks.
+func (s *Server) CompleteTask(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.taskIndex(id)
+	if i < 0 {
+		return
+	}
+	if due := s.tasks[i].Due; due != nil && due.IsRecurring {
+		next := *due
+		if d, err := time.Parse("2006-01-02", due.Date); err == nil {
+			next.Date = d.AddDate(0, 0, 1).Format("2006-01-02")
+		}
+		next.String = strings.NewReplacer(":00am", "am", ":00pm", "pm").Replace(next.String)
+		next.String = strings.ToUpper(next.String[:1]) + next.String[1:]
+		s.tasks[i].Due = &next
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Second) // since and until have second precision
+	var complete func(id string)
+	complete = func(id string) {
+		for _, t := range s.tasks {
+			if t.ID == id {
+				s.completed = append(s.completed, completedTask{task: t, at: now})
+			} else if t.ParentID != nil && *t.ParentID == id {
+				complete(t.ID)
+			}
+		}
+	}
+	complete(id)
+	s.deleteTask(id)
+}
+
+// deleteTask removes a task with its subtasks, like Todoist does.
+func (s *Server) deleteTask(id string) {
+	for _, t := range s.tasks {
+		if t.ParentID != nil && *t.ParentID == id {
+			s.deleteTask(t.ID)
+		}
+	}
+	s.tasks = remove(s.tasks, func(t v1.Task) bool { return t.ID == id })
+}
+
+// moveTask moves the task at index i, with its subtasks, to a project (sectionID and parentID nil),
+// to one of its sections, or under another task (in that task's section).
+func (s *Server) moveTask(i int, projectID string, sectionID, parentID *string) {
+	t := &s.tasks[i]
+	t.ProjectID, t.SectionID, t.ParentID = projectID, copyString(sectionID), copyString(parentID)
+	var walk func(id string)
+	walk = func(id string) {
+		for ci := range s.tasks {
+			if c := &s.tasks[ci]; c.ParentID != nil && *c.ParentID == id {
+				c.ProjectID, c.SectionID = projectID, copyString(sectionID)
+				walk(c.ID)
+			}
+		}
+	}
+	walk(t.ID)
+}
+
+// nextChildOrder is the child_order Todoist gives a task appended under parentID.
+func (s *Server) nextChildOrder(parentID *string) int {
+	n := 0
+	for _, t := range s.tasks {
+		if (t.ParentID == nil) == (parentID == nil) && (parentID == nil || *t.ParentID == *parentID) && t.ChildOrder > n {
+			n = t.ChildOrder
+		}
+	}
+	return n + 1
+}
+
+// parseDue mimics Todoist's handling of due_string closely enough for planning: the string is
+// kept verbatim, "every ..." (or "daily") is recurring and starts today, and an empty string clears
+// the due date.
+func parseDue(s string) *v1.Due {
+	if strings.TrimSpace(s) == "" || strings.EqualFold(s, "no date") {
+		return nil
+	}
+	lower := strings.ToLower(s)
+	recurring := strings.HasPrefix(lower, "every ") || strings.HasPrefix(lower, "every!") || lower == "daily"
+	return &v1.Due{String: s, IsRecurring: recurring, Date: time.Now().UTC().Format("2006-01-02"), Lang: "en"}
+}
+
+func (s *Server) projectIndex(id string) int {
+	for i, p := range s.projects {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) sectionIndex(id string) int {
+	for i, sec := range s.sections {
+		if sec.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) labelIndex(id string) int {
+	for i, l := range s.labels {
+		if l.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) taskIndex(id string) int {
+	for i, t := range s.tasks {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) filterIndex(id string) int {
+	for i, f := range s.filters {
+		if f.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) reminderIndex(id string) int {
+	for i, r := range s.reminders {
+		if r.ID == id && s.taskIndex(r.ItemID) >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func remove[T any](items []T, drop func(T) bool) []T {
+	out := items[:0]
+	for _, it := range items {
+		if !drop(it) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// copyString copies an optional string so tasks never share a pointer.
+func copyString(v *string) *string {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("decode request: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(msg))
+}
@@ -0,0 +1,60 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	todoisthttp "github.com/erauner/homelab-todoist-declarative/internal/todoist/http"
+	todoistsync "github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+func TestServer_PaginationAndTempIDs(t *testing.T) {
+	t.Parallel()
+
+	srv := New(WithPageSize(2))
+	defer srv.Close()
+	for _, name := range []string{"A", "B", "C", "D"} {
+		srv.AddProject(v1.Project{Name: name})
+	}
+
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	ctx := context.Background()
+
+	projects, err := v1.New(h).ListProjects(ctx)
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	if len(projects) != 5 {
+		t.Fatalf("expected 5 projects (inbox + 4) across pages, got %d", len(projects))
+	}
+
+	sc := todoistsync.New(h)
+	cmds := []todoistsync.Command{
+		todoistsync.NewTempIDCommand("filter_add", "tmp", map[string]any{"name": "Important", "query": "p1"}),
+		todoistsync.NewCommand("filter_update", map[string]any{"id": "tmp", "query": "p1 & today"}),
+		todoistsync.NewCommand("nope", map[string]any{}),
+	}
+	resp, err := sc.RunCommands(ctx, cmds)
+	if err != nil {
+		t.Fatalf("RunCommands: %v", err)
+	}
+	if err := todoistsync.RequireAllOK(resp, cmds[:2]); err != nil {
+		t.Fatalf("RequireAllOK: %v", err)
+	}
+	if err := todoistsync.RequireAllOK(resp, cmds[2:]); err == nil {
+		t.Fatalf("expected unsupported command to fail")
+	}
+	id := resp.TempIDMapping["tmp"]
+	if id == "" {
+		t.Fatalf("expected temp_id mapping, got %#v", resp.TempIDMapping)
+	}
+
+	read, err := sc.Read(ctx, []string{"filters"})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(read.Filters) != 1 || read.Filters[0].ID != id || read.Filters[0].Query != "p1 & today" {
+		t.Fatalf("unexpected filters %#v", read.Filters)
+	}
+}
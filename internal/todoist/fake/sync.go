@@ -0,0 +1,616 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	todoistsync "github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// syncError is the per-command error object Todoist returns in sync_status.
+type syncError struct {
+	Error     string `json:"error"`
+	ErrorCode int    `json:"error_code"`
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("parse form: %v", err))
+		return
+	}
+	if raw := r.PostForm.Get("commands"); raw != "" {
+		var cmds []todoistsync.Command
+		if err := json.Unmarshal([]byte(raw), &cmds); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("decode commands: %v", err))
+			return
+		}
+		s.mu.Lock()
+		resp := s.runCommands(cmds)
+		s.mu.Unlock()
+		writeJSON(w, resp)
+		return
+	}
+
+	var types []string
+	if raw := r.PostForm.Get("resource_types"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &types); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("decode resource_types: %v", err))
+			return
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, s.read(r.PostForm.Get("sync_token"), types))
+}
+
+// syncState is the account as /sync would return it, keyed by kind then ID.
+type syncState map[string]map[string]any
+
+func (s *Server) currentState() syncState {
+	st := syncState{"projects": {}, "sections": {}, "labels": {}, "items": {}, "filters": {}, "reminders": {}}
+	for _, p := range s.projects {
+		st["projects"][p.ID] = todoistsync.Project{
+			ID: p.ID, Name: p.Name, Color: p.Color, ParentID: p.ParentID, IsFavorite: p.IsFavorite,
+			ViewStyle: p.ViewStyle, InboxProject: p.InboxProject,
+		}
+	}
+	for _, sec := range s.sections {
+		st["sections"][sec.ID] = todoistsync.Section{ID: sec.ID, ProjectID: sec.ProjectID, Name: sec.Name, SectionOrder: sec.SectionOrder}
+	}
+	for _, l := range s.labels {
+		st["labels"][l.ID] = todoistsync.Label{ID: l.ID, Name: l.Name, Color: l.Color, IsFavorite: l.IsFavorite}
+	}
+	for _, t := range s.tasks {
+		it := todoistsync.Item{
+			ID: t.ID, Content: t.Content, Description: t.Description, ProjectID: t.ProjectID, SectionID: t.SectionID,
+			ParentID: t.ParentID, ChildOrder: t.ChildOrder, Labels: append([]string{}, t.Labels...), Priority: t.Priority,
+		}
+		if t.Due != nil {
+			it.Due = &todoistsync.Due{String: t.Due.String, IsRecurring: t.Due.IsRecurring, Date: t.Due.Date, Timezone: t.Due.Timezone, Lang: t.Due.Lang}
+		}
+		st["items"][t.ID] = it
+	}
+	for _, f := range s.filters {
+		st["filters"][f.ID] = f
+	}
+	for _, r := range s.activeReminders() {
+		st["reminders"][r.ID] = r
+	}
+	return st
+}
+
+// read answers a /sync read. Each response carries a new sync_token; reading with an earlier token
+// returns only what changed since (with is_deleted tombstones), like Todoist.
+func (s *Server) read(token string, types []string) map[string]any {
+	want := map[string]bool{}
+	for _, t := range types {
+		want[t] = true
+	}
+	cur := s.currentState()
+	prev, incremental := s.history[token]
+
+	s.syncReads++
+	next := strconv.Itoa(s.syncReads)
+	s.history[next] = cur
+
+	resp := map[string]any{"sync_token": next, "full_sync": !incremental}
+	for _, kind := range sortedKeys(cur) {
+		if !want[kind] && !want["all"] {
+			continue
+		}
+		out := []any{}
+		for _, id := range sortedKeys(cur[kind]) {
+			if incremental && reflect.DeepEqual(prev[kind][id], cur[kind][id]) {
+				continue
+			}
+			out = append(out, cur[kind][id])
+		}
+		if incremental {
+			for _, id := range sortedKeys(prev[kind]) {
+				if _, ok := cur[kind][id]; !ok {
+					out = append(out, map[string]any{"id": id, "is_deleted": true})
+				}
+			}
+		}
+		resp[kind] = out
+	}
+	return resp
+}
+
+// SyncReads returns the number of /sync reads served so far.
+func (s *Server) SyncReads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.syncReads
+}
+
+// runCommands executes commands in order; callers must hold s.mu.
+func (s *Server) runCommands(cmds []todoistsync.Command) todoistsync.SyncResponse {
+	resp := todoistsync.SyncResponse{SyncStatus: map[string]any{}, TempIDMapping: map[string]string{}}
+	for _, cmd := range cmds {
+		id, err := s.runCommand(cmd, resp.TempIDMapping)
+		if err != nil {
+			resp.SyncStatus[cmd.UUID] = syncError{Error: err.Error(), ErrorCode: 15}
+			continue
+		}
+		if cmd.TempID != nil && id != "" {
+			resp.TempIDMapping[*cmd.TempID] = id
+		}
+		resp.SyncStatus[cmd.UUID] = "ok"
+	}
+	return resp
+}
+
+// runCommand applies one command and returns the ID of the created object, if any.
+func (s *Server) runCommand(cmd todoistsync.Command, tempIDs map[string]string) (string, error) {
+	args := cmd.Args
+	if s.isRejected(args) {
+		return "", fmt.Errorf("rejected by test")
+	}
+	switch cmd.Type {
+	case "filter_add":
+		f := todoistsync.Filter{ID: s.newID(), Color: "charcoal"}
+		f.Name, _ = args["name"].(string)
+		f.Query, _ = args["query"].(string)
+		if f.Name == "" || f.Query == "" {
+			return "", fmt.Errorf("filter_add requires name and query")
+		}
+		if c, ok := args["color"].(string); ok {
+			f.Color = c
+		}
+		if b, ok := args["is_favorite"].(bool); ok {
+			f.IsFavorite = b
+		}
+		if n, ok := args["item_order"].(float64); ok {
+			f.ItemOrder = int(n)
+		} else {
+			for _, other := range s.filters {
+				if other.ItemOrder >= f.ItemOrder {
+					f.ItemOrder = other.ItemOrder + 1
+				}
+			}
+		}
+		s.filters = append(s.filters, f)
+		return f.ID, nil
+
+	case "filter_update":
+		i := s.filterIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("filter not found")
+		}
+		f := &s.filters[i]
+		if v, ok := args["name"].(string); ok {
+			f.Name = v
+		}
+		if v, ok := args["query"].(string); ok {
+			f.Query = v
+		}
+		if v, ok := args["color"].(string); ok {
+			f.Color = v
+		}
+		if v, ok := args["is_favorite"].(bool); ok {
+			f.IsFavorite = v
+		}
+		if v, ok := args["item_order"].(float64); ok {
+			f.ItemOrder = int(v)
+		}
+		return "", nil
+
+	case "filter_delete":
+		id := resolveID(args["id"], tempIDs)
+		if s.filterIndex(id) < 0 {
+			return "", fmt.Errorf("filter not found")
+		}
+		s.filters = remove(s.filters, func(f todoistsync.Filter) bool { return f.ID == id })
+		return "", nil
+
+	case "filter_update_orders":
+		mapping, ok := args["id_order_mapping"].(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("filter_update_orders requires id_order_mapping")
+		}
+		for _, rawID := range sortedKeys(mapping) {
+			i := s.filterIndex(resolveID(rawID, tempIDs))
+			if i < 0 {
+				return "", fmt.Errorf("filter %s not found", rawID)
+			}
+			n, ok := mapping[rawID].(float64)
+			if !ok {
+				return "", fmt.Errorf("invalid order for filter %s", rawID)
+			}
+			s.filters[i].ItemOrder = int(n)
+		}
+		return "", nil
+
+	case "project_move":
+		i := s.projectIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("project not found")
+		}
+		parent, present := args["parent_id"]
+		if !present || parent == nil {
+			s.projects[i].ParentID = nil
+			return "", nil
+		}
+		pid := resolveID(parent, tempIDs)
+		if s.projectIndex(pid) < 0 {
+			return "", fmt.Errorf("parent project not found")
+		}
+		if pid == s.projects[i].ID {
+			return "", fmt.Errorf("project cannot be its own parent")
+		}
+		s.projects[i].ParentID = &pid
+		return "", nil
+
+	case "section_reorder":
+		entries, ok := args["sections"].([]any)
+		if !ok {
+			return "", fmt.Errorf("section_reorder requires sections")
+		}
+		for _, item := range entries {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return "", fmt.Errorf("invalid section entry")
+			}
+			i := s.sectionIndex(resolveID(m["id"], tempIDs))
+			if i < 0 {
+				return "", fmt.Errorf("section not found")
+			}
+			n, ok := m["section_order"].(float64)
+			if !ok {
+				return "", fmt.Errorf("invalid section_order")
+			}
+			s.sections[i].SectionOrder = int(n)
+		}
+		return "", nil
+
+	case "project_add":
+		name, _ := args["name"].(string)
+		if name == "" {
+			return "", fmt.Errorf("project_add requires name")
+		}
+		p := v1.Project{ID: s.newID(), Name: name, Color: "charcoal", ViewStyle: "list"}
+		if parent, ok := args["parent_id"]; ok && parent != nil {
+			pid := resolveID(parent, tempIDs)
+			if s.projectIndex(pid) < 0 {
+				return "", fmt.Errorf("parent project not found")
+			}
+			p.ParentID = &pid
+		}
+		if v, ok := args["color"].(string); ok {
+			p.Color = v
+		}
+		if v, ok := args["is_favorite"].(bool); ok {
+			p.IsFavorite = v
+		}
+		if v, ok := args["view_style"].(string); ok {
+			p.ViewStyle = v
+		}
+		s.projects = append(s.projects, p)
+		return p.ID, nil
+
+	case "project_update":
+		i := s.projectIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("project not found")
+		}
+		p := &s.projects[i]
+		if v, ok := args["name"].(string); ok {
+			p.Name = v
+		}
+		if v, ok := args["color"].(string); ok {
+			p.Color = v
+		}
+		if v, ok := args["is_favorite"].(bool); ok {
+			p.IsFavorite = v
+		}
+		if v, ok := args["view_style"].(string); ok {
+			p.ViewStyle = v
+		}
+		return "", nil
+
+	case "project_delete":
+		id := resolveID(args["id"], tempIDs)
+		i := s.projectIndex(id)
+		if i < 0 {
+			return "", fmt.Errorf("project not found")
+		}
+		if s.projects[i].InboxProject {
+			return "", fmt.Errorf("cannot delete the inbox project")
+		}
+		s.deleteProject(id)
+		return "", nil
+
+	case "section_add":
+		name, _ := args["name"].(string)
+		pid := resolveID(args["project_id"], tempIDs)
+		if name == "" {
+			return "", fmt.Errorf("section_add requires name")
+		}
+		if s.projectIndex(pid) < 0 {
+			return "", fmt.Errorf("project not found")
+		}
+		sec := v1.Section{ID: s.newID(), ProjectID: pid, Name: name}
+		if n, ok := args["section_order"].(float64); ok {
+			sec.SectionOrder = int(n)
+		} else {
+			sec.SectionOrder = 1
+			for _, other := range s.sections {
+				if other.ProjectID == pid && other.SectionOrder >= sec.SectionOrder {
+					sec.SectionOrder = other.SectionOrder + 1
+				}
+			}
+		}
+		s.sections = append(s.sections, sec)
+		return sec.ID, nil
+
+	case "section_update":
+		i := s.sectionIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("section not found")
+		}
+		if v, ok := args["name"].(string); ok {
+			s.sections[i].Name = v
+		}
+		return "", nil
+
+	case "section_delete":
+		id := resolveID(args["id"], tempIDs)
+		if s.sectionIndex(id) < 0 {
+			return "", fmt.Errorf("section not found")
+		}
+		s.deleteSection(id)
+		return "", nil
+
+	case "label_add":
+		name, _ := args["name"].(string)
+		if name == "" {
+			return "", fmt.Errorf("label_add requires name")
+		}
+		for _, l := range s.labels {
+			if strings.EqualFold(l.Name, name) {
+				return "", fmt.Errorf("label already exists")
+			}
+		}
+		l := v1.Label{ID: s.newID(), Name: name, Color: "charcoal"}
+		if v, ok := args["color"].(string); ok {
+			l.Color = v
+		}
+		if v, ok := args["is_favorite"].(bool); ok {
+			l.IsFavorite = v
+		}
+		s.labels = append(s.labels, l)
+		return l.ID, nil
+
+	case "label_update":
+		i := s.labelIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("label not found")
+		}
+		if v, ok := args["name"].(string); ok {
+			s.renameLabel(i, v)
+		}
+		if v, ok := args["color"].(string); ok {
+			s.labels[i].Color = v
+		}
+		if v, ok := args["is_favorite"].(bool); ok {
+			s.labels[i].IsFavorite = v
+		}
+		return "", nil
+
+	case "label_delete":
+		i := s.labelIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("label not found")
+		}
+		s.deleteLabel(i)
+		return "", nil
+
+	case "item_add":
+		content, _ := args["content"].(string)
+		if content == "" {
+			return "", fmt.Errorf("item_add requires content")
+		}
+		t := v1.Task{ID: s.newID(), Content: content, ProjectID: s.inboxID(), Labels: []string{}, Priority: 1}
+		if pid, ok := args["project_id"]; ok {
+			t.ProjectID = resolveID(pid, tempIDs)
+			if s.projectIndex(t.ProjectID) < 0 {
+				return "", fmt.Errorf("project not found")
+			}
+		}
+		if raw, ok := args["section_id"]; ok && raw != nil {
+			section := resolveID(raw, tempIDs)
+			si := s.sectionIndex(section)
+			if si < 0 {
+				return "", fmt.Errorf("section not found")
+			}
+			t.ProjectID, t.SectionID = s.sections[si].ProjectID, &section
+		}
+		if raw, ok := args["parent_id"]; ok && raw != nil {
+			parent := resolveID(raw, tempIDs)
+			pi := s.taskIndex(parent)
+			if pi < 0 {
+				return "", fmt.Errorf("parent item not found")
+			}
+			t.ParentID = &parent
+			t.ProjectID, t.SectionID = s.tasks[pi].ProjectID, copyString(s.tasks[pi].SectionID)
+		}
+		t.ChildOrder = s.nextChildOrder(t.ParentID)
+		if v, ok := args["child_order"].(float64); ok {
+			t.ChildOrder = int(v)
+		}
+		if err := setItemFields(&t, args); err != nil {
+			return "", err
+		}
+		s.tasks = append(s.tasks, t)
+		return t.ID, nil
+
+	case "item_update":
+		i := s.taskIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("item not found")
+		}
+		if v, ok := args["content"].(string); ok {
+			s.tasks[i].Content = v
+		}
+		return "", setItemFields(&s.tasks[i], args)
+
+	case "item_move":
+		i := s.taskIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("item not found")
+		}
+		if raw, ok := args["parent_id"]; ok {
+			parent := resolveID(raw, tempIDs)
+			pi := s.taskIndex(parent)
+			if pi < 0 {
+				return "", fmt.Errorf("parent item not found")
+			}
+			s.moveTask(i, s.tasks[pi].ProjectID, s.tasks[pi].SectionID, &parent)
+			return "", nil
+		}
+		if raw, ok := args["section_id"]; ok {
+			section := resolveID(raw, tempIDs)
+			si := s.sectionIndex(section)
+			if si < 0 {
+				return "", fmt.Errorf("section not found")
+			}
+			s.moveTask(i, s.sections[si].ProjectID, &section, nil)
+			return "", nil
+		}
+		pid := resolveID(args["project_id"], tempIDs)
+		if s.projectIndex(pid) < 0 {
+			return "", fmt.Errorf("project not found")
+		}
+		s.moveTask(i, pid, nil, nil)
+		return "", nil
+
+	case "item_reorder":
+		items, ok := args["items"].([]any)
+		if !ok {
+			return "", fmt.Errorf("item_reorder requires items")
+		}
+		for _, raw := range items {
+			it, _ := raw.(map[string]any)
+			i := s.taskIndex(resolveID(it["id"], tempIDs))
+			if i < 0 {
+				return "", fmt.Errorf("item not found")
+			}
+			if v, ok := it["child_order"].(float64); ok {
+				s.tasks[i].ChildOrder = int(v)
+			}
+		}
+		return "", nil
+
+	case "reminder_add":
+		r := todoistsync.Reminder{ID: s.newID(), ItemID: resolveID(args["item_id"], tempIDs)}
+		i := s.taskIndex(r.ItemID)
+		if i < 0 {
+			return "", fmt.Errorf("item not found")
+		}
+		if err := setReminderFields(&r, args); err != nil {
+			return "", err
+		}
+		if r.Type == "relative" && s.tasks[i].Due == nil {
+			return "", fmt.Errorf("relative reminders require a due date")
+		}
+		s.reminders = append(s.reminders, r)
+		return r.ID, nil
+
+	case "reminder_update":
+		i := s.reminderIndex(resolveID(args["id"], tempIDs))
+		if i < 0 {
+			return "", fmt.Errorf("reminder not found")
+		}
+		return "", setReminderFields(&s.reminders[i], args)
+
+	case "reminder_delete":
+		id := resolveID(args["id"], tempIDs)
+		if s.reminderIndex(id) < 0 {
+			return "", fmt.Errorf("reminder not found")
+		}
+		s.reminders = remove(s.reminders, func(r todoistsync.Reminder) bool { return r.ID == id })
+		return "", nil
+
+	case "item_delete":
+		id := resolveID(args["id"], tempIDs)
+		if s.taskIndex(id) < 0 {
+			return "", fmt.Errorf("item not found")
+		}
+		s.deleteTask(id)
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported command type %q", cmd.Type)
+}
+
+// setItemFields applies the optional item_add/item_update args shared by both commands.
+func setItemFields(t *v1.Task, args map[string]any) error {
+	if v, ok := args["description"].(string); ok {
+		t.Description = v
+	}
+	if raw, ok := args["labels"]; ok {
+		items, ok := raw.([]any)
+		if !ok && raw != nil {
+			return fmt.Errorf("invalid labels")
+		}
+		t.Labels = []string{}
+		for _, l := range items {
+			name, ok := l.(string)
+			if !ok {
+				return fmt.Errorf("invalid label")
+			}
+			t.Labels = append(t.Labels, name)
+		}
+	}
+	if v, ok := args["priority"].(float64); ok {
+		t.Priority = int(v)
+	}
+	if raw, ok := args["due"]; ok {
+		t.Due = nil
+		if due, ok := raw.(map[string]any); ok {
+			str, _ := due["string"].(string)
+			t.Due = parseDue(str)
+		}
+	}
+	return nil
+}
+
+// setReminderFields applies the reminder_add/reminder_update args.
+func setReminderFields(r *todoistsync.Reminder, args map[string]any) error {
+	if v, ok := args["type"].(string); ok {
+		r.Type = v
+	}
+	if v, ok := args["minute_offset"].(float64); ok {
+		r.MinuteOffset = int(v)
+	}
+	if due, ok := args["due"].(map[string]any); ok {
+		date, _ := due["date"].(string)
+		r.Due = &todoistsync.ReminderDue{Date: date}
+	}
+	switch r.Type {
+	case "relative":
+		r.Due = nil
+	case "absolute":
+		if r.Due == nil || r.Due.Date == "" {
+			return fmt.Errorf("absolute reminders require due.date")
+		}
+		r.MinuteOffset = 0
+	default:
+		return fmt.Errorf("invalid reminder type %q", r.Type)
+	}
+	return nil
+}
+
+// resolveID maps a temp_id created earlier in the same request to its real ID.
+func resolveID(v any, tempIDs map[string]string) string {
+	id, _ := v.(string)
+	if real, ok := tempIDs[id]; ok {
+		return real
+	}
+	return id
+}
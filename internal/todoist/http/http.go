@@ -24,12 +24,22 @@ type Client struct {
 	Verbose bool
 	Logger  *log.Logger
 
+	// Observer, if set, is told about every attempt and retry (e.g. to export metrics).
+	Observer Observer
+
 	maxRetries int
 	rng        *rand.Rand
 }
 
 type Option func(*Client)
 
+// Observer receives request attempts. Path is the request path (including any query); status is 0
+// when the attempt failed without a response.
+type Observer interface {
+	ObserveRequest(method, path string, status int, d time.Duration, err error)
+	ObserveRetry(method, path string, status int, err error)
+}
+
 func WithBaseURL(baseURL string) Option {
 	return func(c *Client) { c.BaseURL = strings.TrimRight(baseURL, "/") }
 }
@@ -46,6 +56,10 @@ func WithLogger(l *log.Logger) Option {
 	return func(c *Client) { c.Logger = l }
 }
 
+func WithObserver(o Observer) Option {
+	return func(c *Client) { c.Observer = o }
+}
+
 func New(token string, opts ...Option) *Client {
 	c := &Client{
 		BaseURL: DefaultBaseURL,
@@ -141,13 +155,18 @@ func (c *Client) doWithRetry(ctx context.Context, method, path string, headers m
 	var lastErr error
 
 	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		start := time.Now()
 		status, respBytes, retryAfter, err := c.doOnce(ctx, method, fullURL, headers, body)
 		lastStatus, lastBody, lastErr = status, respBytes, err
+		if c.Observer != nil {
+			c.Observer.ObserveRequest(method, path, status, time.Since(start), err)
+		}
 
 		if err != nil {
 			if attempt == c.maxRetries {
 				return 0, nil, err
 			}
+			c.observeRetry(method, path, 0, err)
 			c.sleepBackoff(ctx, attempt, 0)
 			continue
 		}
@@ -161,6 +180,7 @@ func (c *Client) doWithRetry(ctx context.Context, method, path string, headers m
 			if attempt == c.maxRetries {
 				return status, respBytes, nil
 			}
+			c.observeRetry(method, path, status, nil)
 			c.sleepBackoff(ctx, attempt, retryAfter)
 			continue
 		default:
@@ -213,6 +233,12 @@ func (c *Client) doOnce(ctx context.Context, method, fullURL string, headers map
 	return resp.StatusCode, b, retryAfter, nil
 }
 
+func (c *Client) observeRetry(method, path string, status int, err error) {
+	if c.Observer != nil {
+		c.Observer.ObserveRetry(method, path, status, err)
+	}
+}
+
 func (c *Client) sleepBackoff(ctx context.Context, attempt int, retryAfter time.Duration) {
 	if retryAfter > 0 {
 		select {
@@ -0,0 +1,184 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CacheVersion is the on-disk cache format version. Version 2 added reminders; older caches are
+// discarded, since an incremental read would only return reminders changed since their token.
+const CacheVersion = 2
+
+// Cache is a local copy of /sync resources plus the sync_token they are current as of.
+// Persisting it lets the next run request only changes since that token.
+type Cache struct {
+	Version   int    `json:"version"`
+	Account   string `json:"account"` // opaque account fingerprint; a mismatch discards the cache
+	SyncToken string `json:"sync_token"`
+
+	Projects  map[string]Project  `json:"projects"`
+	Sections  map[string]Section  `json:"sections"`
+	Labels    map[string]Label    `json:"labels"`
+	Items     map[string]Item     `json:"items"`
+	Filters   map[string]Filter   `json:"filters"`
+	Reminders map[string]Reminder `json:"reminders"`
+}
+
+// NewCache returns an empty cache that will trigger a full sync.
+func NewCache(account string) *Cache {
+	return &Cache{
+		Version:   CacheVersion,
+		Account:   account,
+		SyncToken: "*",
+		Projects:  map[string]Project{},
+		Sections:  map[string]Section{},
+		Labels:    map[string]Label{},
+		Items:     map[string]Item{},
+		Filters:   map[string]Filter{},
+		Reminders: map[string]Reminder{},
+	}
+}
+
+// LoadCache reads a cache file. A missing, unreadable-format or foreign (different account or
+// version) cache yields an empty cache, since the worst case is one full sync.
+func LoadCache(path, account string) (*Cache, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return NewCache(account), nil
+		}
+		return nil, fmt.Errorf("read sync cache %q: %w", path, err)
+	}
+	// Unmarshal into initialized maps so fields missing from the file stay usable.
+	c := NewCache(account)
+	if err := json.Unmarshal(b, c); err != nil || c.Version != CacheVersion || c.Account != account {
+		return NewCache(account), nil
+	}
+	return c, nil
+}
+
+// Save writes the cache atomically (temp file + rename), creating parent directories.
+func (c *Cache) Save(path string) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("marshal sync cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create sync cache dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sync-*.json")
+	if err != nil {
+		return fmt.Errorf("write sync cache %q: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write sync cache %q: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write sync cache %q: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write sync cache %q: %w", path, err)
+	}
+	return nil
+}
+
+// Merge applies a read response: a full sync replaces everything, otherwise changed resources
+// are upserted and deleted, archived or completed ones removed.
+func (c *Cache) Merge(resp *SyncResponse) {
+	if resp.FullSync {
+		*c = *NewCache(c.Account)
+	}
+	for _, p := range resp.Projects {
+		if p.IsDeleted || p.IsArchived {
+			delete(c.Projects, p.ID)
+			continue
+		}
+		c.Projects[p.ID] = p
+	}
+	for _, s := range resp.Sections {
+		if s.IsDeleted || s.IsArchived {
+			delete(c.Sections, s.ID)
+			continue
+		}
+		c.Sections[s.ID] = s
+	}
+	for _, l := range resp.Labels {
+		if l.IsDeleted {
+			delete(c.Labels, l.ID)
+			continue
+		}
+		c.Labels[l.ID] = l
+	}
+	for _, it := range resp.Items {
+		if it.IsDeleted || it.Checked {
+			delete(c.Items, it.ID)
+			continue
+		}
+		c.Items[it.ID] = it
+	}
+	for _, f := range resp.Filters {
+		if f.IsDeleted {
+			delete(c.Filters, f.ID)
+			continue
+		}
+		c.Filters[f.ID] = f
+	}
+	for _, r := range resp.Reminders {
+		if r.IsDeleted {
+			delete(c.Reminders, r.ID)
+			continue
+		}
+		c.Reminders[r.ID] = r
+	}
+	if resp.SyncToken != "" {
+		c.SyncToken = resp.SyncToken
+	}
+}
+
+// Refresh brings the cache up to date with one (incremental when possible) read.
+func (c *Client) Refresh(ctx context.Context, cache *Cache) error {
+	resp, err := c.ReadSince(ctx, cache.SyncToken, SnapshotResourceTypes)
+	if err != nil {
+		return err
+	}
+	cache.Merge(resp)
+	return nil
+}
+
+// SortedProjects returns cached projects ordered by ID, for deterministic consumers.
+func (c *Cache) SortedProjects() []Project { return sortedValues(c.Projects) }
+
+// SortedSections returns cached sections ordered by ID.
+func (c *Cache) SortedSections() []Section { return sortedValues(c.Sections) }
+
+// SortedLabels returns cached labels ordered by ID.
+func (c *Cache) SortedLabels() []Label { return sortedValues(c.Labels) }
+
+// SortedItems returns cached items ordered by ID.
+func (c *Cache) SortedItems() []Item { return sortedValues(c.Items) }
+
+// SortedFilters returns cached filters ordered by ID.
+func (c *Cache) SortedFilters() []Filter { return sortedValues(c.Filters) }
+
+// SortedReminders returns cached reminders ordered by ID.
+func (c *Cache) SortedReminders() []Reminder { return sortedValues(c.Reminders) }
+
+func sortedValues[T any](m map[string]T) []T {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	out := make([]T, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, m[id])
+	}
+	return out
+}
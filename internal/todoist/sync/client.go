@@ -80,18 +80,37 @@ type SyncResponse struct {
 	SyncStatus    map[string]any    `json:"sync_status"`
 	TempIDMapping map[string]string `json:"temp_id_mapping"`
 
-	Filters []Filter `json:"filters"`
+	// Read responses: SyncToken is passed to the next ReadSince; FullSync reports whether the
+	// resources below are complete (true) or only changes since the given token (false).
+	SyncToken string `json:"sync_token"`
+	FullSync  bool   `json:"full_sync"`
+
+	Projects  []Project  `json:"projects"`
+	Sections  []Section  `json:"sections"`
+	Labels    []Label    `json:"labels"`
+	Items     []Item     `json:"items"`
+	Filters   []Filter   `json:"filters"`
+	Reminders []Reminder `json:"reminders"`
 }
 
 // Read performs a full sync for the given resource types.
 // resourceTypes examples: ["filters"].
 func (c *Client) Read(ctx context.Context, resourceTypes []string) (*SyncResponse, error) {
+	return c.ReadSince(ctx, "*", resourceTypes)
+}
+
+// ReadSince performs an incremental sync: only resources changed since syncToken are returned
+// (with is_deleted tombstones), unless the server answers with a full sync. "*" requests a full sync.
+func (c *Client) ReadSince(ctx context.Context, syncToken string, resourceTypes []string) (*SyncResponse, error) {
+	if syncToken == "" {
+		syncToken = "*"
+	}
 	rt, err := json.Marshal(resourceTypes)
 	if err != nil {
 		return nil, fmt.Errorf("marshal resource types: %w", err)
 	}
 	values := url.Values{}
-	values.Set("sync_token", "*")
+	values.Set("sync_token", syncToken)
 	values.Set("resource_types", string(rt))
 	var resp SyncResponse
 	if err := c.http.DoForm(ctx, "/api/v1/sync", values, &resp); err != nil {
@@ -100,7 +119,8 @@ func (c *Client) Read(ctx context.Context, resourceTypes []string) (*SyncRespons
 	return &resp, nil
 }
 
-// RunCommands submits /sync commands.
+// RunCommands submits /sync commands. If a later batch fails, the error is returned together with
+// the merged response of the batches that were applied.
 func (c *Client) RunCommands(ctx context.Context, commands []Command) (*SyncResponse, error) {
 	// Todoist limits commands per sync operation (currently 100). Split for callers so higher
 	// level apply logic doesn't need to care about this transport detail.
@@ -114,9 +134,15 @@ func (c *Client) RunCommands(ctx context.Context, commands []Command) (*SyncResp
 			if end > len(commands) {
 				end = len(commands)
 			}
-			resp, err := c.RunCommands(ctx, commands[start:end])
+			// Temp IDs only resolve within one request; later batches get the real IDs.
+			batch, err := resolveTempIDs(commands[start:end], merged.TempIDMapping)
 			if err != nil {
-				return nil, err
+				return &merged, err
+			}
+			resp, err := c.RunCommands(ctx, batch)
+			if err != nil {
+				// Earlier batches were applied; their statuses let callers report partial results.
+				return &merged, err
 			}
 			for k, v := range resp.SyncStatus {
 				merged.SyncStatus[k] = v
@@ -141,6 +167,53 @@ func (c *Client) RunCommands(ctx context.Context, commands []Command) (*SyncResp
 	return &resp, nil
 }
 
+// resolveTempIDs returns copies of commands whose args reference temp IDs from earlier requests
+// rewritten to the real IDs (string values and map keys, at any depth).
+func resolveTempIDs(commands []Command, mapping map[string]string) ([]Command, error) {
+	if len(mapping) == 0 {
+		return commands, nil
+	}
+	out := make([]Command, len(commands))
+	for i, cmd := range commands {
+		b, err := json.Marshal(cmd.Args)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s args: %w", cmd.Type, err)
+		}
+		var args map[string]any
+		if err := json.Unmarshal(b, &args); err != nil {
+			return nil, fmt.Errorf("decode %s args: %w", cmd.Type, err)
+		}
+		cmd.Args = replaceTempIDs(args, mapping).(map[string]any)
+		out[i] = cmd
+	}
+	return out, nil
+}
+
+func replaceTempIDs(v any, mapping map[string]string) any {
+	switch x := v.(type) {
+	case string:
+		if real, ok := mapping[x]; ok {
+			return real
+		}
+		return x
+	case []any:
+		for i := range x {
+			x[i] = replaceTempIDs(x[i], mapping)
+		}
+		return x
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, val := range x {
+			if real, ok := mapping[k]; ok {
+				k = real
+			}
+			out[k] = replaceTempIDs(val, mapping)
+		}
+		return out
+	}
+	return v
+}
+
 // RequireAllOK validates sync_status in a response for a set of commands.
 // Any status that isn't the string "ok" is treated as an error.
 func RequireAllOK(resp *SyncResponse, commands []Command) error {
@@ -152,19 +225,9 @@ func RequireAllOK(resp *SyncResponse, commands []Command) error {
 	}
 	var errs []error
 	for _, cmd := range commands {
-		st, ok := resp.SyncStatus[cmd.UUID]
-		if !ok {
-			errs = append(errs, fmt.Errorf("sync_status missing uuid=%s type=%s", cmd.UUID, cmd.Type))
-			continue
+		if err := CommandError(resp, cmd); err != nil {
+			errs = append(errs, err)
 		}
-		if s, ok := st.(string); ok {
-			if s != "ok" {
-				errs = append(errs, fmt.Errorf("sync_status uuid=%s type=%s: %s", cmd.UUID, cmd.Type, s))
-			}
-			continue
-		}
-		// For some commands, Todoist returns an object (e.g. LRO). Treat as error in MVP.
-		errs = append(errs, fmt.Errorf("sync_status uuid=%s type=%s: unexpected non-string status", cmd.UUID, cmd.Type))
 	}
 	if len(errs) > 0 {
 		return errorsJoin(errs)
@@ -172,6 +235,27 @@ func RequireAllOK(resp *SyncResponse, commands []Command) error {
 	return nil
 }
 
+// CommandError returns the error for one command's sync_status, or nil if it is "ok".
+func CommandError(resp *SyncResponse, cmd Command) error {
+	st, ok := resp.SyncStatus[cmd.UUID]
+	if !ok {
+		return fmt.Errorf("sync_status missing uuid=%s type=%s", cmd.UUID, cmd.Type)
+	}
+	if s, ok := st.(string); ok {
+		if s != "ok" {
+			return fmt.Errorf("sync_status uuid=%s type=%s: %s", cmd.UUID, cmd.Type, s)
+		}
+		return nil
+	}
+	if m, ok := st.(map[string]any); ok {
+		if msg, ok := m["error"].(string); ok {
+			return fmt.Errorf("%s: %s", cmd.Type, msg)
+		}
+	}
+	// For some commands, Todoist returns an object (e.g. LRO). Treat as error in MVP.
+	return fmt.Errorf("sync_status uuid=%s type=%s: unexpected non-string status", cmd.UUID, cmd.Type)
+}
+
 func errorsJoin(errs []error) error {
 	if len(errs) == 0 {
 		return nil
@@ -85,3 +85,90 @@ func TestRunCommands_FormEncoding(t *testing.T) {
 		t.Fatalf("RequireAllOK error: %v", err)
 	}
 }
+
+func TestRunCommands_TempIDsAcrossBatches(t *testing.T) {
+	t.Parallel()
+
+	var batches [][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		vals, _ := url.ParseQuery(string(b))
+		var cmds []map[string]any
+		if err := json.Unmarshal([]byte(vals.Get("commands")), &cmds); err != nil {
+			t.Fatalf("decode commands json: %v", err)
+		}
+		batches = append(batches, cmds)
+		status := map[string]any{}
+		mapping := map[string]string{}
+		for _, c := range cmds {
+			status[c["uuid"].(string)] = "ok"
+			if tmp, ok := c["temp_id"].(string); ok {
+				mapping[tmp] = "real-" + tmp
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"sync_status": status, "temp_id_mapping": mapping})
+	}))
+	defer server.Close()
+
+	c := New(todoisthttp.New("testtoken", todoisthttp.WithBaseURL(server.URL)), WithMaxCommandsPerSync(1))
+	cmds := []Command{
+		NewTempIDCommand("project_add", "PARENT", map[string]any{"name": "Personal"}),
+		NewTempIDCommand("project_add", "CHILD", map[string]any{"name": "Homelab", "parent_id": "PARENT"}),
+		NewCommand("filter_update_orders", map[string]any{"id_order_mapping": map[string]any{"CHILD": 1}}),
+	}
+	resp, err := c.RunCommands(context.Background(), cmds)
+	if err != nil {
+		t.Fatalf("RunCommands: %v", err)
+	}
+	if err := RequireAllOK(resp, cmds); err != nil {
+		t.Fatalf("RequireAllOK: %v", err)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(batches))
+	}
+	if got := batches[1][0]["args"].(map[string]any)["parent_id"]; got != "real-PARENT" {
+		t.Fatalf("expected parent_id resolved to real-PARENT, got %#v", got)
+	}
+	if _, ok := batches[2][0]["args"].(map[string]any)["id_order_mapping"].(map[string]any)["real-CHILD"]; !ok {
+		t.Fatalf("expected map key resolved to real-CHILD, got %#v", batches[2][0]["args"])
+	}
+	if resp.TempIDMapping["CHILD"] != "real-CHILD" {
+		t.Fatalf("unexpected merged temp_id_mapping: %#v", resp.TempIDMapping)
+	}
+}
+
+func TestCache_Merge(t *testing.T) {
+	t.Parallel()
+
+	c := NewCache("acct")
+	c.Merge(&SyncResponse{
+		SyncToken: "t1",
+		FullSync:  true,
+		Projects:  []Project{{ID: "P1", Name: "Work"}, {ID: "P2", Name: "Old"}},
+		Items:     []Item{{ID: "I1", Content: "a"}, {ID: "I2", Content: "b"}},
+		Filters:   []Filter{{ID: "F1", Name: "x"}},
+	})
+	c.Merge(&SyncResponse{
+		SyncToken: "t2",
+		Projects:  []Project{{ID: "P1", Name: "Work 2"}, {ID: "P2", IsDeleted: true}},
+		Items:     []Item{{ID: "I1", Checked: true}},
+	})
+	if c.SyncToken != "t2" {
+		t.Fatalf("expected token t2, got %q", c.SyncToken)
+	}
+	if len(c.Projects) != 1 || c.Projects["P1"].Name != "Work 2" {
+		t.Fatalf("unexpected projects %#v", c.Projects)
+	}
+	if len(c.Items) != 1 || c.Items["I2"].Content != "b" {
+		t.Fatalf("expected completed item dropped, got %#v", c.Items)
+	}
+	if len(c.Filters) != 1 {
+		t.Fatalf("expected untouched filters kept, got %#v", c.Filters)
+	}
+
+	c.Merge(&SyncResponse{SyncToken: "t3", FullSync: true, Projects: []Project{{ID: "P9", Name: "Fresh"}}})
+	if len(c.Projects) != 1 || len(c.Items) != 0 || len(c.Filters) != 0 {
+		t.Fatalf("expected full sync to replace everything, got %#v", c)
+	}
+}
@@ -0,0 +1,78 @@
+package sync
+
+// Resource shapes returned by /sync reads. They carry tombstone fields (is_deleted, checked,
+// is_archived) that the REST v1 list endpoints omit, which incremental syncs need to apply deltas.
+
+type Project struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Color        string  `json:"color"`
+	ParentID     *string `json:"parent_id"`
+	ChildOrder   int     `json:"child_order"`
+	IsFavorite   bool    `json:"is_favorite"`
+	ViewStyle    string  `json:"view_style"`
+	InboxProject bool    `json:"inbox_project"`
+	IsArchived   bool    `json:"is_archived"`
+	IsDeleted    bool    `json:"is_deleted"`
+}
+
+type Section struct {
+	ID           string `json:"id"`
+	ProjectID    string `json:"project_id"`
+	Name         string `json:"name"`
+	SectionOrder int    `json:"section_order"`
+	IsArchived   bool   `json:"is_archived"`
+	IsDeleted    bool   `json:"is_deleted"`
+}
+
+type Label struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	ItemOrder  int    `json:"item_order"`
+	IsFavorite bool   `json:"is_favorite"`
+	IsDeleted  bool   `json:"is_deleted"`
+}
+
+type Due struct {
+	String      string  `json:"string"`
+	IsRecurring bool    `json:"is_recurring"`
+	Date        string  `json:"date"`     // next occurrence: YYYY-MM-DD or a datetime
+	Timezone    *string `json:"timezone"` // nil for floating dates
+	Lang        string  `json:"lang"`
+}
+
+// Item is a task in /sync terminology.
+type Item struct {
+	ID          string   `json:"id"`
+	Content     string   `json:"content"`
+	Description string   `json:"description"`
+	ProjectID   string   `json:"project_id"`
+	SectionID   *string  `json:"section_id"`
+	ParentID    *string  `json:"parent_id"`
+	ChildOrder  int      `json:"child_order"`
+	Labels      []string `json:"labels"`
+	Priority    int      `json:"priority"`
+	Due         *Due     `json:"due"`
+	Checked     bool     `json:"checked"`
+	IsDeleted   bool     `json:"is_deleted"`
+}
+
+// Reminder is a task reminder. Relative reminders fire MinuteOffset minutes before the task's
+// due time; absolute ones at Due.Date. The v1 REST API has no reminders endpoint.
+type Reminder struct {
+	ID           string       `json:"id"`
+	ItemID       string       `json:"item_id"`
+	Type         string       `json:"type"` // relative, absolute or location
+	Due          *ReminderDue `json:"due,omitempty"`
+	MinuteOffset int          `json:"minute_offset,omitempty"`
+	IsDeleted    bool         `json:"is_deleted"`
+}
+
+type ReminderDue struct {
+	Date     string  `json:"date"` // "2026-10-20T09:00:00Z" (UTC) or "2026-10-20T09:00:00" (floating)
+	Timezone *string `json:"timezone,omitempty"`
+}
+
+// SnapshotResourceTypes are the resource types htd reads to build a snapshot.
+var SnapshotResourceTypes = []string{"projects", "sections", "labels", "items", "filters", "reminders"}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"time"
 
 	todoisthttp "github.com/erauner/homelab-todoist-declarative/internal/todoist/http"
 )
@@ -26,6 +27,14 @@ type Project struct {
 	InboxProject bool `json:"inbox_project"`
 }
 
+type Section struct {
+	ID string `json:"id"`
+
+	ProjectID    string `json:"project_id"`
+	Name         string `json:"name"`
+	SectionOrder int    `json:"section_order"`
+}
+
 type Label struct {
 	ID string `json:"id"`
 
@@ -35,8 +44,11 @@ type Label struct {
 }
 
 type Due struct {
-	String      string `json:"string"`
-	IsRecurring bool   `json:"is_recurring"`
+	String      string  `json:"string"`
+	IsRecurring bool    `json:"is_recurring"`
+	Date        string  `json:"date"`     // next occurrence: YYYY-MM-DD or a datetime
+	Timezone    *string `json:"timezone"` // nil for floating dates
+	Lang        string  `json:"lang"`
 }
 
 type Task struct {
@@ -45,6 +57,9 @@ type Task struct {
 	Content     string   `json:"content"`
 	Description string   `json:"description"`
 	ProjectID   string   `json:"project_id"`
+	SectionID   *string  `json:"section_id"`
+	ParentID    *string  `json:"parent_id"`
+	ChildOrder  int      `json:"child_order"`
 	Labels      []string `json:"labels"`
 	Priority    int      `json:"priority"`
 	Due         *Due     `json:"due"`
@@ -115,6 +130,62 @@ func (c *Client) DeleteProject(ctx context.Context, projectID string) error {
 	return c.http.DoJSON(ctx, "DELETE", path, nil, nil)
 }
 
+func (c *Client) ListSections(ctx context.Context) ([]Section, error) {
+	var all []Section
+	var cursor *string
+	for {
+		path := "/api/v1/sections"
+		if cursor != nil && *cursor != "" {
+			q := url.Values{}
+			q.Set("cursor", *cursor)
+			path = path + "?" + q.Encode()
+		}
+		var resp listResponse[Section]
+		if err := c.http.DoJSON(ctx, "GET", path, nil, &resp); err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Results...)
+		if resp.NextCursor == nil || *resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	return all, nil
+}
+
+type CreateSectionRequest struct {
+	Name      string `json:"name"`
+	ProjectID string `json:"project_id"`
+	Order     *int   `json:"order,omitempty"`
+}
+
+func (c *Client) CreateSection(ctx context.Context, req CreateSectionRequest) (*Section, error) {
+	var resp Section
+	if err := c.http.DoJSON(ctx, "POST", "/api/v1/sections", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateSectionRequest only supports renames; ordering is a /sync command (section_reorder).
+type UpdateSectionRequest struct {
+	Name *string `json:"name,omitempty"`
+}
+
+func (c *Client) UpdateSection(ctx context.Context, sectionID string, req UpdateSectionRequest) (*Section, error) {
+	var resp Section
+	path := fmt.Sprintf("/api/v1/sections/%s", url.PathEscape(sectionID))
+	if err := c.http.DoJSON(ctx, "POST", path, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) DeleteSection(ctx context.Context, sectionID string) error {
+	path := fmt.Sprintf("/api/v1/sections/%s", url.PathEscape(sectionID))
+	return c.http.DoJSON(ctx, "DELETE", path, nil, nil)
+}
+
 func (c *Client) ListLabels(ctx context.Context) ([]Label, error) {
 	var all []Label
 	var cursor *string
@@ -195,10 +266,43 @@ func (c *Client) ListTasks(ctx context.Context) ([]Task, error) {
 	return all, nil
 }
 
+type completedResponse struct {
+	Items      []Task  `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// ListCompletedTasks returns the tasks completed between since and until. Todoist limits the
+// range to three months.
+func (c *Client) ListCompletedTasks(ctx context.Context, since, until time.Time) ([]Task, error) {
+	var all []Task
+	var cursor *string
+	for {
+		q := url.Values{}
+		q.Set("since", since.UTC().Format(time.RFC3339))
+		q.Set("until", until.UTC().Format(time.RFC3339))
+		if cursor != nil && *cursor != "" {
+			q.Set("cursor", *cursor)
+		}
+		var resp completedResponse
+		if err := c.http.DoJSON(ctx, "GET", "/api/v1/tasks/completed/by_completion_date?"+q.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Items...)
+		if resp.NextCursor == nil || *resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	return all, nil
+}
+
 type CreateTaskRequest struct {
 	Content     string   `json:"content"`
 	Description *string  `json:"description,omitempty"`
 	ProjectID   *string  `json:"project_id,omitempty"`
+	SectionID   *string  `json:"section_id,omitempty"`
+	ParentID    *string  `json:"parent_id,omitempty"`
+	Order       *int     `json:"order,omitempty"`
 	Labels      []string `json:"labels,omitempty"`
 	Priority    *int     `json:"priority,omitempty"`
 	DueString   *string  `json:"due_string,omitempty"`
@@ -212,6 +316,8 @@ func (c *Client) CreateTask(ctx context.Context, req CreateTaskRequest) (*Task,
 	return &resp, nil
 }
 
+// UpdateTaskRequest cannot change the parent, section or order of a task; those are /sync commands
+// (item_move, item_reorder).
 type UpdateTaskRequest struct {
 	Content     *string   `json:"content,omitempty"`
 	Description *string   `json:"description,omitempty"`
@@ -0,0 +1,171 @@
+// Package watch periodically plans a config against the remote account and reports drift.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/notify"
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+)
+
+// ApplyFunc applies a plan; reconcile.Apply and reconcile.ApplySync both fit.
+type ApplyFunc func(ctx context.Context, cfg *config.TodoistConfig, snap *reconcile.Snapshot, plan *reconcile.Plan, clients reconcile.Clients, opts reconcile.Options) (*reconcile.ApplyResult, error)
+
+// Watcher checks for drift. Drift is reported once: the same pending changes are not notified
+// again until they change or the drift is resolved.
+type Watcher struct {
+	// Load returns the config and planning options; it is called on every check so config
+	// edits are picked up without a restart.
+	Load func() (*config.TodoistConfig, reconcile.Options, error)
+	// Fetch reads the remote snapshot planned against cfg.
+	Fetch func(ctx context.Context, cfg *config.TodoistConfig) (*reconcile.Snapshot, error)
+
+	Notifier notify.Notifier
+	Logger   *log.Logger
+
+	// AutoApply applies the non-destructive part of the plan (everything but deletes).
+	AutoApply bool
+	Apply     ApplyFunc
+	Clients   reconcile.Clients
+	// AfterApply, if set, is called after every auto-apply (e.g. to write a journal).
+	AfterApply func(cfg *config.TodoistConfig, snap *reconcile.Snapshot, plan *reconcile.Plan, res *reconcile.ApplyResult)
+
+	reported string // fingerprint of the drift last notified
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Plan     *reconcile.Plan
+	Applied  *reconcile.ApplyResult
+	Notified bool
+}
+
+// Run checks immediately and then every interval until ctx is done. Errors are logged and
+// the next check proceeds; a watch daemon should survive a flaky network.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch interval must be positive, got %s", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Check(ctx); err != nil && ctx.Err() == nil {
+			w.logger().Printf("watch: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check plans once, auto-applies if enabled and notifies about new drift.
+func (w *Watcher) Check(ctx context.Context) (*Result, error) {
+	cfg, opts, err := w.Load()
+	if err != nil {
+		return nil, err
+	}
+	snap, err := w.Fetch(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := reconcile.BuildPlan(cfg, snap, opts)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Plan: plan}
+	if plan.Summary.TotalChanges() == 0 {
+		if w.reported != "" {
+			w.logger().Printf("watch: %s: no drift", cfg.Metadata.Name)
+			w.reported = ""
+		}
+		return res, nil
+	}
+
+	ev := notify.Event{
+		Config:     cfg.Metadata.Name,
+		Time:       time.Now().UTC(),
+		Summary:    plan.Summary,
+		Operations: plan.Operations,
+	}
+	// What is still pending after this check; the next check reports only if that changes.
+	pending := plan
+	if w.AutoApply {
+		if sub := reconcile.WithoutDeletes(plan); sub.Summary.TotalChanges() > 0 {
+			applyOpts := opts
+			applyOpts.ContinueOnError = true
+			applied, applyErr := w.apply()(ctx, cfg, snap, sub, w.Clients, applyOpts)
+			if applied != nil {
+				res.Applied = applied
+				ev.Applied = applied
+				if w.AfterApply != nil {
+					w.AfterApply(cfg, snap, sub, applied)
+				}
+			}
+			if applyErr != nil {
+				ev.Error = applyErr.Error()
+			} else {
+				pending = &reconcile.Plan{Operations: deletes(plan.Operations)}
+			}
+		}
+	}
+
+	fp := fingerprint(plan)
+	if fp == w.reported {
+		return res, nil
+	}
+	w.logger().Printf("watch: %s", ev.Title())
+	if w.Notifier != nil {
+		if err := w.Notifier.Notify(ctx, ev); err != nil {
+			// Leave the drift unreported so the next check retries the notification.
+			return res, err
+		}
+	}
+	res.Notified = true
+	w.reported = fingerprint(pending)
+	return res, nil
+}
+
+func (w *Watcher) apply() ApplyFunc {
+	if w.Apply != nil {
+		return w.Apply
+	}
+	return reconcile.Apply
+}
+
+func (w *Watcher) logger() *log.Logger {
+	if w.Logger != nil {
+		return w.Logger
+	}
+	return log.New(io.Discard, "", 0)
+}
+
+func deletes(ops []reconcile.Operation) []reconcile.Operation {
+	var out []reconcile.Operation
+	for _, op := range ops {
+		if op.Action == reconcile.ActionDelete {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// fingerprint identifies a plan's pending changes (operations and their field changes).
+func fingerprint(plan *reconcile.Plan) string {
+	var b strings.Builder
+	for _, op := range plan.Operations {
+		b.WriteString(op.SortKey())
+		for _, ch := range op.Changes {
+			fmt.Fprintf(&b, "|%s:%s>%s", ch.Field, ch.From, ch.To)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
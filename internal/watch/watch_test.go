@@ -0,0 +1,201 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/erauner/homelab-todoist-declarative/internal/config"
+	"github.com/erauner/homelab-todoist-declarative/internal/notify"
+	"github.com/erauner/homelab-todoist-declarative/internal/reconcile"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/fake"
+	todoisthttp "github.com/erauner/homelab-todoist-declarative/internal/todoist/http"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/sync"
+	"github.com/erauner/homelab-todoist-declarative/internal/todoist/v1"
+)
+
+// newWatcher returns a watcher of srv that posts to a local webhook receiver, and the events
+// the receiver got.
+func newWatcher(t *testing.T, srv *fake.Server) (*Watcher, *[]notify.Event) {
+	t.Helper()
+	var events []notify.Event
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		events = append(events, ev)
+	}))
+	t.Cleanup(receiver.Close)
+
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "watch"},
+		Spec: config.Spec{
+			Projects: []config.ProjectSpec{{Name: "Work", Color: strPtr("red")}},
+			Labels:   []config.LabelSpec{{Name: "next"}},
+			Prune:    config.PruneSpec{Projects: true},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	h := todoisthttp.New("testtoken", todoisthttp.WithBaseURL(srv.URL()))
+	clients := reconcile.Clients{V1: v1.New(h), Sync: sync.New(h)}
+	n, err := notify.Parse("webhook="+receiver.URL, receiver.Client())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	w := &Watcher{
+		Load: func() (*config.TodoistConfig, reconcile.Options, error) {
+			return cfg, reconcile.Options{Prune: true}, nil
+		},
+		Fetch: func(ctx context.Context, cfg *config.TodoistConfig) (*reconcile.Snapshot, error) {
+			snap, err := reconcile.FetchSnapshot(ctx, clients.V1, clients.Sync)
+			if err != nil {
+				return nil, err
+			}
+			return snap, reconcile.FetchCompletedTasks(ctx, clients.V1, cfg, snap)
+		},
+		Notifier: n,
+		Clients:  clients,
+	}
+	return w, &events
+}
+
+func TestCheck_NotifiesOncePerDrift(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	srv.AddProject(v1.Project{Name: "Work", Color: "red"})
+	srv.AddLabel(v1.Label{Name: "next"})
+	w, events := newWatcher(t, srv)
+	ctx := context.Background()
+
+	res, err := w.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if res.Notified || len(*events) != 0 {
+		t.Fatalf("expected no notification without drift, got %d", len(*events))
+	}
+
+	// Someone edits the managed project in the app.
+	srv.AddProject(v1.Project{Name: "Scratch"})
+	for i := 0; i < 2; i++ {
+		if _, err := w.Check(ctx); err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+	}
+	if len(*events) != 1 {
+		t.Fatalf("expected the drift to be reported once, got %d", len(*events))
+	}
+	ev := (*events)[0]
+	if ev.Config != "watch" || ev.Summary.Delete != 1 || ev.Applied != nil {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestCheck_AutoApplyLeavesDeletes(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	srv.AddProject(v1.Project{Name: "Work", Color: "blue"})
+	srv.AddProject(v1.Project{Name: "Scratch"})
+	w, events := newWatcher(t, srv)
+	w.AutoApply = true
+	ctx := context.Background()
+
+	res, err := w.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if !res.Notified || res.Applied == nil {
+		t.Fatalf("expected a notified auto-apply, got %+v", res)
+	}
+	if len(res.Applied.Applied) != 2 || res.Applied.Failed != 0 {
+		t.Fatalf("expected the update and the label create to be applied, got %+v", res.Applied)
+	}
+	ev := (*events)[0]
+	if ev.Summary.TotalChanges() != 3 || ev.Applied == nil || ev.Error != "" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	for _, p := range srv.Projects() {
+		if p.Name == "Work" && p.Color != "red" {
+			t.Fatalf("expected Work to be red, got %q", p.Color)
+		}
+	}
+	if len(srv.Projects()) != 3 {
+		t.Fatalf("expected Scratch to survive the auto-apply, got %+v", srv.Projects())
+	}
+
+	// Only the delete is left; it was already reported.
+	res, err = w.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if res.Applied != nil || res.Notified || len(*events) != 1 {
+		t.Fatalf("expected nothing new, got %+v and %d event(s)", res, len(*events))
+	}
+	if res.Plan.Summary.Delete != 1 || res.Plan.Summary.TotalChanges() != 1 {
+		t.Fatalf("expected only the delete to remain, got %+v", res.Plan.Summary)
+	}
+}
+
+func TestCheck_AutoApplyOneShotTask(t *testing.T) {
+	srv := fake.New()
+	defer srv.Close()
+	w, _ := newWatcher(t, srv)
+	w.AutoApply = true
+	oneShot := "one_shot"
+	cfg := &config.TodoistConfig{
+		Metadata: config.Metadata{Name: "watch"},
+		Spec: config.Spec{
+			Tasks: []config.TaskSpec{
+				{Key: "certs", Type: &oneShot, Content: "Rotate homelab certs", Subtasks: []config.TaskSpec{
+					{Key: "certs-wildcard", Content: "Renew wildcard cert"},
+				}},
+			},
+		},
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	// No state file: watch never writes one.
+	w.Load = func() (*config.TodoistConfig, reconcile.Options, error) {
+		return cfg, reconcile.Options{}, nil
+	}
+	ctx := context.Background()
+
+	res, err := w.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if res.Applied == nil || len(res.Applied.Applied) != 2 {
+		t.Fatalf("expected the task and its subtask to be created, got %+v", res.Applied)
+	}
+	for _, task := range srv.Tasks() {
+		if task.Description == "HTD_KEY:certs" {
+			srv.CompleteTask(task.ID)
+		}
+	}
+	if len(srv.Tasks()) != 0 {
+		t.Fatalf("expected the one-shot task to be completed, got %+v", srv.Tasks())
+	}
+
+	res, err = w.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if res.Applied != nil || res.Plan.Summary.TotalChanges() != 0 || len(srv.Tasks()) != 0 {
+		t.Fatalf("expected the completed one-shot task not to be recreated, got %+v", res.Plan.Operations)
+	}
+	if !strings.Contains(strings.Join(res.Plan.Notes, "\n"), `one-shot task "Rotate homelab certs" was completed`) {
+		t.Fatalf("unexpected notes: %v", res.Plan.Notes)
+	}
+}
+
+func strPtr(s string) *string { return &s }